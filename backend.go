@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/warthog618/go-gpiocdev/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// Backend provides access to a GPIO chip.
+//
+// The default backend is the GPIO character device.  Alternative backends,
+// such as the simulated chips provided by the sim package, are made available
+// by name using RegisterBackend.
+//
+// Backends follow the semantics of the GPIO uAPI v2 ioctls of the same name.
+// The uAPI v1 is only supported by the GPIO character device.
+type Backend interface {
+	// ChipInfo returns the info for the chip.
+	ChipInfo() (uapi.ChipInfo, error)
+
+	// LineInfo returns the info for a line.
+	LineInfo(offset int) (uapi.LineInfoV2, error)
+
+	// WatchLineInfo returns the info for the line identified by info.Offset,
+	// and enables the reporting of changes to that info.
+	WatchLineInfo(info *uapi.LineInfoV2) error
+
+	// UnwatchLineInfo disables the reporting of changes to the line info.
+	UnwatchLineInfo(offset int) error
+
+	// InfoFd returns the fd from which info changes to watched lines are
+	// read, as uapi.LineInfoChangedV2.
+	InfoFd() int
+
+	// GetLine requests a set of lines.
+	//
+	// The requested lines remain requested until the returned LineBackend is
+	// closed, independent of the Backend.
+	GetLine(request *uapi.LineRequest) (LineBackend, error)
+
+	// Close releases the backend.
+	Close() error
+}
+
+// LineBackend provides access to a set of requested lines.
+type LineBackend interface {
+	// Fd returns the fd from which edge events are read, as uapi.LineEvent.
+	//
+	// The fd must become readable when events are available, and return
+	// end of file if the chip is removed.
+	Fd() uintptr
+
+	// Values returns the values of the lines identified by values.Mask.
+	Values(values *uapi.LineValues) error
+
+	// SetValues sets the values of the lines identified by values.Mask.
+	SetValues(values uapi.LineValues) error
+
+	// SetConfig updates the configuration of the lines.
+	SetConfig(config *uapi.LineConfig) error
+
+	// Close releases the lines.
+	Close() error
+}
+
+var (
+	// mutex covers the attributes below it.
+	backendsMu sync.Mutex
+
+	// the functions to open registered backends, keyed by chip name.
+	backends = map[string]func() (Backend, error){}
+
+	// the eventfds of the ChipWatchers to notify when backends are
+	// registered or unregistered.
+	backendWatchers = map[int]struct{}{}
+)
+
+// RegisterBackend makes a chip provided by an alternative backend available
+// to NewChip, and the other functions that accept a chip name.
+//
+// The open function is called to create a new Backend each time the chip is
+// opened.
+//
+// Returns unix.EEXIST if a backend is already registered with the name.
+func RegisterBackend(name string, open func() (Backend, error)) error {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, ok := backends[name]; ok {
+		return unix.EEXIST
+	}
+	backends[name] = open
+	notifyBackendWatchers()
+	return nil
+}
+
+// UnregisterBackend removes a chip registered using RegisterBackend.
+//
+// Chips already opened from the backend are not affected.
+func UnregisterBackend(name string) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, ok := backends[name]; ok {
+		delete(backends, name)
+		notifyBackendWatchers()
+	}
+}
+
+// notifyBackendWatchers signals the ChipWatchers that the registered backends
+// have changed.
+//
+// Assumes backendsMu is locked.
+func notifyBackendWatchers() {
+	for fd := range backendWatchers {
+		unix.Write(fd, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+	}
+}
+
+// addBackendWatcher adds the eventfd to those notified when the registered
+// backends change.
+func addBackendWatcher(fd int) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backendWatchers[fd] = struct{}{}
+}
+
+// removeBackendWatcher removes the eventfd from those notified when the
+// registered backends change.
+func removeBackendWatcher(fd int) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	delete(backendWatchers, fd)
+}
+
+// registeredBackend returns the open function for the named chip, if
+// registered.
+func registeredBackend(name string) func() (Backend, error) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	return backends[strings.TrimPrefix(name, "/dev/")]
+}
+
+// registeredChips returns the names of the registered chips in natural order.
+func registeredChips() []string {
+	backendsMu.Lock()
+	cc := make([]string, 0, len(backends))
+	for name := range backends {
+		cc = append(cc, name)
+	}
+	backendsMu.Unlock()
+	sort.Slice(cc, func(i, j int) bool { return naturalLess(cc[i], cc[j]) })
+	return cc
+}
+
+// chardev is the Backend for a GPIO character device.
+type chardev struct {
+	f *os.File
+}
+
+func (cd chardev) ChipInfo() (uapi.ChipInfo, error) {
+	return uapi.GetChipInfo(cd.f.Fd())
+}
+
+func (cd chardev) LineInfo(offset int) (uapi.LineInfoV2, error) {
+	return uapi.GetLineInfoV2(cd.f.Fd(), offset)
+}
+
+func (cd chardev) WatchLineInfo(info *uapi.LineInfoV2) error {
+	return uapi.WatchLineInfoV2(cd.f.Fd(), info)
+}
+
+func (cd chardev) UnwatchLineInfo(offset int) error {
+	return uapi.UnwatchLineInfo(cd.f.Fd(), uint32(offset))
+}
+
+func (cd chardev) InfoFd() int {
+	return int(cd.f.Fd())
+}
+
+func (cd chardev) GetLine(request *uapi.LineRequest) (LineBackend, error) {
+	err := uapi.GetLine(cd.f.Fd(), request)
+	if err != nil {
+		return nil, err
+	}
+	return chardevLine(request.Fd), nil
+}
+
+func (cd chardev) Close() error {
+	return cd.f.Close()
+}
+
+// chardevLine is the LineBackend for lines requested from a GPIO character
+// device.
+type chardevLine int32
+
+func (fd chardevLine) Fd() uintptr {
+	return uintptr(fd)
+}
+
+func (fd chardevLine) Values(values *uapi.LineValues) error {
+	return uapi.GetLineValuesV2(uintptr(fd), values)
+}
+
+func (fd chardevLine) SetValues(values uapi.LineValues) error {
+	return uapi.SetLineValuesV2(uintptr(fd), values)
+}
+
+func (fd chardevLine) SetConfig(config *uapi.LineConfig) error {
+	return uapi.SetLineConfigV2(uintptr(fd), config)
+}
+
+func (fd chardevLine) Close() error {
+	return unix.Close(int(fd))
+}
@@ -0,0 +1,467 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package button detects user interface gestures, such as clicks and long
+// presses, from the edge events on a push-button line.
+//
+// The button is pressed when the line is active, so a button that pulls the
+// line low should be requested as active low:
+//
+//	b, _ := button.New("gpiochip0", 27,
+//		button.WithRequestOptions(
+//			gpiocdev.AsActiveLow,
+//			gpiocdev.WithPullUp,
+//			gpiocdev.WithDebounce(10*time.Millisecond)))
+//	defer b.Close()
+//	for evt := range b.Events() {
+//		switch evt.Type {
+//		case button.Click:
+//		...
+//		}
+//	}
+//
+// Gesture timings are measured from the edge event timestamps, not from when
+// the events are read, so delays in reading events do not distort the
+// gestures.
+package button
+
+import (
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+	"golang.org/x/sys/unix"
+)
+
+// Button detects gestures on a push-button line.
+type Button struct {
+	l *gpiocdev.Line
+
+	longPress   time.Duration
+	repeat      time.Duration
+	doubleClick time.Duration
+
+	// the clock used for the event timestamps.
+	clock int32
+
+	events chan Event
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	pressed bool
+
+	// the timestamp of the most recent press.
+	pressTs time.Duration
+
+	// the timestamp of the most recent release.
+	releaseTs time.Duration
+
+	// indicates a long press has been reported for the current press.
+	long bool
+
+	// the number of repeats reported for the current press.
+	repeats int
+
+	// indicates a click is waiting to see if it becomes a double-click.
+	pendingClick bool
+
+	// indicates the current press is the second press of a double-click.
+	secondPress bool
+
+	// the timer for the pending long press, repeat or click.
+	timer *time.Timer
+
+	// identifies the current timer, so a stale timer can be ignored.
+	gen uint64
+
+	closed bool
+}
+
+// EventType identifies the type of button event.
+type EventType int
+
+const (
+	// Press indicates the button has been pressed.
+	Press EventType = iota
+
+	// Release indicates the button has been released.
+	Release
+
+	// Click indicates the button has been pressed and released, without a
+	// long press.
+	//
+	// If double-click detection is enabled then the click is only reported
+	// once the double-click interval has expired without a second press.
+	Click
+
+	// DoubleClick indicates two clicks in quick succession.
+	DoubleClick
+
+	// LongPress indicates the button has been held for the long press
+	// period.
+	LongPress
+
+	// Repeat indicates the button continues to be held after a long press.
+	Repeat
+)
+
+// Event describes a button event.
+type Event struct {
+	// The type of event.
+	Type EventType
+
+	// The time of the event, from the same clock as the line edge event
+	// timestamps.
+	//
+	// For LongPress and Repeat this is the time the button had been held
+	// for the period, and for Click it is the time of the release.
+	Timestamp time.Duration
+
+	// The number of the repeat, starting from 1.
+	//
+	// Only set for Repeat.
+	Count int
+}
+
+// Option defines the interface required to provide an option to New.
+type Option interface {
+	applyOption(*buttonOptions)
+}
+
+type buttonOptions struct {
+	longPress   time.Duration
+	repeat      time.Duration
+	doubleClick time.Duration
+	reqOpts     []gpiocdev.LineReqOption
+	chanSize    int
+}
+
+// LongPressOption sets the long press period.
+type LongPressOption time.Duration
+
+// WithLongPress sets the period the button must be held to be reported as a
+// long press.
+//
+// A period of 0 disables long press detection.
+//
+// The default is 1s.
+func WithLongPress(period time.Duration) LongPressOption {
+	return LongPressOption(period)
+}
+
+func (o LongPressOption) applyOption(opts *buttonOptions) {
+	opts.longPress = time.Duration(o)
+}
+
+// RepeatOption sets the repeat period.
+type RepeatOption time.Duration
+
+// WithRepeat sets the period between Repeat events while the button is held
+// after a long press.
+//
+// A period of 0 disables repeats.
+//
+// The default is 0.
+func WithRepeat(period time.Duration) RepeatOption {
+	return RepeatOption(period)
+}
+
+func (o RepeatOption) applyOption(opts *buttonOptions) {
+	opts.repeat = time.Duration(o)
+}
+
+// DoubleClickOption sets the double-click interval.
+type DoubleClickOption time.Duration
+
+// WithDoubleClick sets the maximum interval between the release of the first
+// click and the press of the second for two clicks to be reported as a
+// double-click.
+//
+// An interval of 0 disables double-click detection, so clicks are reported
+// immediately on release.
+//
+// The default is 300ms.
+func WithDoubleClick(interval time.Duration) DoubleClickOption {
+	return DoubleClickOption(interval)
+}
+
+func (o DoubleClickOption) applyOption(opts *buttonOptions) {
+	opts.doubleClick = time.Duration(o)
+}
+
+// ChannelSizeOption sets the size of the event channel.
+type ChannelSizeOption int
+
+// WithChannelSize sets the buffer size of the event channel.
+//
+// The default is 16.
+func WithChannelSize(size int) ChannelSizeOption {
+	return ChannelSizeOption(size)
+}
+
+func (o ChannelSizeOption) applyOption(opts *buttonOptions) {
+	opts.chanSize = int(o)
+}
+
+// RequestOption provides options for the line request.
+type RequestOption []gpiocdev.LineReqOption
+
+// WithRequestOptions provides options for the request of the button line, such
+// as active level, bias, debounce or consumer.
+//
+// Edge detection and event handling options are overridden by the button.
+func WithRequestOptions(options ...gpiocdev.LineReqOption) RequestOption {
+	return RequestOption(options)
+}
+
+func (o RequestOption) applyOption(opts *buttonOptions) {
+	opts.reqOpts = append(opts.reqOpts, o...)
+}
+
+// New requests the button line from the chip and starts detecting gestures.
+//
+// Returns unix.EINVAL if any of the timings are negative.
+func New(chip string, offset int, options ...Option) (*Button, error) {
+	opts := buttonOptions{
+		longPress:   time.Second,
+		doubleClick: 300 * time.Millisecond,
+		chanSize:    16,
+	}
+	for _, option := range options {
+		option.applyOption(&opts)
+	}
+	if opts.longPress < 0 || opts.repeat < 0 || opts.doubleClick < 0 {
+		return nil, unix.EINVAL
+	}
+	b := &Button{
+		longPress:   opts.longPress,
+		repeat:      opts.repeat,
+		doubleClick: opts.doubleClick,
+		clock:       unix.CLOCK_MONOTONIC,
+		events:      make(chan Event, opts.chanSize),
+	}
+	reqOpts := append([]gpiocdev.LineReqOption{}, opts.reqOpts...)
+	reqOpts = append(reqOpts,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(b.handle))
+	// hold the lock so events are not handled until the initial state is known.
+	b.mu.Lock()
+	l, err := gpiocdev.RequestLine(chip, offset, reqOpts...)
+	if err != nil {
+		b.mu.Unlock()
+		return nil, err
+	}
+	b.l = l
+	err = b.init()
+	b.mu.Unlock()
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// init determines the event clock and initial state of the button.
+//
+// Assumes b is locked.
+func (b *Button) init() error {
+	inf, err := b.l.Info()
+	if err != nil {
+		return err
+	}
+	if inf.Config.EventClock == gpiocdev.LineEventClockRealtime {
+		b.clock = unix.CLOCK_REALTIME
+	}
+	v, err := b.l.Value()
+	if err != nil {
+		return err
+	}
+	// a button held at startup is ignored until it is released.
+	b.pressed = v == 1
+	b.long = b.pressed
+	return nil
+}
+
+// Close releases the button line and closes the event channel.
+func (b *Button) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return gpiocdev.ErrClosed
+	}
+	b.closed = true
+	b.stopTimer()
+	b.mu.Unlock()
+	err := b.l.Close()
+	close(b.events)
+	return err
+}
+
+// Events returns the channel that button events are delivered to.
+//
+// If the channel is full then further events are discarded.
+//
+// The channel is closed when the button is closed.
+func (b *Button) Events() <-chan Event {
+	return b.events
+}
+
+// Pressed returns true if the button is currently pressed.
+func (b *Button) Pressed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pressed
+}
+
+// handle updates the button state from the edge event.
+func (b *Button) handle(evt gpiocdev.LineEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	switch evt.Type {
+	case gpiocdev.LineEventRisingEdge:
+		b.press(evt.Timestamp)
+	case gpiocdev.LineEventFallingEdge:
+		b.release(evt.Timestamp)
+	}
+}
+
+// press handles the button being pressed.
+//
+// Assumes b is locked.
+func (b *Button) press(ts time.Duration) {
+	if b.pressed {
+		return
+	}
+	b.stopTimer()
+	b.pressed = true
+	b.pressTs = ts
+	b.long = false
+	b.repeats = 0
+	b.secondPress = b.pendingClick && ts-b.releaseTs <= b.doubleClick
+	if !b.secondPress && b.pendingClick {
+		b.send(Event{Type: Click, Timestamp: b.releaseTs})
+	}
+	b.pendingClick = false
+	b.send(Event{Type: Press, Timestamp: ts})
+	if b.longPress > 0 {
+		b.startTimer(ts+b.longPress, b.longPressed)
+	}
+}
+
+// release handles the button being released.
+//
+// Assumes b is locked.
+func (b *Button) release(ts time.Duration) {
+	if !b.pressed {
+		return
+	}
+	b.stopTimer()
+	if !b.long && b.longPress > 0 && ts-b.pressTs >= b.longPress {
+		// the release was handled before the long press timer fired, such
+		// as when events are read late, but the press was long all the same.
+		b.longPressed()
+		b.stopTimer()
+	}
+	b.pressed = false
+	b.releaseTs = ts
+	b.send(Event{Type: Release, Timestamp: ts})
+	switch {
+	case b.long:
+	case b.secondPress:
+		b.send(Event{Type: DoubleClick, Timestamp: ts})
+	case b.doubleClick == 0:
+		b.send(Event{Type: Click, Timestamp: ts})
+	default:
+		b.pendingClick = true
+		b.startTimer(ts+b.doubleClick, b.clickExpired)
+	}
+	b.secondPress = false
+}
+
+// longPressed handles the long press period expiring.
+//
+// Assumes b is locked.
+func (b *Button) longPressed() {
+	if b.secondPress {
+		// the first click of the pair is a click after all.
+		b.send(Event{Type: Click, Timestamp: b.releaseTs})
+		b.secondPress = false
+	}
+	b.long = true
+	ts := b.pressTs + b.longPress
+	b.send(Event{Type: LongPress, Timestamp: ts})
+	if b.repeat > 0 {
+		b.startTimer(ts+b.repeat, b.repeated)
+	}
+}
+
+// repeated handles the repeat period expiring.
+//
+// Assumes b is locked.
+func (b *Button) repeated() {
+	b.repeats++
+	ts := b.pressTs + b.longPress + time.Duration(b.repeats)*b.repeat
+	b.send(Event{Type: Repeat, Timestamp: ts, Count: b.repeats})
+	b.startTimer(ts+b.repeat, b.repeated)
+}
+
+// clickExpired handles the double-click interval expiring.
+//
+// Assumes b is locked.
+func (b *Button) clickExpired() {
+	b.pendingClick = false
+	b.send(Event{Type: Click, Timestamp: b.releaseTs})
+}
+
+// startTimer calls f once the event clock reaches the deadline.
+//
+// Assumes b is locked.
+func (b *Button) startTimer(deadline time.Duration, f func()) {
+	gen := b.gen
+	d := deadline - b.now()
+	if d < 0 {
+		d = 0
+	}
+	b.timer = time.AfterFunc(d, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.gen != gen || b.closed {
+			return
+		}
+		b.timer = nil
+		f()
+	})
+}
+
+// stopTimer cancels any pending timer.
+//
+// Assumes b is locked.
+func (b *Button) stopTimer() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.gen++
+}
+
+// now returns the current time from the event clock.
+func (b *Button) now() time.Duration {
+	var ts unix.Timespec
+	unix.ClockGettime(b.clock, &ts)
+	return time.Duration(ts.Nano())
+}
+
+// send delivers the event to the channel, unless the channel is full.
+//
+// Assumes b is locked.
+func (b *Button) send(evt Event) {
+	select {
+	case b.events <- evt:
+	default:
+	}
+}
@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package button_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/button"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"golang.org/x/sys/unix"
+)
+
+const offset = 1
+
+func TestNew(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	_, err = button.New(s.ChipName(), offset, button.WithLongPress(-1))
+	assert.Equal(t, unix.EINVAL, err)
+
+	_, err = button.New(s.ChipName(), 4)
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	b, err := button.New(s.ChipName(), offset)
+	require.Nil(t, err)
+	assert.False(t, b.Pressed())
+	assert.Nil(t, b.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, b.Close())
+	_, ok := <-b.Events()
+	assert.False(t, ok)
+}
+
+func TestClick(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	b, err := button.New(s.ChipName(), offset, button.WithDoubleClick(0))
+	require.Nil(t, err)
+	defer b.Close()
+
+	s.Pullup(offset)
+	p := waitEvent(t, b, button.Press)
+	assert.True(t, b.Pressed())
+	s.Pulldown(offset)
+	r := waitEvent(t, b, button.Release)
+	assert.False(t, b.Pressed())
+	c := waitEvent(t, b, button.Click)
+	assert.Equal(t, r.Timestamp, c.Timestamp)
+	assert.Greater(t, r.Timestamp, p.Timestamp)
+}
+
+func TestDoubleClick(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	b, err := button.New(s.ChipName(), offset,
+		button.WithDoubleClick(100*time.Millisecond))
+	require.Nil(t, err)
+	defer b.Close()
+
+	// two clicks in quick succession
+	s.Pullup(offset)
+	waitEvent(t, b, button.Press)
+	s.Pulldown(offset)
+	waitEvent(t, b, button.Release)
+	s.Pullup(offset)
+	waitEvent(t, b, button.Press)
+	s.Pulldown(offset)
+	waitEvent(t, b, button.Release)
+	waitEvent(t, b, button.DoubleClick)
+
+	// a single click is reported once the interval expires
+	s.Pullup(offset)
+	waitEvent(t, b, button.Press)
+	s.Pulldown(offset)
+	r := waitEvent(t, b, button.Release)
+	start := time.Now()
+	c := waitEvent(t, b, button.Click)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, r.Timestamp, c.Timestamp)
+}
+
+func TestLongPress(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	b, err := button.New(s.ChipName(), offset,
+		button.WithLongPress(50*time.Millisecond),
+		button.WithRepeat(20*time.Millisecond))
+	require.Nil(t, err)
+	defer b.Close()
+
+	s.Pullup(offset)
+	p := waitEvent(t, b, button.Press)
+	l := waitEvent(t, b, button.LongPress)
+	assert.Equal(t, p.Timestamp+50*time.Millisecond, l.Timestamp)
+	r := waitEvent(t, b, button.Repeat)
+	assert.Equal(t, 1, r.Count)
+	assert.Equal(t, l.Timestamp+20*time.Millisecond, r.Timestamp)
+	r = waitEvent(t, b, button.Repeat)
+	assert.Equal(t, 2, r.Count)
+	s.Pulldown(offset)
+	waitEvent(t, b, button.Release)
+
+	// no click after a long press
+	select {
+	case evt := <-b.Events():
+		assert.Fail(t, "unexpected event", evt)
+	case <-time.After(400 * time.Millisecond):
+	}
+}
+
+func TestLongPressDelayed(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	// a blocked handler sharing the loop delays the delivery of the button
+	// events until after the button has been released.
+	el, err := gpiocdev.NewEventLoop()
+	require.Nil(t, err)
+	defer el.Close()
+	block := make(chan struct{})
+	l, err := gpiocdev.RequestLine(s.ChipName(), 0,
+		gpiocdev.WithRisingEdge,
+		gpiocdev.WithEventLoop(el),
+		gpiocdev.WithEventHandler(func(gpiocdev.LineEvent) { <-block }))
+	require.Nil(t, err)
+	defer l.Close()
+	b, err := button.New(s.ChipName(), offset,
+		button.WithLongPress(50*time.Millisecond),
+		button.WithRequestOptions(gpiocdev.WithEventLoop(el)))
+	require.Nil(t, err)
+	defer b.Close()
+
+	s.Pullup(0)
+	time.Sleep(10 * time.Millisecond)
+	s.Pullup(offset)
+	time.Sleep(60 * time.Millisecond)
+	s.Pulldown(offset)
+	close(block)
+
+	p := waitEvent(t, b, button.Press)
+	lp := waitEvent(t, b, button.LongPress)
+	assert.Equal(t, p.Timestamp+50*time.Millisecond, lp.Timestamp)
+	r := waitEvent(t, b, button.Release)
+	assert.GreaterOrEqual(t, r.Timestamp-p.Timestamp, 50*time.Millisecond)
+
+	// no click after a long press
+	select {
+	case evt := <-b.Events():
+		assert.Fail(t, "unexpected event", evt)
+	case <-time.After(400 * time.Millisecond):
+	}
+}
+
+func TestActiveLow(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	s.Pullup(offset)
+	b, err := button.New(s.ChipName(), offset,
+		button.WithDoubleClick(0),
+		button.WithRequestOptions(gpiocdev.AsActiveLow))
+	require.Nil(t, err)
+	defer b.Close()
+	assert.False(t, b.Pressed())
+
+	s.Pulldown(offset)
+	waitEvent(t, b, button.Press)
+	assert.True(t, b.Pressed())
+	s.Pullup(offset)
+	waitEvent(t, b, button.Release)
+	waitEvent(t, b, button.Click)
+}
+
+// waitEvent waits for the next event from the button, which must be of the
+// expected type.
+func waitEvent(t *testing.T, b *button.Button, xtype button.EventType) button.Event {
+	t.Helper()
+	select {
+	case evt := <-b.Events():
+		require.Equal(t, xtype, evt.Type)
+		return evt
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for event")
+	}
+	return button.Event{}
+}
@@ -0,0 +1,300 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// NamedLine identifies a named line.
+type NamedLine struct {
+	Name string
+	ChipOffset
+}
+
+// ErrDuplicateLine indicates a line name is used by more than one line.
+type ErrDuplicateLine struct {
+	Name string
+
+	// The lines with the name, in order of chip and offset.
+	Lines []ChipOffset
+}
+
+func (e ErrDuplicateLine) Error() string {
+	return "line " + e.Name + " is not unique"
+}
+
+// LineCatalog is an index of the named lines on the available chips.
+//
+// The chips are scanned once when the catalog is created, so resolving many
+// names is much cheaper than with FindLine or FindLines, which scan the chips
+// for each call.
+type LineCatalog struct {
+	// the watcher keeping the catalog up to date, if any.
+	cw *ChipWatcher
+
+	// mutex covers the attributes below it.
+	mu sync.RWMutex
+
+	// the lines with each name, in order of chip and offset.
+	lines map[string][]ChipOffset
+
+	// the names of the lines on each chip, keyed by chip name.
+	chips map[string][]string
+}
+
+// NewLineCatalog creates a catalog of the named lines on the available chips.
+//
+// The catalog is a snapshot and is not updated if chips are added or removed,
+// other than by Refresh.
+func NewLineCatalog() *LineCatalog {
+	lc := &LineCatalog{}
+	lc.Refresh()
+	return lc
+}
+
+// WatchLineCatalog creates a catalog of the named lines on the available
+// chips, that is updated as chips are added and removed.
+//
+// The catalog should be closed once no longer required, to stop watching for
+// chip changes.
+//
+// Returns the error from the watcher if it fails, or ErrClosed if its event
+// loop is closed, before the initial scan of the chips is complete.
+func WatchLineCatalog(options ...ChipWatcherOption) (*LineCatalog, error) {
+	lc := &LineCatalog{
+		lines: map[string][]ChipOffset{},
+		chips: map[string][]string{},
+	}
+	cw, err := NewChipWatcher(lc.handleChipChange, options...)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-cw.scanned:
+	case <-cw.failed:
+	case <-cw.loop.doneCh:
+	}
+	select {
+	case <-cw.scanned:
+	default:
+		// the watcher terminated before the scan.
+		cw.Close()
+		if err = cw.Err(); err == nil {
+			err = ErrClosed
+		}
+		return nil, err
+	}
+	lc.cw = cw
+	return lc, nil
+}
+
+// Close stops updating the catalog.
+func (lc *LineCatalog) Close() error {
+	if lc.cw == nil {
+		return nil
+	}
+	return lc.cw.Close()
+}
+
+// Refresh rescans the available chips.
+func (lc *LineCatalog) Refresh() {
+	lines := map[string][]ChipOffset{}
+	chips := map[string][]string{}
+	for _, chip := range Chips() {
+		names := chipLineNames(chip)
+		if names == nil {
+			continue
+		}
+		chips[chip] = names
+		for o, name := range names {
+			if name != "" {
+				lines[name] = append(lines[name], ChipOffset{chip, o})
+			}
+		}
+	}
+	lc.mu.Lock()
+	lc.lines = lines
+	lc.chips = chips
+	lc.mu.Unlock()
+}
+
+// Find returns the chip and offset of the named line.
+//
+// Returns ErrLineNotFound if no line has the name, and ErrDuplicateLine if
+// more than one line has the name.
+func (lc *LineCatalog) Find(name string) (ChipOffset, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.find(name)
+}
+
+// FindLines returns the chip and offset of each of the named lines.
+//
+// Returns ErrLineNotFound or ErrDuplicateLine for the first name that cannot
+// be resolved to a single line.
+func (lc *LineCatalog) FindLines(names ...string) ([]ChipOffset, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	lines := make([]ChipOffset, len(names))
+	for i, name := range names {
+		co, err := lc.find(name)
+		if err != nil {
+			return nil, err
+		}
+		lines[i] = co
+	}
+	return lines, nil
+}
+
+// find returns the chip and offset of the named line.
+//
+// Assumes lc is locked.
+func (lc *LineCatalog) find(name string) (ChipOffset, error) {
+	lines := lc.lines[name]
+	switch len(lines) {
+	case 0:
+		return ChipOffset{}, ErrLineNotFound{name}
+	case 1:
+		return lines[0], nil
+	}
+	return ChipOffset{}, ErrDuplicateLine{name, append([]ChipOffset(nil), lines...)}
+}
+
+// Lookup returns all the lines with the name, in order of chip and offset.
+func (lc *LineCatalog) Lookup(name string) []ChipOffset {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return append([]ChipOffset(nil), lc.lines[name]...)
+}
+
+// Names returns the names of all the named lines, in sorted order.
+func (lc *LineCatalog) Names() []string {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	names := make([]string, 0, len(lc.lines))
+	for name := range lc.lines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Duplicates returns the lines for each name used by more than one line.
+func (lc *LineCatalog) Duplicates() map[string][]ChipOffset {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	dups := map[string][]ChipOffset{}
+	for name, lines := range lc.lines {
+		if len(lines) > 1 {
+			dups[name] = append([]ChipOffset(nil), lines...)
+		}
+	}
+	return dups
+}
+
+// Glob returns the lines with names matching the shell pattern, as per
+// path.Match, in order of name, chip and offset.
+//
+// Returns path.ErrBadPattern if the pattern is malformed.
+func (lc *LineCatalog) Glob(pattern string) ([]NamedLine, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return lc.match(func(name string) bool {
+		m, _ := path.Match(pattern, name)
+		return m
+	}), nil
+}
+
+// Regexp returns the lines with names matching the regular expression, in
+// order of name, chip and offset.
+func (lc *LineCatalog) Regexp(re *regexp.Regexp) []NamedLine {
+	return lc.match(re.MatchString)
+}
+
+// match returns the lines with names matching the filter, in order of name,
+// chip and offset.
+func (lc *LineCatalog) match(filter func(name string) bool) []NamedLine {
+	var names []string
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	for name := range lc.lines {
+		if filter(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var lines []NamedLine
+	for _, name := range names {
+		for _, co := range lc.lines[name] {
+			lines = append(lines, NamedLine{name, co})
+		}
+	}
+	return lines
+}
+
+// handleChipChange updates the catalog for the added or removed chip.
+func (lc *LineCatalog) handleChipChange(cce ChipChangeEvent) {
+	var names []string
+	if cce.Type == ChipAdded {
+		names = chipLineNames(cce.Name)
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	for _, name := range lc.chips[cce.Name] {
+		lines := lc.lines[name][:0]
+		for _, co := range lc.lines[name] {
+			if co.Chip != cce.Name {
+				lines = append(lines, co)
+			}
+		}
+		if len(lines) == 0 {
+			delete(lc.lines, name)
+		} else {
+			lc.lines[name] = lines
+		}
+	}
+	delete(lc.chips, cce.Name)
+	if names == nil {
+		return
+	}
+	lc.chips[cce.Name] = names
+	for o, name := range names {
+		if name == "" {
+			continue
+		}
+		lines := append(lc.lines[name], ChipOffset{cce.Name, o})
+		sort.Slice(lines, func(i, j int) bool {
+			if lines[i].Chip != lines[j].Chip {
+				return naturalLess(lines[i].Chip, lines[j].Chip)
+			}
+			return lines[i].Offset < lines[j].Offset
+		})
+		lc.lines[name] = lines
+	}
+}
+
+// chipLineNames returns the names of the lines on the chip, indexed by
+// offset, or nil if the chip cannot be read.
+func chipLineNames(chip string) []string {
+	c, err := NewChip(chip)
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+	names := make([]string, c.lines)
+	for o := range names {
+		inf, err := c.LineInfo(o)
+		if err != nil {
+			return nil
+		}
+		names[o] = inf.Name
+	}
+	return names
+}
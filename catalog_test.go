@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev_test
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/sim"
+)
+
+// newCatalogChips creates a pair of simulated chips with named lines, closed
+// when the test ends.
+func newCatalogChips(t *testing.T) (*sim.Chip, *sim.Chip) {
+	t.Helper()
+	s1, err := sim.NewChip(8,
+		sim.WithNamedLine(0, "cat-led-red"),
+		sim.WithNamedLine(1, "cat-led-green"),
+		sim.WithNamedLine(4, "cat-dup"))
+	require.Nil(t, err)
+	t.Cleanup(func() { s1.Close() })
+	s2, err := sim.NewChip(8,
+		sim.WithNamedLine(2, "cat-button"),
+		sim.WithNamedLine(3, "cat-dup"))
+	require.Nil(t, err)
+	t.Cleanup(func() { s2.Close() })
+	return s1, s2
+}
+
+func TestLineCatalog(t *testing.T) {
+	s1, s2 := newCatalogChips(t)
+	lc := gpiocdev.NewLineCatalog()
+
+	co, err := lc.Find("cat-button")
+	assert.Nil(t, err)
+	assert.Equal(t, gpiocdev.ChipOffset{Chip: s2.ChipName(), Offset: 2}, co)
+
+	_, err = lc.Find("cat-missing")
+	assert.Equal(t, gpiocdev.ErrLineNotFound{Name: "cat-missing"}, err)
+	assert.True(t, errors.Is(err, gpiocdev.ErrNotFound))
+
+	dups := []gpiocdev.ChipOffset{
+		{Chip: s1.ChipName(), Offset: 4},
+		{Chip: s2.ChipName(), Offset: 3},
+	}
+	_, err = lc.Find("cat-dup")
+	assert.Equal(t, gpiocdev.ErrDuplicateLine{Name: "cat-dup", Lines: dups}, err)
+	assert.Equal(t, dups, lc.Lookup("cat-dup"))
+	assert.Equal(t, dups, lc.Duplicates()["cat-dup"])
+	assert.Empty(t, lc.Lookup("cat-missing"))
+
+	lines, err := lc.FindLines("cat-led-green", "cat-button")
+	assert.Nil(t, err)
+	assert.Equal(t, []gpiocdev.ChipOffset{
+		{Chip: s1.ChipName(), Offset: 1},
+		{Chip: s2.ChipName(), Offset: 2},
+	}, lines)
+	_, err = lc.FindLines("cat-led-green", "cat-dup")
+	assert.Equal(t, gpiocdev.ErrDuplicateLine{Name: "cat-dup", Lines: dups}, err)
+
+	names := lc.Names()
+	assert.Subset(t, names, []string{"cat-button", "cat-dup", "cat-led-green", "cat-led-red"})
+
+	leds, err := lc.Glob("cat-led-*")
+	assert.Nil(t, err)
+	assert.Equal(t, []gpiocdev.NamedLine{
+		{Name: "cat-led-green", ChipOffset: gpiocdev.ChipOffset{Chip: s1.ChipName(), Offset: 1}},
+		{Name: "cat-led-red", ChipOffset: gpiocdev.ChipOffset{Chip: s1.ChipName(), Offset: 0}},
+	}, leds)
+	_, err = lc.Glob("[")
+	assert.Equal(t, path.ErrBadPattern, err)
+
+	matches := lc.Regexp(regexp.MustCompile("^cat-(button|dup)$"))
+	assert.Equal(t, []gpiocdev.NamedLine{
+		{Name: "cat-button", ChipOffset: gpiocdev.ChipOffset{Chip: s2.ChipName(), Offset: 2}},
+		{Name: "cat-dup", ChipOffset: dups[0]},
+		{Name: "cat-dup", ChipOffset: dups[1]},
+	}, matches)
+
+	// snapshot until refreshed
+	s2.Close()
+	assert.Equal(t, dups, lc.Lookup("cat-dup"))
+	lc.Refresh()
+	co, err = lc.Find("cat-dup")
+	assert.Nil(t, err)
+	assert.Equal(t, dups[0], co)
+	_, err = lc.Find("cat-button")
+	assert.Equal(t, gpiocdev.ErrLineNotFound{Name: "cat-button"}, err)
+	assert.Nil(t, lc.Close())
+}
+
+func TestWatchLineCatalog(t *testing.T) {
+	s1, s2 := newCatalogChips(t)
+
+	lc, err := gpiocdev.WatchLineCatalog()
+	require.Nil(t, err)
+	defer lc.Close()
+
+	// populated on return
+	dups := []gpiocdev.ChipOffset{
+		{Chip: s1.ChipName(), Offset: 4},
+		{Chip: s2.ChipName(), Offset: 3},
+	}
+	assert.Equal(t, dups, lc.Lookup("cat-dup"))
+
+	s2.Close()
+	waitCatalog(t, func() bool { return len(lc.Lookup("cat-dup")) == 1 })
+	_, err = lc.Find("cat-button")
+	assert.Equal(t, gpiocdev.ErrLineNotFound{Name: "cat-button"}, err)
+
+	s3, err := sim.NewChip(4, sim.WithNamedLine(1, "cat-dup"), sim.WithNamedLine(3, "cat-new"))
+	require.Nil(t, err)
+	defer s3.Close()
+	waitCatalog(t, func() bool { return len(lc.Lookup("cat-new")) == 1 })
+	assert.Equal(t, []gpiocdev.ChipOffset{
+		{Chip: s1.ChipName(), Offset: 4},
+		{Chip: s3.ChipName(), Offset: 1},
+	}, lc.Lookup("cat-dup"))
+
+	assert.Nil(t, lc.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, lc.Close())
+}
+
+// waitCatalog waits for the catalog to be updated.
+func waitCatalog(t *testing.T, updated func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !updated() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	require.True(t, updated(), "timeout waiting for catalog update")
+}
@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LineNamesSource identifies where the names of the lines of a chip come from.
+type LineNamesSource int
+
+const (
+	// LineNamesNone indicates the lines are not named.
+	LineNamesNone LineNamesSource = iota
+
+	// LineNamesDriver indicates the line names are provided by the chip
+	// driver.
+	LineNamesDriver
+
+	// LineNamesDeviceTree indicates the line names are provided by the
+	// gpio-line-names property of the device tree node of the chip.
+	LineNamesDeviceTree
+
+	// LineNamesFirmware indicates the line names are provided by firmware
+	// other than the device tree, such as the ACPI _DSD gpio-line-names
+	// property.
+	LineNamesFirmware
+)
+
+// ChipDetails contains details of a chip, and the device providing it, beyond
+// those provided by the Chip.
+//
+// The details are read from sysfs, so are only available for GPIO character
+// devices, and then only those attributes provided by the kernel and firmware.
+// Unavailable attributes are left empty.
+type ChipDetails struct {
+	// The system name for the chip.
+	Name string
+
+	// The label of the chip.
+	Label string
+
+	// The number of lines on the chip.
+	Lines int
+
+	// The sysfs path of the chip device.
+	Path string
+
+	// The name of the parent device providing the chip, such as 1-0020 for
+	// an I2C expander at address 0x20 on bus 1.
+	Parent string
+
+	// The subsystem of the parent device, such as platform, i2c or usb.
+	Subsystem string
+
+	// The name of the driver bound to the parent device.
+	Driver string
+
+	// The GPIO number of the first line of the chip in the deprecated sysfs
+	// GPIO interface, or -1 if not available.
+	Base int
+
+	// The path of the device tree node of the chip, such as
+	// /soc/gpio@7e200000.
+	DTNode string
+
+	// The compatible strings of the device tree node of the chip.
+	Compatible []string
+
+	// The source of the line names.
+	LineNames LineNamesSource
+}
+
+// the root of the sysfs devicetree tree.
+const dtBase = "/sys/firmware/devicetree/base"
+
+// Details returns the details of the chip.
+func (c *Chip) Details() (ChipDetails, error) {
+	cd := ChipDetails{
+		Name:  c.Name,
+		Label: c.Label,
+		Lines: c.lines,
+		Base:  -1,
+	}
+	named := false
+	for o := 0; o < c.lines; o++ {
+		inf, err := c.LineInfo(o)
+		if err != nil {
+			return cd, err
+		}
+		if inf.Name != "" {
+			named = true
+			break
+		}
+	}
+	if named {
+		cd.LineNames = LineNamesDriver
+	}
+	if c.f == nil {
+		// only GPIO character devices appear in sysfs.
+		return cd, nil
+	}
+	path, err := filepath.EvalSymlinks("/sys/bus/gpio/devices/" + c.Name)
+	if err != nil {
+		return cd, err
+	}
+	cd.Path = path
+	parent := filepath.Dir(path)
+	cd.Parent = filepath.Base(parent)
+	cd.Subsystem = linkBase(filepath.Join(parent, "subsystem"))
+	cd.Driver = linkBase(filepath.Join(parent, "driver"))
+	cd.Base = legacyBase(parent, c.Label, c.lines)
+	// the chip device shares the node of its parent, unless the parent
+	// provides several chips, each with its own node.
+	node, err := filepath.EvalSymlinks(filepath.Join(path, "of_node"))
+	if err != nil {
+		node, err = filepath.EvalSymlinks(filepath.Join(parent, "of_node"))
+	}
+	if err == nil {
+		cd.DTNode = strings.TrimPrefix(node, dtBase)
+		if b, err := os.ReadFile(filepath.Join(node, "compatible")); err == nil {
+			for _, s := range strings.Split(string(b), "\x00") {
+				if s != "" {
+					cd.Compatible = append(cd.Compatible, s)
+				}
+			}
+		}
+		if _, err := os.Stat(filepath.Join(node, "gpio-line-names")); err == nil {
+			cd.LineNames = LineNamesDeviceTree
+		}
+	} else if named {
+		if _, err := os.Stat(filepath.Join(parent, "firmware_node")); err == nil {
+			cd.LineNames = LineNamesFirmware
+		}
+	}
+	return cd, nil
+}
+
+// linkBase returns the last element of the target of the symlink, or "" if it
+// is not a symlink.
+func linkBase(path string) string {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// legacyBase returns the base of the chip in the deprecated sysfs GPIO
+// interface, or -1 if the interface is not available.
+func legacyBase(parent, label string, lines int) int {
+	dirs, err := filepath.Glob(filepath.Join(parent, "gpio", "gpiochip*"))
+	if err != nil {
+		return -1
+	}
+	for _, dir := range dirs {
+		if readAttr(dir, "label") != label ||
+			readAttr(dir, "ngpio") != strconv.Itoa(lines) {
+			continue
+		}
+		if base, err := strconv.Atoi(readAttr(dir, "base")); err == nil {
+			return base
+		}
+	}
+	return -1
+}
+
+// readAttr returns the value of the sysfs attribute, or "" if it cannot be
+// read.
+func readAttr(dir, attr string) string {
+	b, err := os.ReadFile(filepath.Join(dir, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// ChipSelector identifies a chip by attributes other than its name.
+//
+// Only the attributes that are set are matched.
+type ChipSelector struct {
+	// The label of the chip.
+	Label string
+
+	// The name of the parent device, such as 1-0020.
+	Parent string
+
+	// The path of the device tree node, such as /soc/gpio@7e200000.
+	DTNode string
+}
+
+// FindChip returns the name of the first chip, in the order returned by
+// Chips, matching the selector.
+//
+// The name can then be used to open the chip, or request lines from it:
+//
+//	name, _ := gpiocdev.FindChip(gpiocdev.ChipSelector{Parent: "1-0020"})
+//	c, _ := gpiocdev.NewChip(name)
+//
+// Returns ErrNotFound if no chip matches.
+func FindChip(sel ChipSelector) (string, error) {
+	for _, name := range Chips() {
+		c, err := NewChip(name)
+		if err != nil {
+			continue
+		}
+		match := sel.match(c)
+		c.Close()
+		if match {
+			return name, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// match returns true if the chip matches the selector.
+func (sel ChipSelector) match(c *Chip) bool {
+	if sel.Label != "" && sel.Label != c.Label {
+		return false
+	}
+	if sel.Parent == "" && sel.DTNode == "" {
+		return true
+	}
+	cd, err := c.Details()
+	if err != nil {
+		return false
+	}
+	return (sel.Parent == "" || sel.Parent == cd.Parent) &&
+		(sel.DTNode == "" || sel.DTNode == cd.DTNode)
+}
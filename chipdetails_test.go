@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"github.com/warthog618/go-gpiosim"
+)
+
+func TestChipDetails(t *testing.T) {
+	s, err := sim.NewChip(4, sim.WithLabel("details"))
+	require.Nil(t, err)
+	defer s.Close()
+
+	c, err := gpiocdev.NewChip(s.ChipName())
+	require.Nil(t, err)
+	cd, err := c.Details()
+	assert.Nil(t, err)
+	// no sysfs for simulated chips
+	assert.Equal(t, gpiocdev.ChipDetails{
+		Name:      s.ChipName(),
+		Label:     "details",
+		Lines:     4,
+		Base:      -1,
+		LineNames: gpiocdev.LineNamesNone,
+	}, cd)
+	c.Close()
+	_, err = c.Details()
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+
+	s2, err := sim.NewChip(4, sim.WithLabel("named"), sim.WithNamedLine(2, "blue"))
+	require.Nil(t, err)
+	defer s2.Close()
+	c, err = gpiocdev.NewChip(s2.ChipName())
+	require.Nil(t, err)
+	defer c.Close()
+	cd, err = c.Details()
+	assert.Nil(t, err)
+	assert.Equal(t, gpiocdev.LineNamesDriver, cd.LineNames)
+}
+
+func TestFindChip(t *testing.T) {
+	s1, err := sim.NewChip(4, sim.WithLabel("find-1"))
+	require.Nil(t, err)
+	defer s1.Close()
+	s2, err := sim.NewChip(4, sim.WithLabel("find-2"))
+	require.Nil(t, err)
+	defer s2.Close()
+
+	name, err := gpiocdev.FindChip(gpiocdev.ChipSelector{Label: "find-2"})
+	assert.Nil(t, err)
+	assert.Equal(t, s2.ChipName(), name)
+
+	_, err = gpiocdev.FindChip(gpiocdev.ChipSelector{Label: "find-3"})
+	assert.Equal(t, gpiocdev.ErrNotFound, err)
+
+	_, err = gpiocdev.FindChip(gpiocdev.ChipSelector{Label: "find-1", Parent: "1-0020"})
+	assert.Equal(t, gpiocdev.ErrNotFound, err)
+
+	_, err = gpiocdev.FindChip(gpiocdev.ChipSelector{DTNode: "/soc/gpio@7e200000"})
+	assert.Equal(t, gpiocdev.ErrNotFound, err)
+}
+
+func TestChipDetailsSysfs(t *testing.T) {
+	s, err := gpiosim.NewSim(
+		gpiosim.WithName("gpiocdev_test"),
+		gpiosim.WithBank(gpiosim.NewBank("details-a", 4)),
+		gpiosim.WithBank(gpiosim.NewBank("details-b", 8,
+			gpiosim.WithNamedLine(2, "blue"),
+		)),
+	)
+	require.Nil(t, err)
+	defer s.Close()
+
+	c := getChip(t, s.Chips[0].DevPath())
+	cda, err := c.Details()
+	c.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, s.Chips[0].ChipName(), cda.Name)
+	assert.Equal(t, "details-a", cda.Label)
+	assert.Equal(t, 4, cda.Lines)
+	// gpio-sim chips are provided by a platform device, with no device tree
+	// node.
+	assert.Regexp(t, `^gpio-sim\.\d+$`, cda.Parent)
+	assert.Equal(t, "/sys/devices/platform/"+cda.Parent+"/"+cda.Name, cda.Path)
+	assert.Equal(t, "platform", cda.Subsystem)
+	assert.Equal(t, "gpio-sim", cda.Driver)
+	assert.Empty(t, cda.DTNode)
+	assert.Empty(t, cda.Compatible)
+	assert.Equal(t, gpiocdev.LineNamesNone, cda.LineNames)
+
+	c = getChip(t, s.Chips[1].DevPath())
+	defer c.Close()
+	cdb, err := c.Details()
+	assert.Nil(t, err)
+	assert.Equal(t, s.Chips[1].ChipName(), cdb.Name)
+	assert.Equal(t, "details-b", cdb.Label)
+	assert.Equal(t, 8, cdb.Lines)
+	// both banks are provided by the one device.
+	assert.Equal(t, cda.Parent, cdb.Parent)
+	assert.Equal(t, "gpio-sim", cdb.Driver)
+	assert.Equal(t, gpiocdev.LineNamesDriver, cdb.LineNames)
+}
+
+func TestFindChipSysfs(t *testing.T) {
+	s, err := gpiosim.NewSim(
+		gpiosim.WithName("gpiocdev_test"),
+		gpiosim.WithBank(gpiosim.NewBank("find-a", 4)),
+		gpiosim.WithBank(gpiosim.NewBank("find-b", 4)),
+	)
+	require.Nil(t, err)
+	defer s.Close()
+
+	c := getChip(t, s.Chips[0].DevPath())
+	cd, err := c.Details()
+	c.Close()
+	require.Nil(t, err)
+
+	name, err := gpiocdev.FindChip(gpiocdev.ChipSelector{Parent: cd.Parent})
+	assert.Nil(t, err)
+	assert.Contains(t, []string{s.Chips[0].ChipName(), s.Chips[1].ChipName()}, name)
+
+	name, err = gpiocdev.FindChip(gpiocdev.ChipSelector{Label: "find-b", Parent: cd.Parent})
+	assert.Nil(t, err)
+	assert.Equal(t, s.Chips[1].ChipName(), name)
+
+	_, err = gpiocdev.FindChip(gpiocdev.ChipSelector{Label: "find-b", Parent: "gpio-sim.none"})
+	assert.Equal(t, gpiocdev.ErrNotFound, err)
+
+	// the DTNode must match too, and gpio-sim chips have none.
+	_, err = gpiocdev.FindChip(gpiocdev.ChipSelector{Parent: cd.Parent, DTNode: "/soc/gpio@7e200000"})
+	assert.Equal(t, gpiocdev.ErrNotFound, err)
+}
@@ -0,0 +1,436 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ChipChangeType indicates the type of change to the available chips.
+type ChipChangeType int
+
+const (
+	_ ChipChangeType = iota
+
+	// ChipAdded indicates a chip has become available.
+	ChipAdded
+
+	// ChipRemoved indicates a chip is no longer available.
+	ChipRemoved
+)
+
+// ChipChangeEvent represents a change to the available chips.
+type ChipChangeEvent struct {
+	// The type of change.
+	Type ChipChangeType
+
+	// The name of the chip.
+	Name string
+
+	// The label of the chip.
+	Label string
+
+	// The number of lines on the chip.
+	Lines int
+}
+
+// ChipChangeHandler is a receiver for changes to the available chips.
+type ChipChangeHandler func(ChipChangeEvent)
+
+// ChipWatcherOption defines the interface required to provide an option for
+// NewChipWatcher.
+type ChipWatcherOption interface {
+	applyChipWatcherOption(*chipWatcherOptions)
+}
+
+type chipWatcherOptions struct {
+	errh ErrorHandler
+	loop *EventLoop
+}
+
+func (o ErrorHandler) applyChipWatcherOption(cwo *chipWatcherOptions) {
+	cwo.errh = o
+}
+
+func (el *EventLoop) applyChipWatcherOption(cwo *chipWatcherOptions) {
+	cwo.loop = el
+}
+
+// ChipWatcher reports chips being added and removed, such as USB GPIO adapters
+// being plugged in, or the drivers of GPIO expanders being bound and unbound.
+//
+// GPIO character devices are detected using inotify on /dev, and chips
+// provided by alternative backends are detected as they are registered and
+// unregistered.
+type ChipWatcher struct {
+	loopWatcher
+
+	// the handler for chip changes.
+	ch ChipChangeHandler
+
+	// the inotify fd watching /dev.
+	infd int
+
+	// the eventfd signalled when the registered backends change.
+	befd int
+
+	// the chips known to be available, keyed by name.
+	//
+	// Only accessed from the loop goroutine.
+	chips map[string]ChipChangeEvent
+
+	// closed once the initial scan is complete.
+	scanned chan struct{}
+
+	// closed if reading the fds fails.
+	failed chan struct{}
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	closed bool
+}
+
+// NewChipWatcher creates a watcher that calls the handler whenever a chip is
+// added or removed.
+//
+// The handler is initially called with a ChipAdded event for each chip
+// available when the watcher is created.  The handler is called from the
+// watcher's event loop goroutine.
+//
+// Chips that cannot be opened, such as for lack of permissions, are not
+// reported.  If the permissions of a GPIO character device are later changed,
+// such as by udev, then the chip is reported once it can be opened.
+func NewChipWatcher(ch ChipChangeHandler, options ...ChipWatcherOption) (cw *ChipWatcher, err error) {
+	cwo := chipWatcherOptions{}
+	for _, option := range options {
+		option.applyChipWatcherOption(&cwo)
+	}
+	infd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			unix.Close(infd)
+		}
+	}()
+	_, err = unix.InotifyAddWatch(infd, "/dev",
+		unix.IN_CREATE|unix.IN_DELETE|unix.IN_ATTRIB|unix.IN_MOVED_FROM|unix.IN_MOVED_TO)
+	if err != nil {
+		return nil, err
+	}
+	befd, err := unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			unix.Close(befd)
+		}
+	}()
+	cw = &ChipWatcher{
+		ch:      ch,
+		infd:    infd,
+		befd:    befd,
+		chips:   map[string]ChipChangeEvent{},
+		scanned: make(chan struct{}),
+		failed:  make(chan struct{}),
+	}
+	cw.errh = func(err error) {
+		// called from the loop goroutine, once for each failed fd.
+		select {
+		case <-cw.failed:
+		default:
+			close(cw.failed)
+		}
+		if cwo.errh != nil {
+			cwo.errh(err)
+		}
+	}
+	addBackendWatcher(befd)
+	// trigger the initial scan from the loop.
+	unix.Write(befd, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+	if err = cw.watch(cwo.loop, []int{infd, befd}, cw.read); err != nil {
+		removeBackendWatcher(befd)
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Close stops the watcher.
+//
+// Close waits for any running handler to return, so must not be called from
+// the context of the handler.
+func (cw *ChipWatcher) Close() error {
+	cw.mu.Lock()
+	closed := cw.closed
+	cw.closed = true
+	cw.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	removeBackendWatcher(cw.befd)
+	cw.unwatch()
+	unix.Close(cw.infd)
+	unix.Close(cw.befd)
+	return nil
+}
+
+func (cw *ChipWatcher) read(fd int) error {
+	if fd == cw.befd {
+		var buf [8]byte
+		if _, err := unix.Read(fd, buf[:]); err != nil {
+			return err
+		}
+		cw.scan()
+		select {
+		case <-cw.scanned:
+		default:
+			close(cw.scanned)
+		}
+		return nil
+	}
+	var buf [4096]byte
+	n, err := unix.Read(fd, buf[:])
+	if err != nil {
+		return err
+	}
+	for off := 0; off+unix.SizeofInotifyEvent <= n; {
+		evt := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+		nameStart := off + unix.SizeofInotifyEvent
+		off = nameStart + int(evt.Len)
+		if off > n {
+			break
+		}
+		if evt.Mask&unix.IN_Q_OVERFLOW != 0 {
+			// events have been lost, so rescan to catch up.
+			cw.scan()
+			continue
+		}
+		name := string(bytes.TrimRight(buf[nameStart:off], "\x00"))
+		if !strings.HasPrefix(name, "gpiochip") {
+			continue
+		}
+		if evt.Mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0 {
+			cw.remove(name)
+		} else {
+			cw.add(name)
+		}
+	}
+	return nil
+}
+
+// scan compares the available chips with those known, and reports any
+// changes.
+func (cw *ChipWatcher) scan() {
+	available := map[string]bool{}
+	for _, name := range Chips() {
+		available[name] = true
+		cw.add(name)
+	}
+	for name := range cw.chips {
+		if !available[name] {
+			cw.remove(name)
+		}
+	}
+}
+
+// add reports the chip as added, if it is not already known and can be
+// opened.
+func (cw *ChipWatcher) add(name string) {
+	if _, ok := cw.chips[name]; ok {
+		return
+	}
+	c, err := NewChip(name)
+	if err != nil {
+		return
+	}
+	cce := ChipChangeEvent{
+		Type:  ChipAdded,
+		Name:  name,
+		Label: c.Label,
+		Lines: c.Lines(),
+	}
+	c.Close()
+	cw.chips[name] = cce
+	cw.ch(cce)
+}
+
+// remove reports the chip as removed, if it is known.
+func (cw *ChipWatcher) remove(name string) {
+	cce, ok := cw.chips[name]
+	if !ok {
+		return
+	}
+	delete(cw.chips, name)
+	cce.Type = ChipRemoved
+	cw.ch(cce)
+}
+
+// PersistentLinesHandler receives changes to the request of a
+// PersistentLines.
+//
+// When the lines are requested, l is the request and err is nil.  If the
+// request fails then l is nil and err is the error.  When the chip is removed,
+// l is nil and err is unix.ENODEV.
+type PersistentLinesHandler func(l *Lines, err error)
+
+// PersistentLines maintains a request for a set of lines on a chip identified
+// by its label, re-requesting the lines whenever the chip is re-added.
+//
+// This allows lines on hotpluggable chips, such as USB GPIO adapters, to
+// survive the chip being unplugged and plugged in again, possibly with a
+// different name.
+type PersistentLines struct {
+	cw *ChipWatcher
+
+	label   string
+	offsets []int
+	options []LineReqOption
+	h       PersistentLinesHandler
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	// the current request, if the chip is available.
+	lines *Lines
+
+	// the name of the chip the lines are requested from.
+	chip string
+
+	closed bool
+}
+
+// RequestPersistentLines requests the lines from the chip with the label, if
+// available, and again whenever a chip with the label is added.
+//
+// The lines are requested from the watcher goroutine, so may not be requested
+// by the time RequestPersistentLines returns.  The handler, which may be nil,
+// is called whenever the lines are requested or lost.  If several chips have
+// the label then the lines are requested from the first found.
+//
+// If the request fails when the chip is added, such as with unix.EBUSY as the
+// lines are held by another consumer, then the request is not attempted again
+// until the chip is re-added, or Retry is called.
+func RequestPersistentLines(label string, offsets []int, h PersistentLinesHandler, options ...LineReqOption) (*PersistentLines, error) {
+	pl := &PersistentLines{
+		label:   label,
+		offsets: append([]int(nil), offsets...),
+		options: append([]LineReqOption(nil), options...),
+		h:       h,
+	}
+	cw, err := NewChipWatcher(pl.handle)
+	if err != nil {
+		return nil, err
+	}
+	pl.cw = cw
+	return pl, nil
+}
+
+// Close stops watching for the chip, and releases the lines if requested.
+func (pl *PersistentLines) Close() error {
+	pl.mu.Lock()
+	closed := pl.closed
+	pl.closed = true
+	pl.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	pl.cw.Close()
+	pl.mu.Lock()
+	l := pl.lines
+	pl.lines = nil
+	pl.mu.Unlock()
+	if l != nil {
+		return l.Close()
+	}
+	return nil
+}
+
+// Retry requests the lines from the chip with the label, if they are not
+// already requested, such as after a failed request.
+//
+// The handler is called with the outcome, as for the chip being added.
+//
+// Returns ErrNotFound if no chip with the label is available, or the error
+// from the request.
+func (pl *PersistentLines) Retry() error {
+	pl.mu.Lock()
+	if pl.closed {
+		pl.mu.Unlock()
+		return ErrClosed
+	}
+	if pl.lines != nil {
+		pl.mu.Unlock()
+		return nil
+	}
+	chip, err := FindChip(ChipSelector{Label: pl.label})
+	if err != nil {
+		pl.mu.Unlock()
+		return err
+	}
+	l, err := pl.request(chip)
+	pl.mu.Unlock() // handler called outside lock
+	if pl.h != nil {
+		pl.h(l, err)
+	}
+	return err
+}
+
+// request requests the lines from the chip.
+//
+// Assumes pl is locked.
+func (pl *PersistentLines) request(chip string) (*Lines, error) {
+	l, err := RequestLines(chip, pl.offsets, pl.options...)
+	if err == nil {
+		pl.lines = l
+		pl.chip = chip
+	}
+	return l, err
+}
+
+// Lines returns the current request, or nil if the lines are not currently
+// requested.
+func (pl *PersistentLines) Lines() *Lines {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.lines
+}
+
+func (pl *PersistentLines) handle(cce ChipChangeEvent) {
+	pl.mu.Lock()
+	if pl.closed {
+		pl.mu.Unlock()
+		return
+	}
+	var l *Lines
+	var err error
+	switch cce.Type {
+	case ChipAdded:
+		if pl.lines != nil || cce.Label != pl.label {
+			pl.mu.Unlock()
+			return
+		}
+		l, err = pl.request(cce.Name)
+	case ChipRemoved:
+		if pl.lines == nil || cce.Name != pl.chip {
+			pl.mu.Unlock()
+			return
+		}
+		pl.lines.Close()
+		pl.lines = nil
+		err = unix.ENODEV
+	}
+	pl.mu.Unlock() // handler called outside lock
+	if pl.h != nil {
+		pl.h(l, err)
+	}
+}
@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"golang.org/x/sys/unix"
+)
+
+func TestChipWatcher(t *testing.T) {
+	s1, err := sim.NewChip(4, sim.WithLabel("watched-1"))
+	require.Nil(t, err)
+	defer s1.Close()
+
+	ch := make(chan gpiocdev.ChipChangeEvent, 10)
+	cw, err := gpiocdev.NewChipWatcher(func(cce gpiocdev.ChipChangeEvent) {
+		if cce.Label == "watched-1" || cce.Label == "watched-2" {
+			ch <- cce
+		}
+	})
+	require.Nil(t, err)
+
+	// existing chips
+	cce := waitChipChange(t, ch)
+	assert.Equal(t, gpiocdev.ChipChangeEvent{
+		Type:  gpiocdev.ChipAdded,
+		Name:  s1.ChipName(),
+		Label: "watched-1",
+		Lines: 4,
+	}, cce)
+
+	s2, err := sim.NewChip(6, sim.WithLabel("watched-2"))
+	require.Nil(t, err)
+	cce = waitChipChange(t, ch)
+	assert.Equal(t, gpiocdev.ChipChangeEvent{
+		Type:  gpiocdev.ChipAdded,
+		Name:  s2.ChipName(),
+		Label: "watched-2",
+		Lines: 6,
+	}, cce)
+
+	s2.Close()
+	cce = waitChipChange(t, ch)
+	assert.Equal(t, gpiocdev.ChipChangeEvent{
+		Type:  gpiocdev.ChipRemoved,
+		Name:  s2.ChipName(),
+		Label: "watched-2",
+		Lines: 6,
+	}, cce)
+
+	assert.Nil(t, cw.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, cw.Close())
+	assert.Nil(t, cw.Err())
+
+	// no events once closed
+	s2, err = sim.NewChip(6, sim.WithLabel("watched-2"))
+	require.Nil(t, err)
+	defer s2.Close()
+	select {
+	case cce = <-ch:
+		assert.Fail(t, "unexpected event", cce)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPersistentLines(t *testing.T) {
+	ch := make(chan persistentChange, 10)
+	pl, err := gpiocdev.RequestPersistentLines("persistent", []int{1, 3},
+		func(l *gpiocdev.Lines, err error) {
+			ch <- persistentChange{l, err}
+		},
+		gpiocdev.AsOutput(1, 0))
+	require.Nil(t, err)
+	assert.Nil(t, pl.Lines())
+
+	s, err := sim.NewChip(4, sim.WithLabel("persistent"))
+	require.Nil(t, err)
+	c := waitPersistentChange(t, ch)
+	require.Nil(t, c.err)
+	require.NotNil(t, c.l)
+	assert.Equal(t, c.l, pl.Lines())
+	assert.Equal(t, s.ChipName(), c.l.Chip())
+	assert.Equal(t, []int{1, 3}, c.l.Offsets())
+	v, err := s.Level(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	s.Close()
+	c = waitPersistentChange(t, ch)
+	assert.Nil(t, c.l)
+	assert.Equal(t, unix.ENODEV, c.err)
+	assert.Nil(t, pl.Lines())
+
+	// returns with a different name
+	s, err = sim.NewChip(4, sim.WithLabel("persistent"))
+	require.Nil(t, err)
+	defer s.Close()
+	c = waitPersistentChange(t, ch)
+	require.Nil(t, c.err)
+	require.NotNil(t, c.l)
+	assert.Equal(t, s.ChipName(), c.l.Chip())
+	v, err = s.Level(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	assert.Nil(t, pl.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, pl.Close())
+	// lines released
+	l, err := gpiocdev.RequestLine(s.ChipName(), 1)
+	assert.Nil(t, err)
+	l.Close()
+}
+
+func TestPersistentLinesRetry(t *testing.T) {
+	s, err := sim.NewChip(4, sim.WithLabel("persistent-retry"))
+	require.Nil(t, err)
+	defer s.Close()
+
+	// the line is held by another consumer
+	bl, err := gpiocdev.RequestLine(s.ChipName(), 3)
+	require.Nil(t, err)
+
+	ch := make(chan persistentChange, 10)
+	pl, err := gpiocdev.RequestPersistentLines("persistent-retry", []int{1, 3},
+		func(l *gpiocdev.Lines, err error) {
+			ch <- persistentChange{l, err}
+		})
+	require.Nil(t, err)
+	defer pl.Close()
+	c := waitPersistentChange(t, ch)
+	assert.Nil(t, c.l)
+	assert.Equal(t, unix.EBUSY, c.err)
+	assert.Nil(t, pl.Lines())
+
+	assert.Equal(t, unix.EBUSY, pl.Retry())
+	c = waitPersistentChange(t, ch)
+	assert.Equal(t, unix.EBUSY, c.err)
+
+	bl.Close()
+	assert.Nil(t, pl.Retry())
+	c = waitPersistentChange(t, ch)
+	require.Nil(t, c.err)
+	require.NotNil(t, c.l)
+	assert.Equal(t, c.l, pl.Lines())
+
+	// already requested
+	assert.Nil(t, pl.Retry())
+
+	assert.Nil(t, pl.Close())
+	assert.Nil(t, pl.Lines())
+	assert.Equal(t, gpiocdev.ErrClosed, pl.Retry())
+
+	// no chip
+	pl, err = gpiocdev.RequestPersistentLines("persistent-none", []int{1}, nil)
+	require.Nil(t, err)
+	defer pl.Close()
+	assert.Equal(t, gpiocdev.ErrNotFound, pl.Retry())
+}
+
+func waitChipChange(t *testing.T, ch <-chan gpiocdev.ChipChangeEvent) gpiocdev.ChipChangeEvent {
+	t.Helper()
+	select {
+	case cce := <-ch:
+		return cce
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for chip change")
+	}
+	return gpiocdev.ChipChangeEvent{}
+}
+
+// persistentChange records a call to a PersistentLinesHandler.
+type persistentChange struct {
+	l   *gpiocdev.Lines
+	err error
+}
+
+func waitPersistentChange(t *testing.T, ch <-chan persistentChange) persistentChange {
+	t.Helper()
+	select {
+	case c := <-ch:
+		return c
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for request change")
+	}
+	return persistentChange{}
+}
@@ -0,0 +1,309 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package counter counts the edges on an input line, such as the pulses from
+// flow meters, anemometers or fan tachometers, and reports their rate.
+//
+//	c, _ := counter.New("gpiochip0", 22,
+//		counter.WithEdge(gpiocdev.WithFallingEdge),
+//		counter.WithDebounce(time.Millisecond))
+//	defer c.Close()
+//	...
+//	litres := float64(c.Count()) / pulsesPerLitre
+//	rpm := c.Frequency() * 60 / pulsesPerRev
+//
+// The count is taken from the sequence numbers of the edge events, so it
+// includes edges that were discarded by the kernel when the event buffer
+// overflowed.  Sequence numbers are only available with uAPI v2, so with v1
+// each event is counted as a single edge and discarded edges are not counted.
+package counter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+	"golang.org/x/sys/unix"
+)
+
+// buckets is the number of buckets the frequency window is divided into.
+const buckets = 10
+
+// Counter counts the edges on an input line.
+type Counter struct {
+	l *gpiocdev.Line
+
+	// the width of each bucket of the frequency window.
+	width time.Duration
+
+	// the clock used for the event timestamps.
+	clock int32
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	// the total number of edges.
+	total uint64
+
+	// the total at the last call to Delta.
+	read uint64
+
+	// the sequence number of the most recent event.
+	lineSeqno uint32
+
+	// the number of edges in each bucket of the frequency window.
+	buckets [buckets]uint64
+
+	// the index of the current bucket.
+	cur int
+
+	// the start of the current bucket.
+	bucketTs time.Duration
+
+	// when the counter was started or reset.
+	startTs time.Duration
+
+	closed bool
+}
+
+// Option defines the interface required to provide an option to New.
+type Option interface {
+	applyOption(*counterOptions)
+}
+
+type counterOptions struct {
+	edge    gpiocdev.LineEdge
+	window  time.Duration
+	reqOpts []gpiocdev.LineReqOption
+}
+
+// EdgeOption sets the edge counted.
+type EdgeOption gpiocdev.LineEdge
+
+// WithEdge sets the edge counted, which may be gpiocdev.WithRisingEdge,
+// gpiocdev.WithFallingEdge or gpiocdev.WithBothEdges.
+//
+// The default is gpiocdev.WithRisingEdge.
+func WithEdge(edge gpiocdev.LineEdge) EdgeOption {
+	return EdgeOption(edge)
+}
+
+func (o EdgeOption) applyOption(opts *counterOptions) {
+	opts.edge = gpiocdev.LineEdge(o)
+}
+
+// DebounceOption sets the debounce period.
+type DebounceOption time.Duration
+
+// WithDebounce sets the debounce period of the line, so edges from a bouncing
+// contact, such as the reed switch of an anemometer, are counted once.
+//
+// The default is no debounce.
+func WithDebounce(period time.Duration) DebounceOption {
+	return DebounceOption(period)
+}
+
+func (o DebounceOption) applyOption(opts *counterOptions) {
+	opts.reqOpts = append(opts.reqOpts, gpiocdev.WithDebounce(time.Duration(o)))
+}
+
+// WindowOption sets the frequency window.
+type WindowOption time.Duration
+
+// WithWindow sets the period over which the frequency is measured.
+//
+// The frequency is updated in steps of a tenth of the window, so a longer
+// window gives a smoother, but slower to respond, frequency.
+//
+// The default is 1s.
+func WithWindow(period time.Duration) WindowOption {
+	return WindowOption(period)
+}
+
+func (o WindowOption) applyOption(opts *counterOptions) {
+	opts.window = time.Duration(o)
+}
+
+// RequestOption provides options for the line request.
+type RequestOption []gpiocdev.LineReqOption
+
+// WithRequestOptions provides options for the request of the line, such as
+// active level, bias or consumer.
+//
+// Direction, edge detection and event handling options are overridden.
+func WithRequestOptions(options ...gpiocdev.LineReqOption) RequestOption {
+	return RequestOption(options)
+}
+
+func (o RequestOption) applyOption(opts *counterOptions) {
+	opts.reqOpts = append(opts.reqOpts, o...)
+}
+
+// New requests the line from the chip and starts counting edges.
+//
+// Returns unix.EINVAL if the edge is not valid or the window is too short.
+func New(chip string, offset int, options ...Option) (*Counter, error) {
+	opts := counterOptions{
+		edge:   gpiocdev.WithRisingEdge,
+		window: time.Second,
+	}
+	for _, option := range options {
+		option.applyOption(&opts)
+	}
+	switch opts.edge {
+	case gpiocdev.LineEdgeRising, gpiocdev.LineEdgeFalling, gpiocdev.LineEdgeBoth:
+	default:
+		return nil, unix.EINVAL
+	}
+	if opts.window < buckets {
+		return nil, unix.EINVAL
+	}
+	c := &Counter{
+		width: opts.window / buckets,
+		clock: unix.CLOCK_MONOTONIC,
+	}
+	reqOpts := append([]gpiocdev.LineReqOption{}, opts.reqOpts...)
+	reqOpts = append(reqOpts,
+		gpiocdev.AsInput,
+		opts.edge,
+		gpiocdev.WithEventHandler(c.handle))
+	// hold the lock so events are not handled until the event clock is known.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, err := gpiocdev.RequestLine(chip, offset, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	c.l = l
+	inf, err := l.Info()
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	if inf.Config.EventClock == gpiocdev.LineEventClockRealtime {
+		c.clock = unix.CLOCK_REALTIME
+	}
+	c.startTs = c.now()
+	c.bucketTs = c.startTs
+	return c, nil
+}
+
+// Close releases the line.
+func (c *Counter) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return gpiocdev.ErrClosed
+	}
+	c.closed = true
+	c.mu.Unlock()
+	return c.l.Close()
+}
+
+// Count returns the total number of edges counted.
+func (c *Counter) Count() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// Delta returns the number of edges counted since the previous call to Delta,
+// or since the counter was started or reset.
+func (c *Counter) Delta() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.total - c.read
+	c.read = c.total
+	return d
+}
+
+// Frequency returns the rate of edges over the window, in Hz.
+//
+// Until the counter has been running for the window the rate is taken over
+// the time since the counter was started or reset.
+func (c *Counter) Frequency() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.advance(now)
+	var n uint64
+	for _, b := range c.buckets {
+		n += b
+	}
+	period := (buckets-1)*c.width + now - c.bucketTs
+	if running := now - c.startTs; running < period {
+		period = running
+	}
+	if period <= 0 {
+		return 0
+	}
+	return float64(n) / period.Seconds()
+}
+
+// Reset clears the counts.
+func (c *Counter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total = 0
+	c.read = 0
+	c.buckets = [buckets]uint64{}
+	c.startTs = c.now()
+	c.bucketTs = c.startTs
+}
+
+// handle counts the edge event.
+func (c *Counter) handle(evt gpiocdev.LineEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// events discarded by the kernel are reflected in the gap in the
+	// sequence numbers, which are zero with uAPI v1.
+	n := uint64(1)
+	if evt.Seqno != 0 {
+		n = uint64(evt.LineSeqno - c.lineSeqno)
+		c.lineSeqno = evt.LineSeqno
+	}
+	if evt.Timestamp < c.startTs {
+		// predates a reset.
+		return
+	}
+	c.total += n
+	c.advance(evt.Timestamp)
+	idx := c.cur
+	if evt.Timestamp < c.bucketTs {
+		// the event has been read after the window has advanced.
+		back := int((c.bucketTs-evt.Timestamp-1)/c.width) + 1
+		if back >= buckets {
+			return
+		}
+		idx = (c.cur - back + buckets) % buckets
+	}
+	c.buckets[idx] += n
+}
+
+// advance moves the frequency window forward to include the time.
+//
+// Assumes c is locked.
+func (c *Counter) advance(ts time.Duration) {
+	elapsed := ts - c.bucketTs
+	if elapsed < c.width {
+		return
+	}
+	steps := int(elapsed / c.width)
+	if steps >= buckets {
+		c.buckets = [buckets]uint64{}
+	} else {
+		for i := 0; i < steps; i++ {
+			c.cur = (c.cur + 1) % buckets
+			c.buckets[c.cur] = 0
+		}
+	}
+	c.bucketTs += time.Duration(steps) * c.width
+}
+
+// now returns the current time from the event clock.
+func (c *Counter) now() time.Duration {
+	var ts unix.Timespec
+	unix.ClockGettime(c.clock, &ts)
+	return time.Duration(ts.Nano())
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package counter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/counter"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"github.com/warthog618/go-gpiosim"
+	"golang.org/x/sys/unix"
+)
+
+const offset = 1
+
+func TestNew(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	_, err = counter.New(s.ChipName(), offset, counter.WithEdge(gpiocdev.LineEdgeNone))
+	assert.Equal(t, unix.EINVAL, err)
+	_, err = counter.New(s.ChipName(), offset, counter.WithWindow(0))
+	assert.Equal(t, unix.EINVAL, err)
+	_, err = counter.New(s.ChipName(), 4)
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	c, err := counter.New(s.ChipName(), offset)
+	require.Nil(t, err)
+	assert.Zero(t, c.Count())
+	assert.Zero(t, c.Frequency())
+	assert.Nil(t, c.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, c.Close())
+}
+
+func TestCount(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	edges := []struct {
+		name string
+		edge gpiocdev.LineEdge
+		xc   uint64
+	}{
+		{"rising", gpiocdev.WithRisingEdge, 5},
+		{"falling", gpiocdev.WithFallingEdge, 5},
+		{"both", gpiocdev.WithBothEdges, 10},
+	}
+	for _, tc := range edges {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := counter.New(s.ChipName(), offset, counter.WithEdge(tc.edge))
+			require.Nil(t, err)
+			defer c.Close()
+
+			pulses(s, 5)
+			waitCount(t, c, tc.xc)
+			assert.Equal(t, tc.xc, c.Delta())
+			assert.Zero(t, c.Delta())
+			pulses(s, 2)
+			waitCount(t, c, tc.xc*7/5)
+			assert.Equal(t, tc.xc*2/5, c.Delta())
+
+			c.Reset()
+			assert.Zero(t, c.Count())
+			assert.Zero(t, c.Delta())
+			pulses(s, 1)
+			waitCount(t, c, tc.xc/5)
+		})
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	c, err := counter.New(s.ChipName(), offset,
+		counter.WithRequestOptions(gpiocdev.WithEventBufferSize(2)))
+	require.Nil(t, err)
+	defer c.Close()
+
+	// the count is exact, whether or not events are discarded.
+	pulses(s, 200)
+	waitCount(t, c, 200)
+}
+
+func TestABIv1(t *testing.T) {
+	// the sim does not support uAPI v1, so this requires gpio-sim.
+	s, err := gpiosim.NewSimpleton(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	c, err := counter.New(s.ChipName(), offset,
+		counter.WithRequestOptions(gpiocdev.ABIVersionOption(1)))
+	require.Nil(t, err)
+	defer c.Close()
+
+	pulses(s, 5)
+	waitCount(t, c, 5)
+	assert.Equal(t, uint64(5), c.Delta())
+}
+
+func TestFrequency(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	c, err := counter.New(s.ChipName(), offset,
+		counter.WithWindow(200*time.Millisecond))
+	require.Nil(t, err)
+	defer c.Close()
+
+	// 50Hz for 300ms
+	for i := 0; i < 15; i++ {
+		s.Pullup(offset)
+		time.Sleep(10 * time.Millisecond)
+		s.Pulldown(offset)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.InDelta(t, 50, c.Frequency(), 15)
+
+	// and stopped
+	time.Sleep(250 * time.Millisecond)
+	assert.Zero(t, c.Frequency())
+	assert.Equal(t, uint64(15), c.Count())
+}
+
+// pulser is the subset of the sim and gpiosim chips used to generate pulses.
+type pulser interface {
+	Pullup(offset int) error
+	Pulldown(offset int) error
+}
+
+// pulses generates n pulses on the line.
+func pulses(s pulser, n int) {
+	for i := 0; i < n; i++ {
+		s.Pullup(offset)
+		s.Pulldown(offset)
+	}
+}
+
+// waitCount waits for the counter to reach the expected count.
+func waitCount(t *testing.T, c *counter.Counter, xc uint64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for c.Count() < xc && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, xc, c.Count())
+}
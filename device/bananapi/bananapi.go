@@ -4,6 +4,9 @@
 
 // Package bananapi provides convenience mappings from Banana Pi pin names to
 // offsets.
+//
+// Importing the package also registers the Banana Pi with the board package,
+// so the J8 header pins can be requested by name.
 package bananapi
 
 import (
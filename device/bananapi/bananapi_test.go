@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package bananapi_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/device/bananapi"
+	"github.com/warthog618/go-gpiocdev/device/board"
+	"github.com/warthog618/go-gpiocdev/sim"
+)
+
+func TestBoard(t *testing.T) {
+	compatibles := [][]string{
+		{"sinovoip,bpi-m1-plus", "allwinner,sun7i-a20"},
+		{"lemaker,bananapro", "allwinner,sun7i-a20"},
+		{"sinovoip,bpi-m2-ultra", "allwinner,sun8i-r40"},
+		{"sinovoip,bpi-m2-berry", "allwinner,sun8i-r40"},
+	}
+	for _, c := range compatibles {
+		t.Run(c[0], func(t *testing.T) {
+			b, err := board.Identify("", c)
+			require.Nil(t, err)
+			assert.Equal(t, "Banana Pi", b.Name)
+		})
+	}
+
+	b, err := board.Lookup("Banana Pi")
+	require.Nil(t, err)
+	require.Len(t, b.Headers, 1)
+	gpios := 0
+	for _, pin := range b.Headers[0].Pins {
+		if !pin.IsGPIO() {
+			continue
+		}
+		gpios++
+		assert.Equal(t, "1c20800.pinctrl", pin.Chip, pin.Name())
+		gpio, err := strconv.Atoi(pin.Function[len("GPIO"):])
+		require.Nil(t, err, pin.Name())
+		assert.Equal(t, bananapi.GPIO_TO_OFFSET[gpio], pin.Offset, pin.Name())
+	}
+	assert.Equal(t, len(bananapi.GPIO_TO_OFFSET), gpios)
+
+	pin, err := b.Pin("J8p11")
+	assert.Nil(t, err)
+	assert.Equal(t, "GPIO17", pin.Function)
+	assert.Equal(t, bananapi.GPIO17, pin.Offset)
+	pin, err = b.Pin("J8p6")
+	assert.Nil(t, err)
+	assert.Equal(t, "GND", pin.Function)
+	assert.False(t, pin.IsGPIO())
+	_, err = b.Pin("J8p27")
+	assert.Equal(t, board.ErrInvalid, err)
+}
+
+func TestBoardRequestLine(t *testing.T) {
+	b, err := board.Lookup("Banana Pi")
+	require.Nil(t, err)
+
+	s, err := sim.NewChip(288, sim.WithLabel("1c20800.pinctrl"))
+	require.Nil(t, err)
+	defer s.Close()
+
+	l, err := b.RequestLine("J8p11", gpiocdev.AsOutput(1))
+	require.Nil(t, err)
+	defer l.Close()
+	v, err := s.Level(bananapi.GPIO17)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+}
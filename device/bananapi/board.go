@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package bananapi
+
+import (
+	"strconv"
+
+	"github.com/warthog618/go-gpiocdev/device/board"
+)
+
+// the label of the Allwinner pin controller providing the header lines.
+const chip = "1c20800.pinctrl"
+
+// the functions of the J8 pins, indexed by pin number - 1.
+//
+// GPIO pins are identified by their Pi compatible GPIO number, which is mapped
+// to the offset of the line by GPIO_TO_OFFSET.  Pins 27 and 28 are not mapped,
+// so are omitted.
+var j8Functions = [40]string{
+	"3V3", "5V",
+	"2", "5V",
+	"3", "GND",
+	"4", "14",
+	"GND", "15",
+	"17", "18",
+	"27", "GND",
+	"22", "23",
+	"3V3", "24",
+	"10", "GND",
+	"9", "25",
+	"11", "8",
+	"GND", "7",
+	"", "",
+	"5", "GND",
+	"6", "12",
+	"13", "GND",
+	"19", "16",
+	"26", "20",
+	"GND", "21",
+}
+
+// j8Header returns the J8 header, named as per the Raspberry Pi, so the pins
+// may be requested by the same names on either.
+func j8Header() board.Header {
+	h := board.Header{Name: "J8"}
+	for i, f := range j8Functions {
+		if f == "" {
+			continue
+		}
+		p := board.Pin{Number: i + 1, Function: f}
+		if gpio, err := strconv.Atoi(f); err == nil {
+			p.Function = "GPIO" + f
+			p.Chip = chip
+			p.Offset = GPIO_TO_OFFSET[gpio]
+		}
+		h.Pins = append(h.Pins, p)
+	}
+	return h
+}
+
+func init() {
+	board.Register(board.Board{
+		// the Allwinner A20 and R40 based boards with the 40 pin header.
+		Name: "Banana Pi",
+		Compatible: []string{
+			"sinovoip,bpi-m1-plus",
+			"lemaker,bananapro",
+			"sinovoip,bpi-m2-ultra",
+			"sinovoip,bpi-m2-berry",
+		},
+		Headers: []board.Header{j8Header()},
+	})
+}
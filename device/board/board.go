@@ -0,0 +1,358 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package board provides a registry of boards and the pins on their headers,
+// so lines can be requested by header pin without knowing which board, or
+// which chip, the code is running on.
+//
+// The board is detected from the device tree model and compatible strings:
+//
+//	b, _ := board.Detect()
+//	p, _ := b.Pin("J8p11") // p.Chip, p.Offset, p.Function...
+//	l, _ := b.RequestLine("J8p11", gpiocdev.AsOutput(1))
+//
+// or, more simply:
+//
+//	l, _ := board.RequestLine("J8p11", gpiocdev.AsOutput(1))
+//
+// Boards are registered by the packages describing them, so those packages
+// must be imported for their boards to be detected:
+//
+//	import _ "github.com/warthog618/go-gpiocdev/device/rpi"
+//
+// Boards may also be registered from outside this module using Register.
+package board
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/warthog618/go-gpiocdev"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// ErrUnknownBoard indicates the board is not registered.
+	ErrUnknownBoard = errors.New("unknown board")
+
+	// ErrExists indicates a board with the name is already registered.
+	ErrExists = errors.New("board already registered")
+
+	// ErrInvalid indicates the pin name does not match a known pin.
+	ErrInvalid = errors.New("invalid pin name")
+
+	// ErrNotGPIO indicates the pin is not connected to a GPIO line, such as a
+	// power or ground pin.
+	ErrNotGPIO = errors.New("pin is not a GPIO")
+)
+
+// Pin describes a pin on a header.
+type Pin struct {
+	// The name of the header containing the pin, such as J8.
+	Header string
+
+	// The physical pin number on the header, starting from 1.
+	Number int
+
+	// The function of the pin, such as GPIO17, 3V3 or GND.
+	Function string
+
+	// The label of the chip providing the line connected to the pin, or
+	// empty if the pin is not a GPIO.
+	//
+	// The label is used rather than the chip name as the name depends on the
+	// order in which chips are probed.
+	Chip string
+
+	// The offset of the line on the chip.
+	Offset int
+}
+
+// Name returns the name of the pin, such as J8p11.
+func (p Pin) Name() string {
+	return p.Header + "p" + strconv.Itoa(p.Number)
+}
+
+// IsGPIO returns true if the pin is connected to a GPIO line.
+func (p Pin) IsGPIO() bool {
+	return p.Chip != ""
+}
+
+// Header describes a header on a board.
+type Header struct {
+	// The name of the header, such as J8.
+	Name string
+
+	// The pins on the header.
+	//
+	// Pins that are not listed are treated as not connected.
+	Pins []Pin
+}
+
+// Board describes a board and its headers.
+type Board struct {
+	// The name of the board, such as Raspberry Pi 4.
+	Name string
+
+	// The device tree compatible strings that identify the board, such as
+	// raspberrypi,4-model-b.
+	Compatible []string
+
+	// The prefixes of the device tree model that identify the board, such as
+	// Raspberry Pi 4.
+	//
+	// Models are only matched if no registered board matches the compatible
+	// strings.
+	Models []string
+
+	// The headers on the board.
+	Headers []Header
+}
+
+var (
+	// mutex covers the attributes below it.
+	mu sync.RWMutex
+
+	// the registered boards, in order of registration.
+	boards []*Board
+)
+
+// Register adds the board to the registry.
+//
+// The header name of each pin is set from the header containing it.
+//
+// Returns ErrExists if a board with the same name is already registered, and
+// unix.EINVAL if the board has no name, nothing to identify it, or pins with
+// duplicate numbers on a header.
+func Register(b Board) error {
+	if b.Name == "" || (len(b.Compatible) == 0 && len(b.Models) == 0) {
+		return unix.EINVAL
+	}
+	rb := b.clone()
+	for _, h := range rb.Headers {
+		numbers := map[int]bool{}
+		for j, p := range h.Pins {
+			if p.Number < 1 || numbers[p.Number] {
+				return unix.EINVAL
+			}
+			numbers[p.Number] = true
+			h.Pins[j].Header = h.Name
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, b := range boards {
+		if b.Name == rb.Name {
+			return ErrExists
+		}
+	}
+	boards = append(boards, rb)
+	return nil
+}
+
+// clone returns a deep copy of the board, so the registry is not altered
+// through the boards passed to Register or returned by Lookup, Identify and
+// Detect.
+func (b *Board) clone() *Board {
+	cb := *b
+	cb.Compatible = append([]string(nil), b.Compatible...)
+	cb.Models = append([]string(nil), b.Models...)
+	cb.Headers = make([]Header, len(b.Headers))
+	for i, h := range b.Headers {
+		cb.Headers[i] = Header{Name: h.Name, Pins: append([]Pin(nil), h.Pins...)}
+	}
+	return &cb
+}
+
+// Lookup returns a copy of the registered board with the name.
+//
+// Returns ErrUnknownBoard if no board with the name is registered.
+func Lookup(name string) (*Board, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, b := range boards {
+		if b.Name == name {
+			return b.clone(), nil
+		}
+	}
+	return nil, ErrUnknownBoard
+}
+
+// Boards returns the names of the registered boards, in order of registration.
+func Boards() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, len(boards))
+	for i, b := range boards {
+		names[i] = b.Name
+	}
+	return names
+}
+
+// Identify returns a copy of the registered board matching the device tree
+// model and compatible strings.
+//
+// The compatible strings are matched in order, so the most specific should be
+// first, as they are in the device tree.  If none match then the registered
+// board with the longest matching model prefix is returned.
+//
+// Returns ErrUnknownBoard if no registered board matches.
+func Identify(model string, compatible []string) (*Board, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, c := range compatible {
+		for _, b := range boards {
+			for _, bc := range b.Compatible {
+				if bc == c {
+					return b.clone(), nil
+				}
+			}
+		}
+	}
+	var match *Board
+	mlen := 0
+	for _, b := range boards {
+		for _, m := range b.Models {
+			if len(m) > mlen && strings.HasPrefix(model, m) {
+				match = b
+				mlen = len(m)
+			}
+		}
+	}
+	if match == nil {
+		return nil, ErrUnknownBoard
+	}
+	return match.clone(), nil
+}
+
+// the root of the procfs devicetree tree.
+const dtBase = "/proc/device-tree"
+
+// Detect returns a copy of the registered board matching the device tree of
+// the running system.
+//
+// Returns ErrUnknownBoard if the system has no device tree, or no registered
+// board matches.
+func Detect() (*Board, error) {
+	model, merr := os.ReadFile(dtBase + "/model")
+	compatible, cerr := os.ReadFile(dtBase + "/compatible")
+	if merr != nil && cerr != nil {
+		return nil, ErrUnknownBoard
+	}
+	var cc []string
+	for _, c := range strings.Split(string(compatible), "\x00") {
+		if c != "" {
+			cc = append(cc, c)
+		}
+	}
+	return Identify(strings.TrimRight(string(model), "\x00"), cc)
+}
+
+// Pin returns the pin with the name.
+//
+// Pin names are case insensitive and may be of the form HpX, where H is the
+// header name and X the pin number, such as J8p11, or the function of a GPIO
+// pin, such as GPIO17.
+//
+// Returns ErrInvalid if the name does not match a pin.
+func (b *Board) Pin(name string) (Pin, error) {
+	lname := strings.ToLower(name)
+	for _, h := range b.Headers {
+		prefix := strings.ToLower(h.Name) + "p"
+		if !strings.HasPrefix(lname, prefix) {
+			continue
+		}
+		n, err := strconv.Atoi(lname[len(prefix):])
+		if err != nil {
+			continue
+		}
+		for _, p := range h.Pins {
+			if p.Number == n {
+				return p, nil
+			}
+		}
+	}
+	for _, h := range b.Headers {
+		for _, p := range h.Pins {
+			if p.IsGPIO() && strings.ToLower(p.Function) == lname {
+				return p, nil
+			}
+		}
+	}
+	return Pin{}, ErrInvalid
+}
+
+// ChipOffsets returns the chip and offset of the line connected to each of
+// the named pins.
+//
+// The chips are found by label from the available chips.
+//
+// Returns ErrInvalid if a name does not match a pin, ErrNotGPIO if a pin is
+// not a GPIO, and gpiocdev.ErrNotFound if the chip providing a pin is not
+// available.
+func (b *Board) ChipOffsets(names ...string) ([]gpiocdev.ChipOffset, error) {
+	chips := map[string]string{}
+	lines := make([]gpiocdev.ChipOffset, len(names))
+	for i, name := range names {
+		p, err := b.Pin(name)
+		if err != nil {
+			return nil, err
+		}
+		if !p.IsGPIO() {
+			return nil, ErrNotGPIO
+		}
+		chip, ok := chips[p.Chip]
+		if !ok {
+			chip, err = gpiocdev.FindChip(gpiocdev.ChipSelector{Label: p.Chip})
+			if err != nil {
+				return nil, err
+			}
+			chips[p.Chip] = chip
+		}
+		lines[i] = gpiocdev.ChipOffset{Chip: chip, Offset: p.Offset}
+	}
+	return lines, nil
+}
+
+// RequestLine requests the line connected to the named pin.
+func (b *Board) RequestLine(name string, options ...gpiocdev.LineReqOption) (*gpiocdev.Line, error) {
+	lines, err := b.ChipOffsets(name)
+	if err != nil {
+		return nil, err
+	}
+	return gpiocdev.RequestLine(lines[0].Chip, lines[0].Offset, options...)
+}
+
+// RequestLines requests the lines connected to the named pins, which may be
+// provided by several chips.
+func (b *Board) RequestLines(names []string, options ...gpiocdev.LineReqOption) (*gpiocdev.MultiLines, error) {
+	lines, err := b.ChipOffsets(names...)
+	if err != nil {
+		return nil, err
+	}
+	return gpiocdev.RequestMultiLines(lines, options...)
+}
+
+// RequestLine requests the line connected to the named pin on the detected
+// board.
+func RequestLine(name string, options ...gpiocdev.LineReqOption) (*gpiocdev.Line, error) {
+	b, err := Detect()
+	if err != nil {
+		return nil, err
+	}
+	return b.RequestLine(name, options...)
+}
+
+// RequestLines requests the lines connected to the named pins on the
+// detected board.
+func RequestLines(names []string, options ...gpiocdev.LineReqOption) (*gpiocdev.MultiLines, error) {
+	b, err := Detect()
+	if err != nil {
+		return nil, err
+	}
+	return b.RequestLines(names, options...)
+}
@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package board_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/device/board"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"golang.org/x/sys/unix"
+)
+
+var testBoard = board.Board{
+	Name:       "Test Board",
+	Compatible: []string{"test,board-v2", "test,board"},
+	Models:     []string{"Test Board"},
+	Headers: []board.Header{
+		{
+			Name: "J1",
+			Pins: []board.Pin{
+				{Number: 1, Function: "3V3"},
+				{Number: 2, Function: "GND"},
+				{Number: 3, Function: "GPIO5", Chip: "board-test-a", Offset: 5},
+				{Number: 4, Function: "GPIO1", Chip: "board-test-b", Offset: 1},
+			},
+		},
+		{
+			Name: "CON2",
+			Pins: []board.Pin{
+				{Number: 1, Function: "GPIO2", Chip: "board-test-a", Offset: 2},
+			},
+		},
+	},
+}
+
+func init() {
+	if err := board.Register(testBoard); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	assert.Equal(t, board.ErrExists, board.Register(testBoard))
+	assert.Equal(t, unix.EINVAL, board.Register(board.Board{Compatible: []string{"x"}}))
+	assert.Equal(t, unix.EINVAL, board.Register(board.Board{Name: "x"}))
+	assert.Equal(t, unix.EINVAL, board.Register(board.Board{
+		Name:       "dup pins",
+		Compatible: []string{"test,dup"},
+		Headers: []board.Header{
+			{Name: "J1", Pins: []board.Pin{{Number: 1}, {Number: 1}}},
+		},
+	}))
+	assert.Contains(t, board.Boards(), "Test Board")
+	assert.NotContains(t, board.Boards(), "dup pins")
+
+	b, err := board.Lookup("Test Board")
+	require.Nil(t, err)
+	assert.Equal(t, "J1", b.Headers[0].Pins[2].Header)
+	assert.Equal(t, "CON2", b.Headers[1].Pins[0].Header)
+	_, err = board.Lookup("Unknown Board")
+	assert.Equal(t, board.ErrUnknownBoard, err)
+
+	// the registry is not altered through the boards passed or returned
+	tb := testBoard
+	tb.Name = "Copied Board"
+	tb.Headers = []board.Header{{Name: "J1", Pins: []board.Pin{{Number: 1, Function: "3V3"}}}}
+	require.Nil(t, board.Register(tb))
+	tb.Headers[0].Pins[0].Function = "GND"
+	b, err = board.Lookup("Copied Board")
+	require.Nil(t, err)
+	assert.Equal(t, "3V3", b.Headers[0].Pins[0].Function)
+	b.Headers[0].Pins[0].Function = "GND"
+	b.Compatible[0] = "test,altered"
+	b, err = board.Identify("", []string{"test,board"})
+	require.Nil(t, err)
+	b.Headers[0].Pins[0].Function = "GND"
+	b, err = board.Lookup("Copied Board")
+	require.Nil(t, err)
+	assert.Equal(t, "3V3", b.Headers[0].Pins[0].Function)
+	assert.Equal(t, testBoard.Compatible, b.Compatible)
+	b, err = board.Lookup("Test Board")
+	require.Nil(t, err)
+	assert.Equal(t, "3V3", b.Headers[0].Pins[0].Function)
+}
+
+func TestIdentify(t *testing.T) {
+	patterns := []struct {
+		name       string
+		model      string
+		compatible []string
+		err        error
+	}{
+		{"compatible", "", []string{"test,board"}, nil},
+		{"specific", "Other", []string{"test,board-v2", "vendor,soc"}, nil},
+		{"generic", "Other", []string{"vendor,board", "test,board"}, nil},
+		{"model", "Test Board Rev 1.1", []string{"vendor,board"}, nil},
+		{"unknown", "Other", []string{"vendor,board"}, board.ErrUnknownBoard},
+		{"empty", "", nil, board.ErrUnknownBoard},
+	}
+	for _, p := range patterns {
+		t.Run(p.name, func(t *testing.T) {
+			b, err := board.Identify(p.model, p.compatible)
+			assert.Equal(t, p.err, err)
+			if p.err == nil {
+				require.NotNil(t, b)
+				assert.Equal(t, "Test Board", b.Name)
+			}
+		})
+	}
+}
+
+func TestPin(t *testing.T) {
+	b, err := board.Lookup("Test Board")
+	require.Nil(t, err)
+	patterns := []struct {
+		name string
+		pin  board.Pin
+		err  error
+	}{
+		{"J1p1", board.Pin{Header: "J1", Number: 1, Function: "3V3"}, nil},
+		{"j1P3", board.Pin{Header: "J1", Number: 3, Function: "GPIO5", Chip: "board-test-a", Offset: 5}, nil},
+		{"CON2p1", board.Pin{Header: "CON2", Number: 1, Function: "GPIO2", Chip: "board-test-a", Offset: 2}, nil},
+		{"gpio1", board.Pin{Header: "J1", Number: 4, Function: "GPIO1", Chip: "board-test-b", Offset: 1}, nil},
+		{"J1p5", board.Pin{}, board.ErrInvalid},
+		{"J1p", board.Pin{}, board.ErrInvalid},
+		{"J2p1", board.Pin{}, board.ErrInvalid},
+		{"GND", board.Pin{}, board.ErrInvalid},
+	}
+	for _, p := range patterns {
+		t.Run(p.name, func(t *testing.T) {
+			pin, err := b.Pin(p.name)
+			assert.Equal(t, p.err, err)
+			assert.Equal(t, p.pin, pin)
+		})
+	}
+	assert.Equal(t, "J1p3", b.Headers[0].Pins[2].Name())
+	assert.True(t, b.Headers[0].Pins[2].IsGPIO())
+	assert.False(t, b.Headers[0].Pins[0].IsGPIO())
+}
+
+func TestRequestLines(t *testing.T) {
+	b, err := board.Lookup("Test Board")
+	require.Nil(t, err)
+
+	// chips not available
+	_, err = b.RequestLine("J1p3")
+	assert.Equal(t, gpiocdev.ErrNotFound, err)
+
+	sa, err := sim.NewChip(8, sim.WithLabel("board-test-a"))
+	require.Nil(t, err)
+	defer sa.Close()
+	sb, err := sim.NewChip(4, sim.WithLabel("board-test-b"))
+	require.Nil(t, err)
+	defer sb.Close()
+
+	_, err = b.RequestLine("J1p2")
+	assert.Equal(t, board.ErrNotGPIO, err)
+	_, err = b.RequestLine("J1p9")
+	assert.Equal(t, board.ErrInvalid, err)
+
+	lines, err := b.ChipOffsets("J1p3", "J1p4", "CON2p1")
+	require.Nil(t, err)
+	assert.Equal(t, []gpiocdev.ChipOffset{
+		{Chip: sa.ChipName(), Offset: 5},
+		{Chip: sb.ChipName(), Offset: 1},
+		{Chip: sa.ChipName(), Offset: 2},
+	}, lines)
+
+	l, err := b.RequestLine("J1p3", gpiocdev.AsOutput(1))
+	require.Nil(t, err)
+	v, err := sa.Level(5)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	l.Close()
+
+	ml, err := b.RequestLines([]string{"J1p4", "CON2p1"}, gpiocdev.AsOutput(1, 1))
+	require.Nil(t, err)
+	v, err = sb.Level(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	v, err = sa.Level(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	ml.Close()
+}
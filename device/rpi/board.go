@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package rpi
+
+import (
+	"strconv"
+
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/device/board"
+)
+
+// the functions of the J8 pins, indexed by pin number - 1.
+//
+// GPIO pins are identified by their BCM number, as that is also the offset of
+// the line.
+var j8Functions = [40]string{
+	"3V3", "5V",
+	"2", "5V",
+	"3", "GND",
+	"4", "14",
+	"GND", "15",
+	"17", "18",
+	"27", "GND",
+	"22", "23",
+	"3V3", "24",
+	"10", "GND",
+	"9", "25",
+	"11", "8",
+	"GND", "7",
+	"0", "1",
+	"5", "GND",
+	"6", "12",
+	"13", "GND",
+	"19", "16",
+	"26", "20",
+	"GND", "21",
+}
+
+// j8Header returns the J8 header with the GPIO lines provided by the chip
+// with the label.
+func j8Header(chip string) board.Header {
+	h := board.Header{Name: "J8", Pins: make([]board.Pin, len(j8Functions))}
+	for i, f := range j8Functions {
+		p := board.Pin{Number: i + 1, Function: f}
+		if offset, err := strconv.Atoi(f); err == nil {
+			p.Function = "GPIO" + f
+			p.Chip = chip
+			p.Offset = offset
+		}
+		h.Pins[i] = p
+	}
+	return h
+}
+
+// the Pi variants, identified by their SoC, and the chip providing the J8
+// lines on each.
+var boards = []board.Board{
+	{
+		// Pi 1, 2, 3, Zero, Zero 2 and CM1 to CM3.
+		Name:       "Raspberry Pi",
+		Compatible: []string{"brcm,bcm2835", "brcm,bcm2836", "brcm,bcm2837"},
+		Headers:    []board.Header{j8Header("pinctrl-bcm2835")},
+	},
+	{
+		// Pi 4, 400 and CM4.
+		Name:       "Raspberry Pi 4",
+		Compatible: []string{"brcm,bcm2711"},
+		Headers:    []board.Header{j8Header("pinctrl-bcm2711")},
+	},
+	{
+		// Pi 5, 500 and CM5, where the header is provided by the RP1 I/O
+		// controller rather than the SoC.
+		Name:       "Raspberry Pi 5",
+		Compatible: []string{"brcm,bcm2712"},
+		Headers:    []board.Header{j8Header("pinctrl-rp1")},
+	},
+}
+
+func init() {
+	for _, b := range boards {
+		board.Register(b)
+	}
+}
+
+// isPi returns true if the board is one of the Pi variants.
+func isPi(b *board.Board) bool {
+	for _, pb := range boards {
+		if pb.Name == b.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Chip returns the name of the chip providing the lines on the J8 header of
+// the Pi the code is running on.
+//
+// Returns board.ErrUnknownBoard if not running on a Pi, and
+// gpiocdev.ErrNotFound if the chip is not available.
+func Chip() (string, error) {
+	b, err := board.Detect()
+	if err != nil {
+		return "", err
+	}
+	if !isPi(b) {
+		return "", board.ErrUnknownBoard
+	}
+	lines, err := b.ChipOffsets("J8p3")
+	if err != nil {
+		return "", err
+	}
+	return lines[0].Chip, nil
+}
+
+// RequestLine requests the line at the offset, such as rpi.J8p11 or
+// rpi.GPIO17, from the chip providing the J8 header.
+func RequestLine(offset int, options ...gpiocdev.LineReqOption) (*gpiocdev.Line, error) {
+	if offset < 0 || offset >= MaxGPIOPin {
+		return nil, ErrInvalid
+	}
+	chip, err := Chip()
+	if err != nil {
+		return nil, err
+	}
+	return gpiocdev.RequestLine(chip, offset, options...)
+}
+
+// RequestLines requests the lines at the offsets, such as rpi.J8p11 or
+// rpi.GPIO17, from the chip providing the J8 header.
+func RequestLines(offsets []int, options ...gpiocdev.LineReqOption) (*gpiocdev.Lines, error) {
+	for _, offset := range offsets {
+		if offset < 0 || offset >= MaxGPIOPin {
+			return nil, ErrInvalid
+		}
+	}
+	chip, err := Chip()
+	if err != nil {
+		return nil, err
+	}
+	return gpiocdev.RequestLines(chip, offsets, options...)
+}
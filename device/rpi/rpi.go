@@ -5,9 +5,17 @@
 // Package rpi provides convenience mappings from Raspberry Pi pin names to
 // offsets.
 //
-// The gpiochip these mappings apply to depends on the Pi variant.
-// For Pi0 to Pi4 it is gpiochip0.
-// For Pi5 it is gpiochip0 or gpiochip4 (depending on kernel version).
+// The chip these mappings apply to depends on the Pi variant.
+// For Pi 1 to 3 and Zero it is labelled pinctrl-bcm2835.
+// For Pi 4 and CM4 it is labelled pinctrl-bcm2711.
+// For Pi 5 and CM5 it is the RP1 labelled pinctrl-rp1, which is gpiochip0 or
+// gpiochip4 depending on kernel version.
+//
+// The Pi variants are registered with the board package, so the chip can be
+// determined from the device tree using Chip, and the lines requested from it
+// using RequestLine and RequestLines:
+//
+//	l, _ := rpi.RequestLine(rpi.J8p11, gpiocdev.AsOutput(1))
 package rpi
 
 import (
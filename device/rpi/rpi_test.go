@@ -5,10 +5,15 @@
 package rpi_test
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/device/board"
 	"github.com/warthog618/go-gpiocdev/device/rpi"
+	"github.com/warthog618/go-gpiocdev/sim"
 )
 
 var patterns = []struct {
@@ -100,3 +105,219 @@ func TestMustPin(t *testing.T) {
 		t.Run(p.name, tf)
 	}
 }
+
+func TestBoard(t *testing.T) {
+	boards := []struct {
+		name       string
+		model      string
+		compatible []string
+		board      string
+		chip       string
+		lines      int
+		// the gpio-line-names of the header lines, from the device tree of
+		// the model.
+		names []string
+	}{
+		{
+			"pi1",
+			"Raspberry Pi Model B Plus Rev 1.2",
+			[]string{"raspberrypi,model-b-plus", "brcm,bcm2835"},
+			"Raspberry Pi",
+			"pinctrl-bcm2835",
+			54,
+			// arch/arm/boot/dts/broadcom/bcm2835-rpi-b-plus.dts
+			schematicLineNames("TXD0", "RXD0"),
+		},
+		{
+			"pi2",
+			"Raspberry Pi 2 Model B Rev 1.1",
+			[]string{"raspberrypi,2-model-b", "brcm,bcm2836"},
+			"Raspberry Pi",
+			"pinctrl-bcm2835",
+			54,
+			// arch/arm/boot/dts/broadcom/bcm2836-rpi-2-b.dts
+			schematicLineNames("TXD0", "RXD0"),
+		},
+		{
+			"pi3",
+			"Raspberry Pi 3 Model B Rev 1.2",
+			[]string{"raspberrypi,3-model-b", "brcm,bcm2837"},
+			"Raspberry Pi",
+			"pinctrl-bcm2835",
+			54,
+			// arch/arm/boot/dts/broadcom/bcm2837-rpi-3-b.dts
+			schematicLineNames("TXD1", "RXD1"),
+		},
+		{
+			"zero2",
+			"Raspberry Pi Zero 2 W Rev 1.0",
+			[]string{"raspberrypi,model-zero-2-w", "brcm,bcm2837"},
+			"Raspberry Pi",
+			"pinctrl-bcm2835",
+			54,
+			// arch/arm/boot/dts/broadcom/bcm2837-rpi-zero-2-w.dts
+			gpioLineNames("ID_SDA", "ID_SCL"),
+		},
+		{
+			"pi4",
+			"Raspberry Pi 4 Model B Rev 1.4",
+			[]string{"raspberrypi,4-model-b", "brcm,bcm2711"},
+			"Raspberry Pi 4",
+			"pinctrl-bcm2711",
+			58,
+			// arch/arm/boot/dts/broadcom/bcm2711-rpi-4-b.dts
+			schematicLineNames("TXD1", "RXD1"),
+		},
+		{
+			"pi400",
+			"Raspberry Pi 400 Rev 1.0",
+			[]string{"raspberrypi,400", "brcm,bcm2711"},
+			"Raspberry Pi 4",
+			"pinctrl-bcm2711",
+			58,
+			// arch/arm/boot/dts/broadcom/bcm2711-rpi-400.dts
+			gpioLineNames("ID_SDA", "ID_SCL"),
+		},
+		{
+			"cm4",
+			"Raspberry Pi Compute Module 4 Rev 1.0",
+			[]string{"raspberrypi,4-compute-module", "brcm,bcm2711"},
+			"Raspberry Pi 4",
+			"pinctrl-bcm2711",
+			58,
+			// arch/arm/boot/dts/broadcom/bcm2711-rpi-cm4-io.dts
+			schematicLineNames("TXD1", "RXD1"),
+		},
+		{
+			"pi5",
+			"Raspberry Pi 5 Model B Rev 1.0",
+			[]string{"raspberrypi,5-model-b", "brcm,bcm2712"},
+			"Raspberry Pi 5",
+			"pinctrl-rp1",
+			54,
+			// arch/arm64/boot/dts/broadcom/bcm2712-rpi-5-b.dts in
+			// github.com/raspberrypi/linux
+			gpioLineNames("ID_SD", "ID_SC"),
+		},
+		{
+			"cm5",
+			"Raspberry Pi Compute Module 5 Rev 1.0",
+			[]string{"raspberrypi,5-compute-module", "brcm,bcm2712"},
+			"Raspberry Pi 5",
+			"pinctrl-rp1",
+			54,
+			// arch/arm64/boot/dts/broadcom/bcm2712-rpi-cm5.dtsi in
+			// github.com/raspberrypi/linux
+			gpioLineNames("ID_SD", "ID_SC"),
+		},
+	}
+	for _, bc := range boards {
+		t.Run(bc.name, func(t *testing.T) {
+			b, err := board.Identify(bc.model, bc.compatible)
+			require.Nil(t, err)
+			assert.Equal(t, bc.board, b.Name)
+
+			// consistent with the J8 mapping
+			for _, p := range patterns {
+				pin, err := b.Pin(p.name)
+				if p.err != nil || len(p.name) < 3 || p.name[:3] != "J8p" {
+					continue
+				}
+				require.Nil(t, err, p.name)
+				assert.Equal(t, bc.chip, pin.Chip, p.name)
+				assert.Equal(t, p.val, pin.Offset, p.name)
+			}
+			pin, err := b.Pin("J8p6")
+			assert.Nil(t, err)
+			assert.Equal(t, "GND", pin.Function)
+			assert.False(t, pin.IsGPIO())
+
+			// and with the line names of the model
+			options := []sim.ChipOption{sim.WithLabel(bc.chip)}
+			for o, name := range bc.names {
+				options = append(options, sim.WithNamedLine(o, name))
+			}
+			s, err := sim.NewChip(bc.lines, options...)
+			require.Nil(t, err)
+			defer s.Close()
+			c, err := gpiocdev.NewChip(s.ChipName())
+			require.Nil(t, err)
+			defer c.Close()
+			for number, gpio := range j8Pinout {
+				name := "J8p" + strconv.Itoa(number)
+				lines, err := b.ChipOffsets(name)
+				require.Nil(t, err, name)
+				assert.Equal(t, s.ChipName(), lines[0].Chip, name)
+				inf, err := c.LineInfo(lines[0].Offset)
+				require.Nil(t, err, name)
+				assert.Equal(t, bc.names[gpio], inf.Name, name)
+			}
+		})
+	}
+}
+
+func TestRequestLine(t *testing.T) {
+	_, err := rpi.RequestLine(-1)
+	assert.Equal(t, rpi.ErrInvalid, err)
+	_, err = rpi.RequestLine(rpi.MaxGPIOPin)
+	assert.Equal(t, rpi.ErrInvalid, err)
+	_, err = rpi.RequestLines([]int{rpi.J8p11, rpi.MaxGPIOPin})
+	assert.Equal(t, rpi.ErrInvalid, err)
+}
+
+// the GPIO connected to each of the J8 GPIO pins, keyed by pin number, as per
+// https://www.raspberrypi.com/documentation/computers/raspberry-pi.html#gpio
+var j8Pinout = map[int]int{
+	3: 2, 5: 3, 7: 4, 8: 14, 10: 15, 11: 17, 12: 18, 13: 27,
+	15: 22, 16: 23, 18: 24, 19: 10, 21: 9, 22: 25, 23: 11, 24: 8,
+	26: 7, 27: 0, 28: 1, 29: 5, 31: 6, 32: 12, 33: 13, 35: 19,
+	36: 16, 37: 26, 38: 20, 40: 21,
+}
+
+// schematicLineNames returns the gpio-line-names of GPIO0 to GPIO27 from the
+// device trees that name the lines after their function on the schematic,
+// given the names of the UART lines, which depend on the UART connected to
+// the header.
+func schematicLineNames(txd, rxd string) []string {
+	return []string{
+		"ID_SDA",
+		"ID_SCL",
+		"SDA1",
+		"SCL1",
+		"GPIO_GCLK",
+		"GPIO5",
+		"GPIO6",
+		"SPI_CE1_N",
+		"SPI_CE0_N",
+		"SPI_MISO",
+		"SPI_MOSI",
+		"SPI_SCLK",
+		"GPIO12",
+		"GPIO13",
+		txd,
+		rxd,
+		"GPIO16",
+		"GPIO17",
+		"GPIO18",
+		"GPIO19",
+		"GPIO20",
+		"GPIO21",
+		"GPIO22",
+		"GPIO23",
+		"GPIO24",
+		"GPIO25",
+		"GPIO26",
+		"GPIO27",
+	}
+}
+
+// gpioLineNames returns the gpio-line-names of GPIO0 to GPIO27 from the
+// device trees that name the lines GPIO2 to GPIO27, given the names of the ID
+// EEPROM lines.
+func gpioLineNames(idsd, idsc string) []string {
+	names := []string{idsd, idsc}
+	for i := 2; i < rpi.MaxGPIOPin; i++ {
+		names = append(names, "GPIO"+strconv.Itoa(i))
+	}
+	return names
+}
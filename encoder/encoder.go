@@ -0,0 +1,488 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package encoder provides a decoder for quadrature rotary encoders connected
+// to GPIO lines.
+//
+// The encoder is decoded from the edge events on its A and B lines, so no
+// polling is required:
+//
+//	e, _ := encoder.New("gpiochip0", 17, 18, encoder.WithButton(27))
+//	defer e.Close()
+//	for c := range e.Changes() {
+//		fmt.Println(c.Position)
+//	}
+package encoder
+
+import (
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+	"golang.org/x/sys/unix"
+)
+
+// Encoder decodes the position of a quadrature rotary encoder.
+type Encoder struct {
+	ll *gpiocdev.Lines
+
+	// the offsets of the A, B and optional button lines.
+	a, b, button int
+
+	// the number of quadrature transitions per position step.
+	div int
+
+	changes chan Change
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	// the levels of the A and B lines, as a quadrature state (A<<1 | B).
+	state int
+
+	// the count of valid quadrature transitions.
+	count int
+
+	// the current position, in steps.
+	position int
+
+	// the direction of the most recent step.
+	direction int
+
+	// the timestamp of the most recent step, and of the step before it.
+	lastStep time.Duration
+	prevStep time.Duration
+
+	// the timestamp of the most recent event accepted.
+	lastEvent time.Duration
+
+	// the time the state was last read from the lines.
+	//
+	// Events with earlier timestamps are already reflected in the state.
+	syncTs time.Duration
+
+	// the seqno of the most recent event.
+	seqno uint32
+
+	// the line seqno of the most recent event on each line, keyed by offset.
+	lineSeqno map[int]uint32
+
+	// the number of events rejected as invalid.
+	rejected uint64
+
+	pressed bool
+
+	closed bool
+}
+
+// Mode determines the number of positions counted for each quadrature
+// cycle.
+type Mode int
+
+const (
+	// X1 counts one position per quadrature cycle.
+	X1 Mode = 1
+
+	// X2 counts two positions per quadrature cycle.
+	X2 Mode = 2
+
+	// X4 counts a position for every edge on either line, so four positions
+	// per quadrature cycle.
+	X4 Mode = 4
+)
+
+// ChangeType identifies the type of change to the encoder.
+type ChangeType int
+
+const (
+	// ChangeRotation indicates the position of the encoder has changed.
+	ChangeRotation ChangeType = iota
+
+	// ChangePress indicates the encoder button has been pressed.
+	ChangePress
+
+	// ChangeRelease indicates the encoder button has been released.
+	ChangeRelease
+)
+
+// Change describes a change to the encoder.
+type Change struct {
+	// The type of change.
+	Type ChangeType
+
+	// The position of the encoder after the change.
+	Position int
+
+	// The direction of rotation, +1 for clockwise (A leads B), -1 for
+	// counter-clockwise.
+	//
+	// Only set for ChangeRotation.
+	Direction int
+
+	// The timestamp of the edge event that caused the change.
+	Timestamp time.Duration
+}
+
+// Option defines the interface required to provide an option to New.
+type Option interface {
+	applyOption(*encoderOptions)
+}
+
+type encoderOptions struct {
+	mode       Mode
+	button     int
+	buttonOpts []gpiocdev.SubsetLineConfigOption
+	reqOpts    []gpiocdev.LineReqOption
+	chanSize   int
+}
+
+// ModeOption sets the counting mode of the encoder.
+type ModeOption Mode
+
+// WithMode sets the number of positions counted per quadrature cycle.
+//
+// The default is X4.
+func WithMode(mode Mode) ModeOption {
+	return ModeOption(mode)
+}
+
+func (o ModeOption) applyOption(opts *encoderOptions) {
+	opts.mode = Mode(o)
+}
+
+// ButtonOption specifies the push-button line of the encoder.
+type ButtonOption struct {
+	offset  int
+	options []gpiocdev.SubsetLineConfigOption
+}
+
+// WithButton specifies the offset of the push-button line of the encoder,
+// which must be on the same chip as the A and B lines.
+//
+// The options are applied to the button line, after any WithRequestOptions,
+// such as gpiocdev.AsActiveLow for a button that pulls the line low.
+func WithButton(offset int, options ...gpiocdev.SubsetLineConfigOption) ButtonOption {
+	return ButtonOption{offset, options}
+}
+
+func (o ButtonOption) applyOption(opts *encoderOptions) {
+	opts.button = o.offset
+	opts.buttonOpts = o.options
+}
+
+// RequestOption provides options for the line request.
+type RequestOption []gpiocdev.LineReqOption
+
+// WithRequestOptions provides options for the request of the encoder lines,
+// such as bias, debounce or consumer.
+//
+// Edge detection and event handling options are overridden by the encoder.
+func WithRequestOptions(options ...gpiocdev.LineReqOption) RequestOption {
+	return RequestOption(options)
+}
+
+func (o RequestOption) applyOption(opts *encoderOptions) {
+	opts.reqOpts = append(opts.reqOpts, o...)
+}
+
+// ChannelSizeOption sets the size of the change channel.
+type ChannelSizeOption int
+
+// WithChannelSize sets the buffer size of the change channel.
+//
+// The default is 16.
+func WithChannelSize(size int) ChannelSizeOption {
+	return ChannelSizeOption(size)
+}
+
+func (o ChannelSizeOption) applyOption(opts *encoderOptions) {
+	opts.chanSize = int(o)
+}
+
+// the transitions between quadrature states, indexed by prev<<2 | next,
+// where the state is A<<1 | B.
+//
+// 1 is a step clockwise, -1 a step counter-clockwise, 0 no change, and 2
+// an invalid transition, where both lines changed.
+var transitions = [16]int{
+	0, -1, 1, 2,
+	1, 0, 2, -1,
+	-1, 2, 0, 1,
+	2, 1, -1, 0,
+}
+
+// New requests the A and B lines, and optional button line, of an encoder
+// from the chip, and starts decoding the encoder.
+//
+// The position starts at 0.
+func New(chip string, a, b int, options ...Option) (*Encoder, error) {
+	opts := encoderOptions{mode: X4, button: -1, chanSize: 16}
+	for _, option := range options {
+		option.applyOption(&opts)
+	}
+	if opts.mode != X1 && opts.mode != X2 && opts.mode != X4 {
+		return nil, unix.EINVAL
+	}
+	e := &Encoder{
+		a:         a,
+		b:         b,
+		button:    opts.button,
+		div:       4 / int(opts.mode),
+		changes:   make(chan Change, opts.chanSize),
+		lineSeqno: map[int]uint32{},
+	}
+	offsets := []int{a, b}
+	if e.button >= 0 {
+		offsets = append(offsets, e.button)
+	}
+	reqOpts := append([]gpiocdev.LineReqOption{}, opts.reqOpts...)
+	reqOpts = append(reqOpts,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(e.handle))
+	if e.button >= 0 && len(opts.buttonOpts) != 0 {
+		reqOpts = append(reqOpts, gpiocdev.WithLines([]int{e.button}, opts.buttonOpts...))
+	}
+	// hold the lock so events are not handled until the initial state is known.
+	e.mu.Lock()
+	ll, err := gpiocdev.RequestLines(chip, offsets, reqOpts...)
+	if err != nil {
+		e.mu.Unlock()
+		return nil, err
+	}
+	e.ll = ll
+	err = e.resync()
+	e.mu.Unlock()
+	if err != nil {
+		ll.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// Close releases the encoder lines and closes the change channel.
+func (e *Encoder) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return gpiocdev.ErrClosed
+	}
+	e.closed = true
+	e.mu.Unlock()
+	// closing the lines waits for the handler to return, so it is safe to
+	// close the channel after.
+	err := e.ll.Close()
+	close(e.changes)
+	return err
+}
+
+// Changes returns the channel that changes to the encoder are delivered to.
+//
+// If the channel is full then further changes are discarded, though the
+// position remains accurate.
+//
+// The channel is closed when the encoder is closed.
+func (e *Encoder) Changes() <-chan Change {
+	return e.changes
+}
+
+// Position returns the current position of the encoder.
+func (e *Encoder) Position() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.position
+}
+
+// SetPosition sets the current position of the encoder.
+func (e *Encoder) SetPosition(position int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.position = position
+	e.count = position * e.div
+}
+
+// Direction returns the direction of the most recent step, +1 for clockwise
+// and -1 for counter-clockwise, or 0 if the encoder has not moved.
+func (e *Encoder) Direction() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.direction
+}
+
+// Velocity returns the velocity of the encoder, in positions per second.
+//
+// The velocity is determined from the interval between the two most recent
+// steps, and decays towards zero if the encoder has not moved for longer than
+// that interval.
+//
+// Requires the lines use the monotonic event clock, which is the default.
+func (e *Encoder) Velocity() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.prevStep == 0 {
+		return 0
+	}
+	interval := e.lastStep - e.prevStep
+	if since := monotonicNow() - e.lastStep; since > interval {
+		interval = since
+	}
+	if interval <= 0 {
+		return 0
+	}
+	return float64(e.direction) * float64(time.Second) / float64(interval)
+}
+
+// Pressed returns true if the encoder button is pressed.
+//
+// Always false if the encoder has no button.
+func (e *Encoder) Pressed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pressed
+}
+
+// Rejected returns the number of edge events rejected as invalid.
+//
+// Events are rejected if they would cause an invalid quadrature transition,
+// are out of order, or follow events lost by the kernel.
+func (e *Encoder) Rejected() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.rejected
+}
+
+// handle decodes the edge event.
+func (e *Encoder) handle(evt gpiocdev.LineEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if evt.Timestamp < e.syncTs {
+		// queued before the state was read, so already accounted for.
+		e.lost(evt)
+		return
+	}
+	if e.lost(evt) {
+		// the state is stale, so resync from the current line values.
+		e.rejected++
+		e.resync()
+		return
+	}
+	if evt.Timestamp < e.lastEvent {
+		// out of order
+		e.rejected++
+		return
+	}
+	e.lastEvent = evt.Timestamp
+	level := 0
+	if evt.Type == gpiocdev.LineEventRisingEdge {
+		level = 1
+	}
+	if evt.Offset == e.button {
+		pressed := level == 1
+		if pressed == e.pressed {
+			return
+		}
+		e.pressed = pressed
+		c := Change{Type: ChangeRelease, Position: e.position, Timestamp: evt.Timestamp}
+		if pressed {
+			c.Type = ChangePress
+		}
+		e.send(c)
+		return
+	}
+	next := e.state
+	if evt.Offset == e.a {
+		next = next&^2 | level<<1
+	} else {
+		next = next&^1 | level
+	}
+	step := transitions[e.state<<2|next]
+	e.state = next
+	if step == 0 || step == 2 {
+		// repeated edge on the line, so an edge has been missed.
+		e.rejected++
+		return
+	}
+	e.count += step
+	position := floorDiv(e.count, e.div)
+	if position == e.position {
+		return
+	}
+	e.direction = position - e.position
+	e.position = position
+	e.prevStep = e.lastStep
+	e.lastStep = evt.Timestamp
+	e.send(Change{
+		Type:      ChangeRotation,
+		Position:  position,
+		Direction: e.direction,
+		Timestamp: evt.Timestamp,
+	})
+}
+
+// lost updates the sequence numbers from the event and returns true if events
+// have been lost since the previous event.
+//
+// Sequence numbers are only available with uAPI v2, so lost events are not
+// detected with uAPI v1.
+//
+// Assumes e is locked.
+func (e *Encoder) lost(evt gpiocdev.LineEvent) bool {
+	if evt.Type == gpiocdev.LineEventOverflow {
+		return true
+	}
+	if evt.Seqno == 0 {
+		return false
+	}
+	lost := e.seqno != 0 && evt.Seqno != e.seqno+1
+	e.seqno = evt.Seqno
+	if last, ok := e.lineSeqno[evt.Offset]; ok && evt.LineSeqno != last+1 {
+		lost = true
+	}
+	e.lineSeqno[evt.Offset] = evt.LineSeqno
+	return lost
+}
+
+// resync sets the state from the current values of the lines.
+//
+// Events queued before the values are read are discarded, so requires the
+// lines use the monotonic event clock, which is the default.
+//
+// Assumes e is locked.
+func (e *Encoder) resync() error {
+	ts := monotonicNow()
+	values := make([]int, len(e.ll.Offsets()))
+	if err := e.ll.Values(values); err != nil {
+		return err
+	}
+	e.syncTs = ts
+	e.state = values[0]<<1 | values[1]
+	if e.button >= 0 {
+		e.pressed = values[2] == 1
+	}
+	return nil
+}
+
+// send delivers the change to the channel, unless the channel is full.
+//
+// Assumes e is locked.
+func (e *Encoder) send(c Change) {
+	select {
+	case e.changes <- c:
+	default:
+	}
+}
+
+// floorDiv returns x/y rounded towards negative infinity.
+func floorDiv(x, y int) int {
+	q := x / y
+	if (x%y != 0) && ((x < 0) != (y < 0)) {
+		q--
+	}
+	return q
+}
+
+func monotonicNow() time.Duration {
+	var ts unix.Timespec
+	unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts)
+	return time.Duration(ts.Nano())
+}
@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package encoder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/encoder"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	lineA      = 1
+	lineB      = 2
+	lineButton = 3
+)
+
+// cw rotates the encoder clockwise by one quadrature cycle.
+func cw(s *sim.Chip) {
+	for _, o := range []int{lineA, lineB, lineA, lineB} {
+		s.Toggle(o)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// ccw rotates the encoder counter-clockwise by one quadrature cycle.
+func ccw(s *sim.Chip) {
+	for _, o := range []int{lineB, lineA, lineB, lineA} {
+		s.Toggle(o)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNew(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	_, err = encoder.New(s.ChipName(), lineA, lineB, encoder.WithMode(3))
+	assert.Equal(t, unix.EINVAL, err)
+
+	_, err = encoder.New(s.ChipName(), lineA, 4)
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	e, err := encoder.New(s.ChipName(), lineA, lineB)
+	require.Nil(t, err)
+	assert.Equal(t, 0, e.Position())
+	assert.Equal(t, 0, e.Direction())
+	assert.Zero(t, e.Velocity())
+	assert.False(t, e.Pressed())
+	assert.Nil(t, e.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, e.Close())
+	_, ok := <-e.Changes()
+	assert.False(t, ok)
+}
+
+func TestModes(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	for _, mode := range []encoder.Mode{encoder.X1, encoder.X2, encoder.X4} {
+		e, err := encoder.New(s.ChipName(), lineA, lineB, encoder.WithMode(mode))
+		require.Nil(t, err)
+		cw(s)
+		cw(s)
+		waitPosition(t, e, 2*int(mode))
+		assert.Equal(t, 1, e.Direction())
+		assert.Greater(t, e.Velocity(), 0.0)
+		ccw(s)
+		waitPosition(t, e, int(mode))
+		assert.Equal(t, -1, e.Direction())
+		assert.Less(t, e.Velocity(), 0.0)
+		assert.Zero(t, e.Rejected())
+		e.Close()
+	}
+}
+
+func TestChanges(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	e, err := encoder.New(s.ChipName(), lineA, lineB,
+		encoder.WithMode(encoder.X1),
+		encoder.WithButton(lineButton))
+	require.Nil(t, err)
+	defer e.Close()
+
+	cw(s)
+	c := waitChange(t, e)
+	assert.Equal(t, encoder.ChangeRotation, c.Type)
+	assert.Equal(t, 1, c.Position)
+	assert.Equal(t, 1, c.Direction)
+	assert.NotZero(t, c.Timestamp)
+
+	s.Pullup(lineButton)
+	c = waitChange(t, e)
+	assert.Equal(t, encoder.ChangePress, c.Type)
+	assert.Equal(t, 1, c.Position)
+	assert.True(t, e.Pressed())
+
+	s.Pulldown(lineButton)
+	c = waitChange(t, e)
+	assert.Equal(t, encoder.ChangeRelease, c.Type)
+	assert.False(t, e.Pressed())
+
+	ccw(s)
+	ccw(s)
+	c = waitChange(t, e)
+	assert.Equal(t, 0, c.Position)
+	assert.Equal(t, -1, c.Direction)
+	c = waitChange(t, e)
+	assert.Equal(t, -1, c.Position)
+
+	e.SetPosition(10)
+	assert.Equal(t, 10, e.Position())
+	cw(s)
+	c = waitChange(t, e)
+	assert.Equal(t, 11, c.Position)
+}
+
+func TestRejected(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	// a blocked handler sharing the loop stalls the encoder, so the events
+	// overflow the tiny kernel buffer.
+	el, err := gpiocdev.NewEventLoop()
+	require.Nil(t, err)
+	defer el.Close()
+	block := make(chan struct{})
+	l, err := gpiocdev.RequestLine(s.ChipName(), 0,
+		gpiocdev.WithRisingEdge,
+		gpiocdev.WithEventLoop(el),
+		gpiocdev.WithEventHandler(func(gpiocdev.LineEvent) { <-block }))
+	require.Nil(t, err)
+	defer l.Close()
+	e, err := encoder.New(s.ChipName(), lineA, lineB,
+		encoder.WithRequestOptions(
+			gpiocdev.WithEventBufferSize(1),
+			gpiocdev.WithEventLoop(el)))
+	require.Nil(t, err)
+	defer e.Close()
+
+	s.Pullup(0)
+	time.Sleep(10 * time.Millisecond)
+	cw(s)
+	close(block)
+	assert.Eventually(t, func() bool { return e.Rejected() != 0 },
+		time.Second, time.Millisecond)
+
+	// the encoder resyncs after the lost events.
+	p := e.Position()
+	cw(s)
+	waitPosition(t, e, p+4)
+}
+
+func TestResyncDiscardsStale(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	el, err := gpiocdev.NewEventLoop()
+	require.Nil(t, err)
+	defer el.Close()
+	block := make(chan struct{})
+	l, err := gpiocdev.RequestLine(s.ChipName(), 0,
+		gpiocdev.WithRisingEdge,
+		gpiocdev.WithEventLoop(el),
+		gpiocdev.WithEventHandler(func(gpiocdev.LineEvent) { <-block }))
+	require.Nil(t, err)
+	defer l.Close()
+	e, err := encoder.New(s.ChipName(), lineA, lineB,
+		encoder.WithRequestOptions(
+			gpiocdev.WithEventBufferSize(4),
+			gpiocdev.WithEventLoop(el)))
+	require.Nil(t, err)
+	defer e.Close()
+
+	s.Toggle(lineA)
+	c := waitChange(t, e)
+	assert.Equal(t, 1, c.Position)
+
+	// stall the encoder while the lines step on, so the oldest events are
+	// lost and the remaining events are stale once the encoder resyncs.
+	s.Pullup(0)
+	time.Sleep(10 * time.Millisecond)
+	for _, o := range []int{lineB, lineA, lineB, lineA, lineB, lineA} {
+		s.Toggle(o)
+		time.Sleep(time.Millisecond)
+	}
+	close(block)
+	assert.Eventually(t, func() bool { return e.Rejected() != 0 },
+		time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	// only the first event after the gap is rejected, and the stale events
+	// do not move the encoder.
+	assert.Equal(t, uint64(1), e.Rejected())
+	assert.Equal(t, 1, e.Position())
+	select {
+	case c = <-e.Changes():
+		assert.Fail(t, "unexpected change", c)
+	default:
+	}
+
+	// and continue from the resynced state.
+	s.Toggle(lineB)
+	cw(s)
+	waitPosition(t, e, 6)
+}
+
+func waitPosition(t *testing.T, e *encoder.Encoder, position int) {
+	t.Helper()
+	assert.Eventually(t, func() bool { return e.Position() == position },
+		time.Second, time.Millisecond)
+}
+
+func waitChange(t *testing.T, e *encoder.Encoder) encoder.Change {
+	t.Helper()
+	select {
+	case c := <-e.Changes():
+		return c
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for change")
+	}
+	return encoder.Change{}
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev
+
+import "sync"
+
+// eventChannel forwards line events from the watcher to a channel.
+type eventChannel struct {
+	ch chan LineEvent
+
+	// the policy applied when ch is full
+	policy EventChannelPolicy
+
+	// closed to release any blocked handler
+	doneCh chan struct{}
+
+	// ensures ch is only closed once, whether by the watcher failing or the
+	// request being closed.
+	closeOnce sync.Once
+
+	// indicates the watcher has failed and ch has been closed.
+	//
+	// Only accessed from the watcher goroutine.
+	failed bool
+}
+
+func newEventChannel(o EventChannelOption) *eventChannel {
+	return &eventChannel{
+		ch:     make(chan LineEvent, o.size),
+		policy: o.policy,
+		doneCh: make(chan struct{}),
+	}
+}
+
+// handle delivers the event to the channel.
+//
+// It is called from the watcher goroutine.
+func (ec *eventChannel) handle(evt LineEvent) {
+	if ec.failed {
+		return
+	}
+	switch ec.policy {
+	case EventChannelDropNewest:
+		select {
+		case ec.ch <- evt:
+		default:
+		}
+	case EventChannelDropOldest:
+		for {
+			select {
+			case ec.ch <- evt:
+				return
+			default:
+			}
+			select {
+			case <-ec.ch:
+			default:
+			}
+		}
+	default:
+		select {
+		case ec.ch <- evt:
+		case <-ec.doneCh:
+		}
+	}
+}
+
+// stop releases any blocked handler.
+//
+// Must be called before the watcher is closed.
+func (ec *eventChannel) stop() {
+	close(ec.doneCh)
+}
+
+// close closes the channel.
+//
+// Must only be called once the watcher has exited.
+func (ec *eventChannel) close() {
+	ec.closeOnce.Do(func() { close(ec.ch) })
+}
+
+// errorHandler returns an ErrorHandler that closes the channel, as no further
+// events will be delivered, before passing the error to errh, if set.
+//
+// The returned handler is called from the watcher goroutine.
+func (ec *eventChannel) errorHandler(errh ErrorHandler) ErrorHandler {
+	return func(err error) {
+		ec.failed = true
+		ec.close()
+		if errh != nil {
+			errh(err)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev
+
+import (
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// EventLoop reads and dispatches the events for a set of line requests and chip
+// info watches from a single goroutine.
+//
+// By default each line request with an event handler, and each chip with an
+// info watch, has its own goroutine, epoll instance and eventfd to read and
+// dispatch its events.  An EventLoop allows those resources to be shared by
+// any number of requests and chips, using the WithEventLoop option.
+//
+// As the handlers for all requests and chips registered with the loop are
+// called from the one goroutine, a handler that blocks delays the delivery of
+// events to all the others.
+type EventLoop struct {
+	epfd int
+
+	// eventfd to signal loop to shutdown
+	donefd int
+
+	// closed once loop exits
+	doneCh chan struct{}
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	// signalled when a source completes a dispatch.
+	cond sync.Cond
+
+	// the sources registered with the loop, keyed by id.
+	sources map[int32]*eventSource
+
+	// the id of the most recently added source.
+	lastID int32
+
+	// called after each set of ready sources has been dispatched.
+	flushers []flusher
+
+	// indicates the flushers are being called.
+	flushing bool
+
+	// the error that caused the loop to exit, if any.
+	err error
+
+	// indicates the loop goroutine has exited.
+	exited bool
+
+	// indicates the loop has been closed.
+	closed bool
+}
+
+// eventSource is a file descriptor registered with the EventLoop.
+type eventSource struct {
+	// the identifier of the source within the loop.
+	//
+	// This is used in place of the fd in the epoll data, so events pending
+	// for a removed source are not misdirected to a later source that reuses
+	// the fd.
+	id int32
+
+	fd int
+
+	// reads and dispatches the events available from the fd.
+	read func(fd int) error
+
+	// called if reading the fd fails, after the source has been removed from
+	// the loop.
+	fail func(err error)
+
+	// indicates the source is being dispatched.
+	//
+	// Covered by the EventLoop mutex.
+	active bool
+}
+
+// the id reserved for the donefd.
+const doneID = 0
+
+// flusher is notified by the EventLoop once it has dispatched all the sources
+// found ready by a single wait.
+//
+// This allows events read from several sources to be collected and then
+// delivered together.
+type flusher interface {
+	flush()
+}
+
+// NewEventLoop creates a new EventLoop.
+//
+// The loop should be closed once all requests and chips registered with it
+// have been closed.
+func NewEventLoop() (el *EventLoop, err error) {
+	var epfd, donefd int
+	epfd, err = unix.EpollCreate1(unix.EPOLL_CLOEXEC)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			unix.Close(epfd)
+		}
+	}()
+	donefd, err = unix.Eventfd(0, unix.EFD_CLOEXEC)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			unix.Close(donefd)
+		}
+	}()
+	epv := unix.EpollEvent{Events: unix.EPOLLIN, Fd: doneID}
+	err = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, donefd, &epv)
+	if err != nil {
+		return
+	}
+	el = &EventLoop{
+		epfd:    epfd,
+		donefd:  donefd,
+		doneCh:  make(chan struct{}),
+		sources: map[int32]*eventSource{},
+	}
+	el.cond.L = &el.mu
+	go el.run()
+	return
+}
+
+// Close stops the loop and releases its resources.
+//
+// Events are no longer dispatched for any requests or chips still registered
+// with the loop.
+//
+// Close waits for any running handler to return, so must not be called from
+// the context of a handler.
+func (el *EventLoop) Close() error {
+	el.mu.Lock()
+	closed := el.closed
+	el.closed = true
+	el.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	unix.Write(el.donefd, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+	<-el.doneCh
+	unix.Close(el.donefd)
+	return nil
+}
+
+// WithEventLoop indicates that the events for a line request, or for the line
+// info watches of a chip, are to be read and dispatched by the provided loop.
+//
+// When applied to a chip it also provides the default loop for lines requested
+// from the chip.
+func WithEventLoop(el *EventLoop) *EventLoop {
+	return el
+}
+
+func (el *EventLoop) applyChipOption(c *ChipOptions) {
+	c.loop = el
+}
+
+func (el *EventLoop) applyLineReqOption(lro *lineReqOptions) {
+	lro.loop = el
+}
+
+// Err returns the error that caused the loop to stop dispatching events, if
+// any.
+//
+// Returns nil while the loop is running, or if it was stopped by Close.
+func (el *EventLoop) Err() error {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	return el.err
+}
+
+// add registers the fd with the loop.
+//
+// The read function is called, from the loop goroutine, when the fd is ready
+// to read.  If the read function returns an error, other than EAGAIN or EINTR,
+// then the source is removed from the loop and the fail function is called
+// with the error.
+func (el *EventLoop) add(fd int, read func(fd int) error, fail func(err error)) (*eventSource, error) {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	if el.closed {
+		return nil, ErrClosed
+	}
+	if el.err != nil {
+		return nil, el.err
+	}
+	id := el.lastID + 1
+	for ; id == doneID || el.sources[id] != nil; id++ {
+	}
+	src := &eventSource{id: id, fd: fd, read: read, fail: fail}
+	epv := unix.EpollEvent{Events: unix.EPOLLIN, Fd: id}
+	err := unix.EpollCtl(el.epfd, unix.EPOLL_CTL_ADD, fd, &epv)
+	if err != nil {
+		return nil, err
+	}
+	el.lastID = id
+	el.sources[id] = src
+	return src, nil
+}
+
+// remove deregisters the source from the loop.
+//
+// Waits for any dispatch of the source in progress to complete, so must not be
+// called from the context of the source's own read function.
+func (el *EventLoop) remove(src *eventSource) {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	if el.sources[src.id] != src {
+		return
+	}
+	delete(el.sources, src.id)
+	if !el.exited {
+		unix.EpollCtl(el.epfd, unix.EPOLL_CTL_DEL, src.fd, nil)
+	}
+	for src.active {
+		el.cond.Wait()
+	}
+}
+
+// addFlusher registers the flusher with the loop.
+func (el *EventLoop) addFlusher(f flusher) {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	el.flushers = append(el.flushers, f)
+}
+
+// removeFlusher deregisters the flusher from the loop.
+//
+// Waits for any flush in progress to complete, so must not be called from the
+// context of a flush or read function.
+func (el *EventLoop) removeFlusher(f flusher) {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	for i, ff := range el.flushers {
+		if ff == f {
+			el.flushers = append(el.flushers[:i:i], el.flushers[i+1:]...)
+			break
+		}
+	}
+	for el.flushing {
+		el.cond.Wait()
+	}
+}
+
+// flush calls the flushers.
+func (el *EventLoop) flush() {
+	el.mu.Lock()
+	if len(el.flushers) == 0 {
+		el.mu.Unlock()
+		return
+	}
+	el.flushing = true
+	ff := el.flushers
+	el.mu.Unlock()
+
+	for _, f := range ff {
+		f.flush()
+	}
+
+	el.mu.Lock()
+	el.flushing = false
+	el.cond.Broadcast()
+	el.mu.Unlock()
+}
+
+func (el *EventLoop) run() {
+	epollEvents := make([]unix.EpollEvent, 16)
+	defer close(el.doneCh)
+	for {
+		n, err := unix.EpollWait(el.epfd, epollEvents[:], -1)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			el.stop(err)
+			return
+		}
+		for i := 0; i < n; i++ {
+			id := epollEvents[i].Fd
+			if id == doneID {
+				el.stop(nil)
+				return
+			}
+			el.dispatch(id)
+		}
+		el.flush()
+	}
+}
+
+// stop releases the epoll instance and, if err is set, notifies all the
+// sources of the error.
+func (el *EventLoop) stop(err error) {
+	el.mu.Lock()
+	el.exited = true
+	unix.Close(el.epfd)
+	if err == nil {
+		el.mu.Unlock()
+		return
+	}
+	el.err = err
+	srcs := el.sources
+	el.sources = map[int32]*eventSource{}
+	el.mu.Unlock()
+	for _, src := range srcs {
+		src.fail(err)
+	}
+}
+
+func (el *EventLoop) dispatch(id int32) {
+	el.mu.Lock()
+	src := el.sources[id]
+	if src == nil {
+		// removed since the epoll event was generated
+		el.mu.Unlock()
+		return
+	}
+	src.active = true
+	el.mu.Unlock()
+
+	err := src.read(src.fd)
+	if err == unix.EAGAIN || err == unix.EINTR {
+		err = nil
+	}
+
+	el.mu.Lock()
+	src.active = false
+	if err != nil && el.sources[id] == src {
+		delete(el.sources, id)
+		unix.EpollCtl(el.epfd, unix.EPOLL_CTL_DEL, src.fd, nil)
+	}
+	el.cond.Broadcast()
+	el.mu.Unlock()
+	if err != nil {
+		src.fail(err)
+	}
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// eventTracker detects events lost by the kernel using the gaps in the event
+// sequence numbers.
+//
+// Requires uAPI v2.
+type eventTracker struct {
+	// the total number of events lost on the request.
+	lost uint64
+
+	// mu covers the attributes below it.
+	mu sync.Mutex
+
+	// the last seqno seen for the request.
+	seqno uint32
+
+	// the last seqno seen for each line, keyed by offset.
+	lineSeqno map[int]uint32
+}
+
+func newEventTracker() *eventTracker {
+	return &eventTracker{lineSeqno: map[int]uint32{}}
+}
+
+// update updates the tracker with the event and returns the number of events
+// lost on the line since its previous event.
+func (et *eventTracker) update(evt LineEvent) uint32 {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+	return et.track(evt)
+}
+
+// track updates the tracker with the event and returns the number of events
+// lost on the line since its previous event.
+//
+// Assumes et is locked.
+func (et *eventTracker) track(evt LineEvent) uint32 {
+	if gap := int32(evt.Seqno - et.seqno - 1); gap > 0 {
+		atomic.AddUint64(&et.lost, uint64(gap))
+	}
+	et.seqno = evt.Seqno
+	var lost uint32
+	if gap := int32(evt.LineSeqno - et.lineSeqno[evt.Offset] - 1); gap > 0 {
+		lost = uint32(gap)
+	}
+	et.lineSeqno[evt.Offset] = evt.LineSeqno
+	return lost
+}
+
+// lostEvents returns the total number of events lost on the request.
+func (et *eventTracker) lostEvents() uint64 {
+	return atomic.LoadUint64(&et.lost)
+}
+
+// handler wraps the batch handler to track events and, if overflow is set,
+// to report lost events to the handler.
+func (et *eventTracker) handler(bh EventBatchHandler, overflow bool) EventBatchHandler {
+	// batch including overflow events
+	var obuf []LineEvent
+	return func(evts []LineEvent) {
+		et.mu.Lock()
+		if !overflow {
+			for _, evt := range evts {
+				et.track(evt)
+			}
+			et.mu.Unlock()
+			bh(evts)
+			return
+		}
+		for i, evt := range evts {
+			lost := et.track(evt)
+			if lost == 0 {
+				if len(obuf) != 0 {
+					obuf = append(obuf, evt)
+				}
+				continue
+			}
+			if len(obuf) == 0 {
+				obuf = append(obuf, evts[:i]...)
+			}
+			obuf = append(obuf,
+				LineEvent{
+					Offset:    evt.Offset,
+					Timestamp: evt.Timestamp,
+					Type:      LineEventOverflow,
+					Seqno:     evt.Seqno,
+					LineSeqno: evt.LineSeqno,
+					Lost:      lost,
+				},
+				evt)
+		}
+		et.mu.Unlock()
+		if len(obuf) == 0 {
+			bh(evts)
+			return
+		}
+		bh(obuf)
+		obuf = obuf[:0]
+	}
+}
@@ -33,6 +33,7 @@
 package gpiocdev
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -49,7 +50,14 @@ import (
 
 // Chip represents a single GPIO chip that controls a set of lines.
 type Chip struct {
+	// the backend providing access to the chip.
+	be Backend
+
+	// the GPIO character device, for uAPI v1.
+	//
+	// nil for other backends.
 	f *os.File
+
 	// The system name for this chip.
 	Name string
 
@@ -222,7 +230,7 @@ func Chips() []string {
 	}
 	// sort in numeric order
 	sort.Slice(cc, func(i, j int) bool { return naturalLess(cc[i], cc[j]) })
-	return cc
+	return append(cc, registeredChips()...)
 }
 
 // RequestLine requests control of a single line on a chip.
@@ -249,31 +257,75 @@ func RequestLines(chip string, offsets []int, options ...LineReqOption) (*Lines,
 	return c.RequestLines(offsets, options...)
 }
 
-// NewChip opens a GPIO character device.
-func NewChip(name string, options ...ChipOption) (*Chip, error) {
-	path := nameToPath(name)
-	err := IsChip(path)
+// RequestLineContext requests control of a single line on a chip, for the
+// lifetime of the context.
+//
+// If granted, control is maintained until the Line is closed or the context is
+// done, whichever comes first.
+func RequestLineContext(ctx context.Context, chip string, offset int, options ...LineReqOption) (*Line, error) {
+	c, err := NewChip(chip)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.RequestLineContext(ctx, offset, options...)
+}
+
+// RequestLinesContext requests control of a collection of lines on a chip, for
+// the lifetime of the context.
+//
+// If granted, control is maintained until the Lines are closed or the context
+// is done, whichever comes first.
+func RequestLinesContext(ctx context.Context, chip string, offsets []int, options ...LineReqOption) (*Lines, error) {
+	c, err := NewChip(chip)
 	if err != nil {
 		return nil, err
 	}
+	defer c.Close()
+	return c.RequestLinesContext(ctx, offsets, options...)
+}
+
+// NewChip opens a GPIO character device, or a chip registered using
+// RegisterBackend.
+func NewChip(name string, options ...ChipOption) (*Chip, error) {
+	open := registeredBackend(name)
+	if open == nil {
+		path := nameToPath(name)
+		if err := IsChip(path); err != nil {
+			return nil, err
+		}
+	}
 	co := ChipOptions{
 		consumer: "gpiocdev-" + strconv.Itoa(os.Getpid()),
 	}
 	for _, option := range options {
 		option.applyChipOption(&co)
 	}
-	f, err := os.OpenFile(path, unix.O_CLOEXEC, unix.O_RDONLY)
+	var f *os.File
+	var be Backend
+	var err error
+	if open != nil {
+		if co.abi == 1 {
+			return nil, ErrUapiIncompatibility{"alternative backend", 1}
+		}
+		co.abi = 2
+		be, err = open()
+	} else {
+		f, err = os.OpenFile(nameToPath(name), unix.O_CLOEXEC, unix.O_RDONLY)
+		be = chardev{f}
+	}
 	if err != nil {
 		// only happens if device removed/locked since IsChip call.
 		return nil, err
 	}
-	ci, err := uapi.GetChipInfo(f.Fd())
+	ci, err := be.ChipInfo()
 	if err != nil {
 		// only occurs if IsChip was wrong?
-		f.Close()
+		be.Close()
 		return nil, err
 	}
 	c := Chip{
+		be:      be,
 		f:       f,
 		Name:    uapi.BytesToString(ci.Name[:]),
 		Label:   uapi.BytesToString(ci.Label[:]),
@@ -309,7 +361,21 @@ func (c *Chip) Close() error {
 	if c.iw != nil {
 		c.iw.close()
 	}
-	return c.f.Close()
+	return c.be.Close()
+}
+
+// Err returns the error that stopped the delivery of line info change events,
+// if any.
+//
+// Returns nil if the line info is not being watched, or if the delivery of
+// events has not been stopped by an error.
+func (c *Chip) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.iw == nil {
+		return nil
+	}
+	return c.iw.Err()
 }
 
 // FindLine returns the offset of the named line, if found on the chip.
@@ -348,7 +414,7 @@ func (c *Chip) LineInfo(offset int) (info LineInfo, err error) {
 		return
 	}
 	var li uapi.LineInfoV2
-	li, err = uapi.GetLineInfoV2(c.f.Fd(), offset)
+	li, err = c.be.LineInfo(offset)
 	if err == nil {
 		info = newLineInfoV2(li)
 	}
@@ -462,7 +528,10 @@ func (c *Chip) RequestLine(offset int, options ...LineReqOption) (*Line, error)
 			chip:    ll.chip,
 			abi:     ll.abi,
 			defCfg:  ll.defCfg,
+			req:     ll.req,
 			watcher: ll.watcher,
+			ec:      ll.ec,
+			tracker: ll.tracker,
 		},
 	}
 	return &l, nil
@@ -470,6 +539,11 @@ func (c *Chip) RequestLine(offset int, options ...LineReqOption) (*Line, error)
 
 // RequestLines requests control of a collection of lines on the chip.
 //
+// The kernel limits a single request to 64 lines, so larger collections are
+// split into several requests of up to 64 lines each.  The Lines behave as a
+// single request, except that operations are only atomic within each split,
+// so setting or reading the values of all the lines is not atomic.
+//
 // If granted, control is maintained until the Lines are closed.
 func (c *Chip) RequestLines(offsets []int, options ...LineReqOption) (*Lines, error) {
 	for _, o := range offsets {
@@ -487,10 +561,49 @@ func (c *Chip) RequestLines(offsets []int, options ...LineReqOption) (*Lines, er
 		consumer: c.options.consumer,
 		abi:      c.options.abi,
 		eh:       c.options.eh,
+		bh:       c.options.bh,
+		errh:     c.options.errh,
+		loop:     c.options.loop,
 	}
 	for _, option := range options {
 		option.applyLineReqOption(&lro)
 	}
+	if lro.eventChan != nil {
+		if err := lro.eventChan.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return c.requestLines(lro)
+}
+
+// requestLines requests the lines described by the options.
+func (c *Chip) requestLines(lro lineReqOptions) (*Lines, error) {
+	max := uapi.LinesMax
+	if lro.abi == 1 {
+		max = uapi.HandlesMax
+	}
+	if len(lro.offsets) > max {
+		return c.requestSplitLines(lro, max)
+	}
+	offsets := lro.offsets
+	var ec *eventChannel
+	if lro.eventChan != nil {
+		ec = newEventChannel(*lro.eventChan)
+		lro.eh = ec.handle
+		lro.errh = ec.errorHandler(lro.errh)
+	}
+	var et *eventTracker
+	if lro.abi == 2 {
+		et = newEventTracker()
+		if lro.eh != nil {
+			lro.bh = lro.eh.batchHandler()
+		}
+		if lro.bh != nil {
+			lro.bh = et.handler(lro.bh, lro.overflowEvents)
+		}
+	} else if lro.bh != nil {
+		lro.eh = lro.bh.eventHandler()
+	}
 	ll := Lines{
 		baseLine: baseLine{
 			offsets: offsets,
@@ -498,11 +611,17 @@ func (c *Chip) RequestLines(offsets []int, options ...LineReqOption) (*Lines, er
 			chip:    c.Name,
 			abi:     lro.abi,
 			defCfg:  lro.defCfg,
+			lineCfg: lro.lineCfg,
+			ec:      ec,
+			tracker: et,
 		},
 	}
 	var err error
 	if ll.abi == 2 {
-		ll.vfd, ll.watcher, err = c.getLine(ll.offsets, lro)
+		ll.req, ll.watcher, err = c.getLine(ll.offsets, lro)
+		if err == nil {
+			ll.vfd = ll.req.Fd()
+		}
 	} else {
 		err = lro.defCfg.v1Validate()
 		if err != nil {
@@ -521,11 +640,124 @@ func (c *Chip) RequestLines(offsets []int, options ...LineReqOption) (*Lines, er
 	return &ll, nil
 }
 
+// requestSplitLines requests lines that are too numerous for a single kernel
+// request, by splitting them into chunks of at most max lines and requesting
+// each chunk separately.
+//
+// Events from the chunks are delivered via a common EventLoop, so they are
+// serialised and can be merged.
+func (c *Chip) requestSplitLines(lro lineReqOptions, max int) (*Lines, error) {
+	ll := Lines{
+		baseLine: baseLine{
+			offsets: lro.offsets,
+			values:  lro.values,
+			chip:    c.Name,
+			abi:     lro.abi,
+			defCfg:  lro.defCfg,
+			lineCfg: lro.lineCfg,
+		},
+	}
+	if lro.eventChan != nil {
+		ll.ec = newEventChannel(*lro.eventChan)
+		lro.eh = ll.ec.handle
+		lro.errh = ll.ec.errorHandler(lro.errh)
+	}
+	if lro.eh != nil {
+		lro.bh = lro.eh.batchHandler()
+	}
+	clro := lro
+	clro.eh = nil
+	clro.eventChan = nil
+	var sw *splitWatcher
+	if lro.bh != nil {
+		sw = &splitWatcher{
+			loop:   lro.loop,
+			merger: &eventMerger{bh: lro.bh},
+		}
+		if sw.loop == nil {
+			loop, err := NewEventLoop()
+			if err != nil {
+				return nil, err
+			}
+			sw.loop = loop
+			sw.private = true
+		}
+		clro.loop = sw.loop
+		clro.bh = sw.merger.handler("")
+	}
+	for start := 0; start < len(lro.offsets); start += max {
+		end := start + max
+		if end > len(lro.offsets) {
+			end = len(lro.offsets)
+		}
+		offsets := lro.offsets[start:end]
+		clro.lineConfigOptions = lro.subset(offsets, offsets)
+		chunk, err := c.requestLines(clro)
+		if err != nil {
+			if ll.ec != nil {
+				ll.ec.stop()
+			}
+			for _, chunk := range ll.chunks {
+				chunk.Close()
+			}
+			if sw != nil {
+				sw.Close()
+			}
+			if ll.ec != nil {
+				ll.ec.close()
+			}
+			return nil, err
+		}
+		ll.isEvent = ll.isEvent || chunk.isEvent
+		ll.chunks = append(ll.chunks, chunk)
+	}
+	if sw != nil {
+		sw.chunks = ll.chunks
+		sw.loop.addFlusher(sw.merger)
+		ll.watcher = sw
+	}
+	return &ll, nil
+}
+
+// RequestLineContext requests control of a single line on the chip, for the
+// lifetime of the context.
+//
+// If granted, control is maintained until the Line is closed or the context is
+// done, whichever comes first.
+func (c *Chip) RequestLineContext(ctx context.Context, offset int, options ...LineReqOption) (*Line, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l, err := c.RequestLine(offset, options...)
+	if err != nil {
+		return nil, err
+	}
+	l.closeWithContext(ctx)
+	return l, nil
+}
+
+// RequestLinesContext requests control of a collection of lines on the chip,
+// for the lifetime of the context.
+//
+// If granted, control is maintained until the Lines are closed or the context
+// is done, whichever comes first.
+func (c *Chip) RequestLinesContext(ctx context.Context, offsets []int, options ...LineReqOption) (*Lines, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ll, err := c.RequestLines(offsets, options...)
+	if err != nil {
+		return nil, err
+	}
+	ll.closeWithContext(ctx)
+	return ll, nil
+}
+
 // creates the iw and ich
 //
 // Assumes c is locked.
 func (c *Chip) createInfoWatcher() error {
-	iw, err := newInfoWatcher(int(c.f.Fd()),
+	iw, err := newInfoWatcher(c.be.InfoFd(),
 		func(lic LineInfoChangeEvent) {
 			c.mu.Lock()
 			ich := c.ich[lic.Info.Offset]
@@ -534,7 +766,9 @@ func (c *Chip) createInfoWatcher() error {
 				ich(lic)
 			}
 		},
-		c.options.abi)
+		c.options.errh,
+		c.options.abi,
+		c.options.loop)
 	if err != nil {
 		return err
 	}
@@ -573,7 +807,7 @@ func (c *Chip) WatchLineInfo(offset int, lich InfoChangeHandler) (info LineInfo,
 		return
 	}
 	li := uapi.LineInfoV2{Offset: uint32(offset)}
-	err = uapi.WatchLineInfoV2(c.f.Fd(), &li)
+	err = c.be.WatchLineInfo(&li)
 	if err != nil {
 		return
 	}
@@ -592,37 +826,38 @@ func (c *Chip) UnwatchLineInfo(offset int) error {
 		return nil
 	}
 	delete(c.ich, offset)
-	return uapi.UnwatchLineInfo(c.f.Fd(), uint32(offset))
+	return c.be.UnwatchLineInfo(offset)
 }
 
-func (c *Chip) getLine(offsets []int, lro lineReqOptions) (uintptr, io.Closer, error) {
+func (c *Chip) getLine(offsets []int, lro lineReqOptions) (LineBackend, io.Closer, error) {
 
 	config, err := lro.toULineConfig()
 	if err != nil {
-		return 0, nil, err
+		return nil, nil, err
 	}
 	lr := uapi.LineRequest{
-		Lines:  uint32(len(offsets)),
-		Config: config,
+		Lines:           uint32(len(offsets)),
+		Config:          config,
+		EventBufferSize: uint32(lro.eventBufferSize),
 	}
 	copy(lr.Consumer[:len(lr.Consumer)-1], lro.consumer)
 	// copy(hr.Offsets[:], offsets) - with cast
 	for i, o := range offsets {
 		lr.Offsets[i] = uint32(o)
 	}
-	err = uapi.GetLine(c.f.Fd(), &lr)
+	req, err := c.be.GetLine(&lr)
 	if err != nil {
-		return 0, nil, err
+		return nil, nil, err
 	}
 	var w io.Closer
-	if lro.eh != nil {
-		w, err = newWatcher(lr.Fd, lro.eh)
+	if lro.bh != nil {
+		w, err = newWatcher(int32(req.Fd()), lro.bh, lro.errh, lro.eventBatchSize(), lro.loop)
 		if err != nil {
-			unix.Close(int(lr.Fd))
-			return 0, nil, err
+			req.Close()
+			return nil, nil, err
 		}
 	}
-	return uintptr(lr.Fd), w, nil
+	return req, w, nil
 }
 
 func (lc LineConfig) toHandleFlags() uapi.HandleFlag {
@@ -752,7 +987,7 @@ func (c *Chip) getEventRequest(offsets []int, lro lineReqOptions) (uintptr, io.C
 		}
 		fds[int(fd)] = o
 	}
-	w, err := newWatcherV1(fds, lro.eh)
+	w, err := newWatcherV1(fds, lro.eh, lro.errh, lro.loop)
 	if err != nil {
 		for fd := range fds {
 			unix.Close(fd)
@@ -793,6 +1028,9 @@ type baseLine struct {
 	isEvent bool
 	chip    string
 	abi     int
+	// the requests for each chunk of the lines, if the lines are too numerous
+	// for a single kernel request.
+	chunks []*Lines
 	// mu covers all that follow - those above are immutable
 	mu      sync.Mutex
 	values  map[int]int
@@ -800,7 +1038,44 @@ type baseLine struct {
 	lineCfg map[int]*LineConfig
 	info    []*LineInfo
 	closed  bool
+	// the requested lines, for uAPI v2.
+	req     LineBackend
 	watcher io.Closer
+	ec      *eventChannel
+	tracker *eventTracker
+	// closed to terminate the context watcher, if any.
+	ctxDone chan struct{}
+	// eventfd to wake synchronous event readers, created on first use.
+	wakefd int
+	// synchronous event readers
+	readers sync.WaitGroup
+}
+
+// isOutput returns true if the line with the offset is configured as an
+// output.
+//
+// The per-line configuration is not applied with uAPI v1, so only the default
+// configuration is considered.
+//
+// Assumes l is locked.
+func (l *baseLine) isOutput(offset int) bool {
+	if lc := l.lineCfg[offset]; lc != nil && l.abi != 1 {
+		return lc.Direction == LineDirectionOutput
+	}
+	return l.defCfg.Direction == LineDirectionOutput
+}
+
+// anyOutput returns true if any of the lines with the offsets are configured as
+// outputs.
+//
+// Assumes l is locked.
+func (l *baseLine) anyOutput(offsets []int) bool {
+	for _, offset := range offsets {
+		if l.isOutput(offset) {
+			return true
+		}
+	}
+	return false
 }
 
 // UapiAbiVersion returns the version of the GPIO uAPI the line is using.
@@ -825,15 +1100,278 @@ func (l *baseLine) Close() error {
 		return ErrClosed
 	}
 	l.closed = true
+	if l.ctxDone != nil {
+		close(l.ctxDone)
+	}
+	if l.wakefd != 0 {
+		unix.Write(l.wakefd, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+		l.readers.Wait()
+		unix.Close(l.wakefd)
+	}
+	if l.ec != nil {
+		l.ec.stop()
+	}
+	for _, chunk := range l.chunks {
+		chunk.Close()
+	}
 	if l.watcher != nil {
 		l.watcher.Close()
 	}
-	if !l.isEvent { // isEvent => v1 => closed by watcher
+	if l.ec != nil {
+		l.ec.close()
+	}
+	if l.req != nil {
+		l.req.Close()
+	} else if !l.isEvent && l.chunks == nil { // isEvent => v1 => closed by watcher
 		unix.Close(int(l.vfd))
 	}
 	return nil
 }
 
+// closeWithContext closes the line(s) when the context is done.
+//
+// Must be called before the line is returned to the caller.
+func (l *baseLine) closeWithContext(ctx context.Context) {
+	if ctx.Done() == nil {
+		return
+	}
+	l.ctxDone = make(chan struct{})
+	go func(done <-chan struct{}) {
+		select {
+		case <-ctx.Done():
+			l.Close()
+		case <-done:
+		}
+	}(l.ctxDone)
+}
+
+// readEvents reads edge events directly from the line request.
+//
+// Blocks until at least one event is available, the context is done, or the
+// line is closed.
+func (l *baseLine) readEvents(ctx context.Context, buf []LineEvent) (int, error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	ll, err := l.startReading()
+	if err != nil {
+		return 0, err
+	}
+	defer stopReading(ll)
+	n, err := readLineEvents(ctx, ll, buf, false)
+	if len(ll) > 1 {
+		sortEvents(buf[:n])
+	}
+	return n, err
+}
+
+// startReading prepares the line request(s) to be read synchronously, and
+// registers the caller as a reader.
+//
+// Returns the requests to be read, which are the chunks if the lines are
+// split.  The caller must call stopReading with those requests once it has
+// finished reading.
+func (l *baseLine) startReading() ([]*baseLine, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, ErrClosed
+	}
+	if l.abi == 1 {
+		return nil, ErrUapiIncompatibility{"synchronous events", 1}
+	}
+	if l.watcher != nil {
+		return nil, ErrAsyncEvents
+	}
+	if l.chunks != nil {
+		var ll []*baseLine
+		for _, chunk := range l.chunks {
+			cll, err := chunk.startReading()
+			if err != nil {
+				stopReading(ll)
+				return nil, err
+			}
+			ll = append(ll, cll...)
+		}
+		return ll, nil
+	}
+	if l.wakefd == 0 {
+		fd, err := unix.Eventfd(0, unix.EFD_CLOEXEC)
+		if err != nil {
+			return nil, err
+		}
+		// concurrent readers may race to read the request, so the loser
+		// must not block in the read.
+		if err = unix.SetNonblock(int(l.vfd), true); err != nil {
+			unix.Close(fd)
+			return nil, err
+		}
+		l.wakefd = fd
+	}
+	l.readers.Add(1)
+	return []*baseLine{l}, nil
+}
+
+// stopReading deregisters the caller as a reader of the requests.
+func stopReading(ll []*baseLine) {
+	for _, l := range ll {
+		l.readers.Done()
+	}
+}
+
+// readLineEvents reads edge events directly from a set of line requests.
+//
+// Blocks until at least one event is available, the context is done, or any
+// of the lines is closed.  Events are read from each of the requests with
+// events available, in order, until buf is full.
+//
+// If setChip is set then the Chip field of the events is set to the chip of
+// the request the event was read from.
+//
+// All the requests must have been prepared using startReading.
+func readLineEvents(ctx context.Context, ll []*baseLine, buf []LineEvent, setChip bool) (int, error) {
+	pfds := make([]unix.PollFd, 0, 2*len(ll)+1)
+	for _, l := range ll {
+		pfds = append(pfds, unix.PollFd{Fd: int32(l.vfd), Events: unix.POLLIN})
+	}
+	for _, l := range ll {
+		pfds = append(pfds, unix.PollFd{Fd: int32(l.wakefd), Events: unix.POLLIN})
+	}
+	if ctx.Done() != nil {
+		ctxfd, err := unix.Eventfd(0, unix.EFD_CLOEXEC)
+		if err != nil {
+			return 0, err
+		}
+		done := make(chan struct{})
+		exited := make(chan struct{})
+		go func() {
+			defer close(exited)
+			select {
+			case <-ctx.Done():
+				unix.Write(ctxfd, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+			case <-done:
+			}
+		}()
+		defer func() {
+			close(done)
+			<-exited
+			unix.Close(ctxfd)
+		}()
+		pfds = append(pfds, unix.PollFd{Fd: int32(ctxfd), Events: unix.POLLIN})
+	}
+	ubuf := make([]uapi.LineEvent, len(buf))
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		_, err := unix.Poll(pfds, -1)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return 0, err
+		}
+		for _, pfd := range pfds[len(ll) : 2*len(ll)] {
+			if pfd.Revents != 0 {
+				return 0, ErrClosed
+			}
+		}
+		n := 0
+		for i, l := range ll {
+			if pfds[i].Revents == 0 || n == len(buf) {
+				continue
+			}
+			m, err := uapi.ReadLineEvents(l.vfd, ubuf[:len(buf)-n])
+			if err == unix.EAGAIN {
+				continue
+			}
+			if err == nil && m == 0 {
+				// the kernel only returns 0 if the request has been removed.
+				err = unix.ENODEV
+			}
+			if err != nil {
+				if n != 0 {
+					// return the events already read - the error recurs
+					// on the next read.
+					return n, nil
+				}
+				return 0, err
+			}
+			for j := 0; j < m; j++ {
+				evt := newLineEvent(ubuf[j])
+				if setChip {
+					evt.Chip = l.chip
+				}
+				l.tracker.update(evt)
+				buf[n+j] = evt
+			}
+			n += m
+		}
+		if n != 0 {
+			return n, nil
+		}
+	}
+}
+
+// Err returns the error that stopped the delivery of edge events to the event
+// handler or event channel, if any.
+//
+// An error of ENODEV indicates the chip has been removed.
+//
+// Returns nil if events are not being delivered asynchronously, or if the
+// delivery of events has not been stopped by an error.
+func (l *baseLine) Err() error {
+	if w, ok := l.watcher.(interface{ Err() error }); ok {
+		return w.Err()
+	}
+	return nil
+}
+
+// LostEvents returns the number of edge events lost by the kernel for the line
+// request.
+//
+// Events are lost when the kernel event buffer overflows, as events are not
+// being read from the kernel fast enough.  Lost events are detected from gaps
+// in the sequence numbers of the events subsequently read, so may not be
+// reported until the next event is read.
+//
+// Requires uAPI v2 - always returns 0 for uAPI v1.
+func (l *baseLine) LostEvents() uint64 {
+	if l.chunks != nil {
+		var lost uint64
+		for _, chunk := range l.chunks {
+			lost += chunk.LostEvents()
+		}
+		return lost
+	}
+	if l.tracker == nil {
+		return 0
+	}
+	return l.tracker.lostEvents()
+}
+
+// Events returns the channel that line events are delivered to.
+//
+// Returns nil unless the line(s) were requested with the WithEventChannel
+// option.
+//
+// The channel is closed when the line(s) are closed, or when the delivery of
+// events is stopped by an error, so Err should be checked once the channel is
+// closed:
+//
+//	for evt := range l.Events() {
+//		...
+//	}
+//	if err := l.Err(); err != nil {
+//		...
+//	}
+func (l *baseLine) Events() <-chan LineEvent {
+	if l.ec == nil {
+		return nil
+	}
+	return l.ec.ch
+}
+
 // Reconfigure updates the configuration of the requested line(s).
 //
 // Configuration for options other than those passed in remain unchanged.
@@ -864,29 +1402,49 @@ func (l *baseLine) Reconfigure(options ...LineConfigOption) error {
 	for _, option := range options {
 		option.applyLineConfigOption(&lro.lineConfigOptions)
 	}
+	return l.reconfigure(lro.lineConfigOptions)
+}
+
+// reconfigure applies the configuration to the requested lines.
+//
+// Assumes l is locked.
+func (l *baseLine) reconfigure(lco lineConfigOptions) error {
+	if l.chunks != nil {
+		for _, chunk := range l.chunks {
+			chunk.mu.Lock()
+			err := chunk.reconfigure(lco.subset(chunk.offsets, chunk.offsets))
+			chunk.mu.Unlock()
+			if err != nil {
+				return err
+			}
+		}
+		l.defCfg = lco.defCfg
+		l.lineCfg = lco.lineCfg
+		return nil
+	}
 	if l.abi == 1 {
-		err := lro.defCfg.v1Validate()
+		err := lco.defCfg.v1Validate()
 		if err != nil {
 			return err
 		}
-		hc := uapi.HandleConfig{Flags: lro.defCfg.toHandleFlags()}
-		for idx, offset := range lro.offsets {
-			hc.DefaultValues[idx] = uint8(lro.values[offset])
+		hc := uapi.HandleConfig{Flags: lco.defCfg.toHandleFlags()}
+		for idx, offset := range lco.offsets {
+			hc.DefaultValues[idx] = uint8(lco.values[offset])
 		}
 		err = uapi.SetLineConfig(l.vfd, &hc)
 		if err == nil {
-			l.defCfg = lro.defCfg
+			l.defCfg = lco.defCfg
 		}
 		return err
 	}
-	config, err := lro.toULineConfig()
+	config, err := lco.toULineConfig()
 	if err != nil {
 		return err
 	}
-	err = uapi.SetLineConfigV2(l.vfd, &config)
+	err = l.req.SetConfig(&config)
 	if err == nil {
-		l.defCfg = lro.defCfg
-		l.lineCfg = lro.lineCfg
+		l.defCfg = lco.defCfg
+		l.lineCfg = lco.lineCfg
 	}
 	return err
 }
@@ -942,7 +1500,7 @@ func (l *Line) Value() (int, error) {
 		return int(hd[0]), err
 	}
 	lv := uapi.LineValues{Mask: 1}
-	err := uapi.GetLineValuesV2(l.vfd, &lv)
+	err := l.req.Values(&lv)
 	return lv.Get(0), err
 }
 
@@ -954,7 +1512,7 @@ func (l *Line) Value() (int, error) {
 func (l *Line) SetValue(value int) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.defCfg.Direction != LineDirectionOutput {
+	if !l.isOutput(l.offsets[0]) {
 		return ErrPermissionDenied
 	}
 	if l.closed {
@@ -973,13 +1531,29 @@ func (l *Line) SetValue(value int) error {
 		Mask: 1,
 		Bits: uapi.NewLineBitmap(value),
 	}
-	err := uapi.SetLineValuesV2(l.vfd, lsv)
+	err := l.req.SetValues(lsv)
 	if err == nil {
 		l.values[l.offsets[0]] = value
 	}
 	return err
 }
 
+// WaitEvent waits for and returns the next edge event on the line.
+//
+// The event is read directly from the line request, so the line must have been
+// requested with edge detection enabled, and without an event handler or event
+// channel.
+//
+// Blocks until an event is available, the context is done, or the line is
+// closed.
+//
+// Requires uAPI v2.
+func (l *Line) WaitEvent(ctx context.Context) (LineEvent, error) {
+	var buf [1]LineEvent
+	_, err := l.readEvents(ctx, buf[:])
+	return buf[0], err
+}
+
 // Lines represents a collection of requested lines.
 type Lines struct {
 	baseLine
@@ -1033,6 +1607,23 @@ func (l *Lines) Values(values []int) error {
 	if lines > len(l.offsets) {
 		lines = len(l.offsets)
 	}
+	if l.chunks != nil {
+		start := 0
+		for _, chunk := range l.chunks {
+			if start >= lines {
+				break
+			}
+			end := start + len(chunk.offsets)
+			if end > lines {
+				end = lines
+			}
+			if err := chunk.Values(values[start:end]); err != nil {
+				return err
+			}
+			start = end
+		}
+		return nil
+	}
 	if l.abi == 1 {
 		hd := uapi.HandleData{}
 		err := uapi.GetLineValues(l.vfd, &hd)
@@ -1045,7 +1636,7 @@ func (l *Lines) Values(values []int) error {
 		return nil
 	}
 	lv := uapi.LineValues{Mask: uapi.NewLineBitMask(lines)}
-	err := uapi.GetLineValuesV2(l.vfd, &lv)
+	err := l.req.Values(&lv)
 	if err != nil {
 		return err
 	}
@@ -1057,7 +1648,14 @@ func (l *Lines) Values(values []int) error {
 
 // SetValues sets the current active state of the collection of lines.
 //
-// Only valid for output lines.
+// Only valid for output lines.  A request may mix input and output lines,
+// using WithLines, in which case the values corresponding to the input lines
+// are ignored and those lines are left unchanged.  Returns ErrPermissionDenied
+// if none of the lines are outputs.
+//
+// With uAPI v1 the per-line configuration is not applied, so all lines have
+// the direction of the request as a whole, and either all or none of the lines
+// are outputs.
 //
 // Values are 0 for inactive and 1 for active.
 //
@@ -1067,7 +1665,7 @@ func (l *Lines) Values(values []int) error {
 func (l *Lines) SetValues(values []int) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.defCfg.Direction != LineDirectionOutput {
+	if !l.anyOutput(l.offsets) {
 		return ErrPermissionDenied
 	}
 	if l.closed {
@@ -1076,6 +1674,38 @@ func (l *Lines) SetValues(values []int) error {
 	if len(values) > len(l.offsets) {
 		values = values[:len(l.offsets)]
 	}
+	if l.chunks != nil {
+		start := 0
+		for _, chunk := range l.chunks {
+			end := start + len(chunk.offsets)
+			var cv []int
+			if start < len(values) {
+				cv = values[start:]
+				if end < len(values) {
+					cv = values[start:end]
+				}
+			}
+			start = end
+			if !l.anyOutput(chunk.offsets) {
+				// the chunk is all inputs, so leave it unchanged.
+				continue
+			}
+			if err := chunk.SetValues(cv); err != nil {
+				return err
+			}
+			for i, offset := range chunk.offsets {
+				if !l.isOutput(offset) {
+					continue
+				}
+				v := 0
+				if i < len(cv) {
+					v = cv[i]
+				}
+				l.values[offset] = v
+			}
+		}
+		return nil
+	}
 	if l.abi == 1 {
 		hd := uapi.HandleData{}
 		for i, v := range values {
@@ -1090,19 +1720,51 @@ func (l *Lines) SetValues(values []int) error {
 		return err
 	}
 	lv := uapi.LineValues{
-		Mask: uapi.NewLineBitMask(len(l.offsets)),
+		Mask: l.outputMask(),
 		Bits: uapi.NewLineBitmap(values...),
 	}
-	err := uapi.SetLineValuesV2(l.vfd, lv)
+	err := l.req.SetValues(lv)
 	if err == nil {
 		for i, v := range values {
-			l.values[l.offsets[i]] = v
+			if lv.Mask.Get(i) != 0 {
+				l.values[l.offsets[i]] = v
+			}
 		}
 	}
 
 	return err
 }
 
+// outputMask returns the mask of the lines that are outputs.
+//
+// Input lines are excluded, as the kernel rejects attempts to set them.
+//
+// Assumes l is locked.
+func (l *Lines) outputMask() uapi.LineBitmap {
+	mask := uapi.LineBitmap(0)
+	for i, offset := range l.offsets {
+		if l.isOutput(offset) {
+			mask = mask.Set(i, 1)
+		}
+	}
+	return mask
+}
+
+// ReadEvents reads edge events on the lines into buf.
+//
+// The events are read directly from the line request, so the lines must have
+// been requested with edge detection enabled, and without an event handler or
+// event channel.
+//
+// Blocks until at least one event is available, the context is done, or the
+// lines are closed.  Returns the number of events read, which may be fewer
+// than the length of buf.
+//
+// Requires uAPI v2.
+func (l *Lines) ReadEvents(ctx context.Context, buf []LineEvent) (int, error) {
+	return l.readEvents(ctx, buf)
+}
+
 // LineEventType indicates the type of change to the line active state.
 //
 // Note that for active low lines a low line level results in a high active
@@ -1116,6 +1778,16 @@ const (
 
 	// LineEventFallingEdge indicates an active to inactive event.
 	LineEventFallingEdge
+
+	// LineEventOverflow indicates that events on the line have been lost as
+	// the kernel event buffer overflowed.
+	//
+	// The number of events lost is indicated by the Lost field. The sequence
+	// numbers are those of the event that revealed the loss, which
+	// immediately follows the overflow event.
+	//
+	// Only generated for requests with the WithOverflowEvents option.
+	LineEventOverflow
 )
 
 // LineEvent represents a change in the state of a line.
@@ -1143,6 +1815,27 @@ type LineEvent struct {
 	//
 	// Requires uAPI v2.
 	LineSeqno uint32
+
+	// The number of events lost on the line.
+	//
+	// Only set for LineEventOverflow events.
+	Lost uint32
+
+	// The name of the chip containing the line.
+	//
+	// Only set for events from MultiLines, where the Offset alone does not
+	// identify the line.
+	Chip string
+}
+
+func newLineEvent(evt uapi.LineEvent) LineEvent {
+	return LineEvent{
+		Offset:    int(evt.Offset),
+		Timestamp: time.Duration(evt.Timestamp),
+		Type:      LineEventType(evt.ID),
+		Seqno:     evt.Seqno,
+		LineSeqno: evt.LineSeqno,
+	}
 }
 
 // LineInfoChangeEvent represents a change in the info a line.
@@ -1200,10 +1893,14 @@ func FindLine(name string) (chip string, offset int, err error) {
 	return "", 0, ErrNotFound
 }
 
-// IsChip checks if the named device is an accessible GPIO character device.
+// IsChip checks if the named device is an accessible GPIO character device,
+// or a chip registered using RegisterBackend.
 //
 // Returns an error if not.
 func IsChip(name string) error {
+	if registeredBackend(name) != nil {
+		return nil
+	}
 	path := nameToPath(name)
 	fi, err := os.Lstat(path)
 	if err != nil {
@@ -1247,6 +1944,11 @@ func nameToPath(name string) string {
 }
 
 var (
+	// ErrAsyncEvents indicates the events for the line are being delivered
+	// asynchronously, to an event handler or event channel, so cannot be read
+	// directly.
+	ErrAsyncEvents = errors.New("events are delivered asynchronously")
+
 	// ErrClosed indicates the chip or line has already been closed.
 	ErrClosed = errors.New("already closed")
 
@@ -5,12 +5,17 @@
 package gpiocdev_test
 
 import (
+	"context"
+	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/uapi"
 	"github.com/warthog618/go-gpiosim"
+	"golang.org/x/sys/unix"
 )
 
 func BenchmarkChipNewClose(b *testing.B) {
@@ -153,3 +158,150 @@ func BenchmarkInterruptLatency(b *testing.B) {
 	}
 	r.Close()
 }
+
+func BenchmarkEventThroughput(b *testing.B) {
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(b, err)
+	defer s.Close()
+	c, err := gpiocdev.NewChip(s.DevPath())
+	require.Nil(b, err)
+	require.NotNil(b, c)
+	defer c.Close()
+	offset := 2
+	burst := 64
+
+	// measures the time to deliver a burst of events that is already queued in
+	// the kernel, so the delivery rate is not limited by the event source.
+	// The handlers are held off by the gate while the burst is queued.
+	var gate sync.Mutex
+	measure := func(b *testing.B, ich <-chan int) {
+		var elapsed time.Duration
+		for i := 0; i < b.N; i++ {
+			gate.Lock()
+			for j := 0; j < burst; j++ {
+				s.SetPull(offset, (j+1)&1)
+			}
+			time.Sleep(time.Millisecond)
+			start := time.Now()
+			gate.Unlock()
+			for n := 0; n < burst; {
+				n += <-ich
+			}
+			elapsed += time.Since(start)
+		}
+		b.ReportMetric(float64(b.N*burst)/elapsed.Seconds(), "events/s")
+	}
+	run := func(b *testing.B, opt gpiocdev.LineReqOption, ich <-chan int) {
+		s.SetPull(offset, 0)
+		r, err := c.RequestLine(offset,
+			gpiocdev.WithBothEdges,
+			gpiocdev.WithEventBufferSize(burst*2),
+			opt)
+		require.Nil(b, err)
+		require.NotNil(b, r)
+		defer r.Close()
+		measure(b, ich)
+	}
+	// the baseline, reading one event per read as the watcher did prior to
+	// batching.
+	b.Run("handler_single", func(b *testing.B) {
+		s.SetPull(offset, 0)
+		lr := uapi.LineRequest{
+			Lines:           1,
+			EventBufferSize: uint32(burst * 2),
+			Config: uapi.LineConfig{
+				Flags: uapi.LineFlagV2Input | uapi.LineFlagV2EdgeRising | uapi.LineFlagV2EdgeFalling,
+			},
+		}
+		lr.Offsets[0] = uint32(offset)
+		f, err := os.Open(s.DevPath())
+		require.Nil(b, err)
+		defer f.Close()
+		err = uapi.GetLine(f.Fd(), &lr)
+		require.Nil(b, err)
+		donefd, err := unix.Eventfd(0, unix.EFD_CLOEXEC)
+		require.Nil(b, err)
+		defer unix.Close(donefd)
+		ich := make(chan int, burst)
+		doneCh := make(chan struct{})
+		go func() {
+			defer close(doneCh)
+			pfds := []unix.PollFd{
+				{Fd: lr.Fd, Events: unix.POLLIN},
+				{Fd: int32(donefd), Events: unix.POLLIN},
+			}
+			for {
+				if _, err := unix.Poll(pfds, -1); err != nil && err != unix.EINTR {
+					return
+				}
+				if pfds[1].Revents != 0 {
+					return
+				}
+				if pfds[0].Revents == 0 {
+					continue
+				}
+				if _, err := uapi.ReadLineEvent(uintptr(lr.Fd)); err != nil {
+					return
+				}
+				gate.Lock()
+				gate.Unlock()
+				ich <- 1
+			}
+		}()
+		measure(b, ich)
+		unix.Write(donefd, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+		<-doneCh
+		unix.Close(int(lr.Fd))
+	})
+	b.Run("handler", func(b *testing.B) {
+		ich := make(chan int, burst)
+		run(b, gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {
+			gate.Lock()
+			gate.Unlock()
+			ich <- 1
+		}), ich)
+	})
+	b.Run("batch", func(b *testing.B) {
+		ich := make(chan int, burst)
+		run(b, gpiocdev.WithEventBatchHandler(func(evts []gpiocdev.LineEvent) {
+			gate.Lock()
+			gate.Unlock()
+			ich <- len(evts)
+		}), ich)
+	})
+
+	// as above, but reading the events synchronously, either one event per
+	// read or as many as are available.
+	runSync := func(b *testing.B, bufSize int) {
+		s.SetPull(offset, 0)
+		r, err := c.RequestLines([]int{offset},
+			gpiocdev.WithBothEdges,
+			gpiocdev.WithEventBufferSize(burst*2))
+		require.Nil(b, err)
+		require.NotNil(b, r)
+		defer r.Close()
+		buf := make([]gpiocdev.LineEvent, bufSize)
+		ctx := context.Background()
+		var elapsed time.Duration
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < burst; j++ {
+				s.SetPull(offset, (j+1)&1)
+			}
+			time.Sleep(time.Millisecond)
+			start := time.Now()
+			for n := 0; n < burst; {
+				m, err := r.ReadEvents(ctx, buf)
+				require.Nil(b, err)
+				n += m
+			}
+			elapsed += time.Since(start)
+		}
+		b.ReportMetric(float64(b.N*burst)/elapsed.Seconds(), "events/s")
+	}
+	b.Run("read_single", func(b *testing.B) {
+		runSync(b, 1)
+	})
+	b.Run("read_batch", func(b *testing.B) {
+		runSync(b, burst)
+	})
+}
@@ -5,6 +5,7 @@
 package gpiocdev_test
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -400,6 +401,79 @@ func TestChipRequestLines(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestChipRequestLineContext(t *testing.T) {
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(t, err)
+	defer s.Close()
+
+	c := getChip(t, s.DevPath())
+	defer c.Close()
+
+	offset := 3
+
+	// already done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l, err := c.RequestLineContext(ctx, offset)
+	assert.Equal(t, context.Canceled, err)
+	require.Nil(t, l)
+
+	// released by cancel
+	ctx, cancel = context.WithCancel(context.Background())
+	l, err = c.RequestLineContext(ctx, offset)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	inf, err := c.LineInfo(offset)
+	assert.Nil(t, err)
+	assert.True(t, inf.Used)
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	inf, err = c.LineInfo(offset)
+	assert.Nil(t, err)
+	assert.False(t, inf.Used)
+	err = l.Close()
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+
+	// closed before cancel
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	l, err = c.RequestLineContext(ctx, offset)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	err = l.Close()
+	assert.Nil(t, err)
+}
+
+func TestChipRequestLinesContext(t *testing.T) {
+	offsets := []int{4, 2}
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(t, err)
+	defer s.Close()
+
+	c := getChip(t, s.DevPath())
+	defer c.Close()
+
+	// invalid offset
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ll, err := c.RequestLinesContext(ctx, append(offsets, -1))
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+	require.Nil(t, ll)
+
+	// released by timeout
+	ll, err = c.RequestLinesContext(ctx, offsets, gpiocdev.AsOutput())
+	assert.Nil(t, err)
+	require.NotNil(t, ll)
+	<-ctx.Done()
+	time.Sleep(20 * time.Millisecond)
+	err = ll.SetValues([]int{1, 1})
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+	ll2, err := c.RequestLines(offsets)
+	assert.Nil(t, err)
+	require.NotNil(t, ll2)
+	ll2.Close()
+}
+
 func TestChipWatchLineInfo(t *testing.T) {
 	requireKernel(t, infoWatchKernel)
 
@@ -806,6 +880,59 @@ func TestLineValue(t *testing.T) {
 	assert.Equal(t, gpiocdev.ErrClosed, err)
 }
 
+func TestLineWaitEvent(t *testing.T) {
+	requireKernel(t, uapiV2Kernel)
+	offset := 2
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(t, err)
+	defer s.Close()
+	s.SetPull(offset, 0)
+	c := getChip(t, s.DevPath())
+	defer c.Close()
+	requireABI(t, c, 2)
+
+	// with event handler
+	l, err := c.RequestLine(offset,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(func(gpiocdev.LineEvent) {}))
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	_, err = l.WaitEvent(context.Background())
+	assert.Equal(t, gpiocdev.ErrAsyncEvents, err)
+	l.Close()
+
+	l, err = c.RequestLine(offset, gpiocdev.WithBothEdges)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+
+	// timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.WaitEvent(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// event
+	s.SetPull(offset, 1)
+	evt, err := l.WaitEvent(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, offset, evt.Offset)
+	assert.Equal(t, gpiocdev.LineEventRisingEdge, evt.Type)
+	assert.Equal(t, uint32(1), evt.Seqno)
+	assert.Equal(t, uint32(1), evt.LineSeqno)
+
+	// released by close
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		l.Close()
+	}()
+	_, err = l.WaitEvent(context.Background())
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+
+	// after close
+	_, err = l.WaitEvent(context.Background())
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+}
+
 func TestLineSetValue(t *testing.T) {
 	offset := 0
 	s, err := gpiosim.NewSimpleton(6)
@@ -969,6 +1096,56 @@ func TestLinesValues(t *testing.T) {
 	l.Close()
 }
 
+func TestLinesReadEvents(t *testing.T) {
+	requireKernel(t, uapiV2Kernel)
+	offsets := []int{1, 3}
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(t, err)
+	defer s.Close()
+	c := getChip(t, s.DevPath())
+	defer c.Close()
+	requireABI(t, c, 2)
+
+	l, err := c.RequestLines(offsets, gpiocdev.WithBothEdges)
+	assert.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+
+	// empty buffer
+	n, err := l.ReadEvents(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Zero(t, n)
+
+	s.SetPull(offsets[0], 1)
+	s.SetPull(offsets[1], 1)
+	s.SetPull(offsets[0], 0)
+	time.Sleep(20 * time.Millisecond)
+
+	buf := make([]gpiocdev.LineEvent, 5)
+	n, err = l.ReadEvents(context.Background(), buf)
+	assert.Nil(t, err)
+	require.Equal(t, 3, n)
+	assert.Equal(t, offsets[0], buf[0].Offset)
+	assert.Equal(t, gpiocdev.LineEventRisingEdge, buf[0].Type)
+	assert.Equal(t, offsets[1], buf[1].Offset)
+	assert.Equal(t, gpiocdev.LineEventRisingEdge, buf[1].Type)
+	assert.Equal(t, offsets[0], buf[2].Offset)
+	assert.Equal(t, gpiocdev.LineEventFallingEdge, buf[2].Type)
+	for i := 0; i < n; i++ {
+		assert.Equal(t, uint32(i+1), buf[i].Seqno)
+	}
+
+	// cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	n, err = l.ReadEvents(ctx, buf)
+	assert.Equal(t, context.Canceled, err)
+	assert.Zero(t, n)
+}
+
 func checkLevels(t *testing.T, s *gpiosim.Simpleton, offsets, values []int) {
 	for i, o := range offsets {
 		v, err := s.Level(o)
@@ -1017,6 +1194,67 @@ func TestLinesSetValues(t *testing.T) {
 	assert.Equal(t, gpiocdev.ErrClosed, err)
 }
 
+func TestLinesSetValuesMixed(t *testing.T) {
+	offsets := []int{2, 3, 1}
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(t, err)
+	defer s.Close()
+	c := getChip(t, s.DevPath())
+	defer c.Close()
+
+	// line 3 is an input, pulled up
+	s.SetPull(3, 1)
+	l, err := c.RequestLines(offsets,
+		gpiocdev.AsOutput(0, 0, 0),
+		gpiocdev.WithLines([]int{3}, gpiocdev.AsInput))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	checkLevels(t, s, offsets, []int{0, 1, 0})
+
+	// the outputs are set and the input is left unchanged
+	err = l.SetValues([]int{1, 0, 1})
+	assert.Nil(t, err)
+	checkLevels(t, s, offsets, []int{1, 1, 1})
+	s.SetPull(3, 0)
+	err = l.SetValues([]int{0, 1, 1})
+	assert.Nil(t, err)
+	checkLevels(t, s, offsets, []int{0, 0, 1})
+	values := make([]int, len(offsets))
+	err = l.Values(values)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 0, 1}, values)
+
+	// reconfigured as an output
+	err = l.Reconfigure(gpiocdev.WithLines([]int{3}, gpiocdev.AsOutput(1)))
+	assert.Nil(t, err)
+	checkLevels(t, s, offsets, []int{0, 1, 1})
+	err = l.SetValues([]int{1, 0, 0})
+	assert.Nil(t, err)
+	checkLevels(t, s, offsets, []int{1, 0, 0})
+	l.Close()
+
+	// inputs by default, with outputs set by WithLines
+	s.SetPull(2, 1)
+	s.SetPull(3, 0)
+	l, err = c.RequestLines(offsets,
+		gpiocdev.AsInput,
+		gpiocdev.WithLines([]int{3, 1}, gpiocdev.AsOutput(1, 0)))
+	require.Nil(t, err)
+	require.NotNil(t, l)
+	defer l.Close()
+	checkLevels(t, s, offsets, []int{1, 1, 0})
+	err = l.SetValues([]int{0, 0, 1})
+	assert.Nil(t, err)
+	checkLevels(t, s, offsets, []int{1, 0, 1})
+
+	// all inputs
+	err = l.Reconfigure(gpiocdev.WithLines([]int{3, 1}, gpiocdev.AsInput))
+	assert.Nil(t, err)
+	err = l.SetValues([]int{0, 0, 1})
+	assert.Equal(t, gpiocdev.ErrPermissionDenied, err)
+}
+
 func naturalLess(lhs, rhs string) bool {
 	llhs := len(lhs)
 	lrhs := len(rhs)
@@ -0,0 +1,467 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package i2c provides a bit-banged I2C master using two GPIO lines.
+//
+// The SCL and SDA lines are requested as open-drain outputs with pull-up
+// bias, so the master and targets can both pull the lines low, and the lines
+// are read back to detect acknowledgements and clock stretching.
+//
+// The timing targets standard mode (up to 100kHz).  As the master is clocked
+// from user space the actual clock rate is typically lower, and the clock may
+// be paused at any point, which I2C permits.
+//
+//	m, _ := i2c.New("gpiochip0", 3, 2)
+//	defer m.Close()
+//	// read two bytes from register 0x05 of the target at 0x48
+//	r := make([]byte, 2)
+//	err := m.Tx(0x48, []byte{0x05}, r)
+package i2c
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+	"golang.org/x/sys/unix"
+)
+
+// Bus is the interface used by device drivers to perform transactions on an
+// I2C bus.
+type Bus interface {
+	// Tx writes w to, then reads len(r) bytes into r from, the target at
+	// addr, within a single transaction.
+	Tx(addr uint16, w, r []byte) error
+}
+
+// TenBit flags an address as a 10-bit address.
+//
+// Addresses without the flag are 7-bit addresses.
+const TenBit uint16 = 0x8000
+
+var (
+	// ErrAddressNack indicates no target acknowledged the address.
+	ErrAddressNack = errors.New("address not acknowledged")
+
+	// ErrDataNack indicates the target did not acknowledge a written byte.
+	ErrDataNack = errors.New("data not acknowledged")
+
+	// ErrClockStretch indicates a target held SCL low for longer than the
+	// stretch timeout.
+	ErrClockStretch = errors.New("clock stretch timeout")
+
+	// ErrBusBusy indicates SDA is held low, and could not be released by
+	// clocking the bus.
+	ErrBusBusy = errors.New("bus busy")
+)
+
+// Master is a bit-banged I2C bus master.
+type Master struct {
+	scl *gpiocdev.Line
+	sda *gpiocdev.Line
+
+	// half the period of SCL.
+	half time.Duration
+
+	// the maximum time a target may hold SCL low.
+	stretchTimeout time.Duration
+
+	// mutex serialises transactions.
+	mu sync.Mutex
+
+	closed bool
+}
+
+var _ Bus = (*Master)(nil)
+
+// Option defines the interface required to provide an option to New.
+type Option interface {
+	applyOption(*masterOptions)
+}
+
+type masterOptions struct {
+	frequency      int
+	stretchTimeout time.Duration
+	reqOpts        []gpiocdev.LineReqOption
+}
+
+// FrequencyOption sets the SCL frequency.
+type FrequencyOption int
+
+// WithFrequency sets the target SCL frequency, in Hz, up to 100kHz.
+//
+// This determines the minimum time for each half of the SCL period, so the
+// actual frequency will be lower.
+//
+// The default is 100kHz.
+func WithFrequency(hz int) FrequencyOption {
+	return FrequencyOption(hz)
+}
+
+func (o FrequencyOption) applyOption(opts *masterOptions) {
+	opts.frequency = int(o)
+}
+
+// StretchTimeoutOption sets the clock stretch timeout.
+type StretchTimeoutOption time.Duration
+
+// WithStretchTimeout sets the maximum time a target may stretch the clock by
+// holding SCL low.
+//
+// The default is 25ms.
+func WithStretchTimeout(period time.Duration) StretchTimeoutOption {
+	return StretchTimeoutOption(period)
+}
+
+func (o StretchTimeoutOption) applyOption(opts *masterOptions) {
+	opts.stretchTimeout = time.Duration(o)
+}
+
+// RequestOption provides options for the line requests.
+type RequestOption []gpiocdev.LineReqOption
+
+// WithRequestOptions provides options for the requests of the SCL and SDA
+// lines, such as consumer.
+//
+// Direction, drive, bias and active level options are overridden by the
+// master.
+func WithRequestOptions(options ...gpiocdev.LineReqOption) RequestOption {
+	return RequestOption(options)
+}
+
+func (o RequestOption) applyOption(opts *masterOptions) {
+	opts.reqOpts = append(opts.reqOpts, o...)
+}
+
+// New requests the SCL and SDA lines from the chip and creates a master for
+// the bus.
+//
+// If a target is holding SDA low, such as after an interrupted transaction,
+// the bus is clocked to release it.
+//
+// Returns unix.EINVAL if the frequency or stretch timeout is invalid.
+func New(chip string, scl, sda int, options ...Option) (*Master, error) {
+	opts := masterOptions{
+		frequency:      100000,
+		stretchTimeout: 25 * time.Millisecond,
+	}
+	for _, option := range options {
+		option.applyOption(&opts)
+	}
+	if opts.frequency <= 0 || opts.frequency > 100000 || opts.stretchTimeout <= 0 {
+		return nil, unix.EINVAL
+	}
+	reqOpts := append([]gpiocdev.LineReqOption{}, opts.reqOpts...)
+	reqOpts = append(reqOpts,
+		gpiocdev.AsActiveHigh,
+		gpiocdev.AsOpenDrain,
+		gpiocdev.WithPullUp,
+		gpiocdev.AsOutput(1))
+	m := &Master{
+		half:           time.Second / time.Duration(2*opts.frequency),
+		stretchTimeout: opts.stretchTimeout,
+	}
+	var err error
+	m.scl, err = gpiocdev.RequestLine(chip, scl, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	m.sda, err = gpiocdev.RequestLine(chip, sda, reqOpts...)
+	if err != nil {
+		m.scl.Close()
+		return nil, err
+	}
+	if err = m.recover(); err != nil {
+		m.sda.Close()
+		m.scl.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Close releases the SCL and SDA lines.
+func (m *Master) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return gpiocdev.ErrClosed
+	}
+	m.closed = true
+	err := m.sda.Close()
+	if serr := m.scl.Close(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Tx writes w to, then reads len(r) bytes into r from, the target at addr,
+// within a single transaction.
+//
+// If both w and r are provided then the read follows a repeated start.
+// If both are empty then only the address is sent, which probes for the
+// presence of the target.
+//
+// The addr is a 7-bit address, or a 10-bit address if flagged with TenBit.
+// Returns unix.EINVAL if the address is out of range.
+func (m *Master) Tx(addr uint16, w, r []byte) error {
+	if addr&TenBit != 0 {
+		if addr&^TenBit > 0x3ff {
+			return unix.EINVAL
+		}
+	} else if addr > 0x7f {
+		return unix.EINVAL
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return gpiocdev.ErrClosed
+	}
+	err := m.tx(addr, w, r)
+	if serr := m.stop(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// tx performs the transaction, up to but excluding the stop.
+func (m *Master) tx(addr uint16, w, r []byte) error {
+	tenBit := addr&TenBit != 0
+	addr &^= TenBit
+	// the first byte of a 10-bit address.
+	hdr := byte(0xf0 | (addr>>7)&0x06)
+	if err := m.start(); err != nil {
+		return err
+	}
+	// 10-bit reads must write the full address before the repeated start.
+	if len(w) != 0 || len(r) == 0 || tenBit {
+		if tenBit {
+			if err := m.writeAddress(hdr); err != nil {
+				return err
+			}
+			if err := m.writeAddress(byte(addr)); err != nil {
+				return err
+			}
+		} else if err := m.writeAddress(byte(addr << 1)); err != nil {
+			return err
+		}
+		for _, b := range w {
+			ack, err := m.writeByte(b)
+			if err != nil {
+				return err
+			}
+			if !ack {
+				return ErrDataNack
+			}
+		}
+		if len(r) == 0 {
+			return nil
+		}
+		if err := m.start(); err != nil {
+			return err
+		}
+	}
+	if tenBit {
+		if err := m.writeAddress(hdr | 1); err != nil {
+			return err
+		}
+	} else if err := m.writeAddress(byte(addr<<1) | 1); err != nil {
+		return err
+	}
+	for i := range r {
+		b, err := m.readByte(i < len(r)-1)
+		if err != nil {
+			return err
+		}
+		r[i] = b
+	}
+	return nil
+}
+
+// recover clocks the bus until any target holding SDA low releases it, then
+// issues a stop to return the bus to idle.
+func (m *Master) recover() error {
+	v, err := m.sda.Value()
+	if err != nil || v == 1 {
+		return err
+	}
+	// a target may be mid-byte, so needs up to 9 clocks to release SDA.
+	for i := 0; i < 9 && v == 0; i++ {
+		if err = m.sclLow(); err != nil {
+			return err
+		}
+		if err = m.sclHigh(); err != nil {
+			return err
+		}
+		if v, err = m.sda.Value(); err != nil {
+			return err
+		}
+	}
+	if v == 0 {
+		return ErrBusBusy
+	}
+	if err = m.sclLow(); err != nil {
+		return err
+	}
+	return m.stop()
+}
+
+// start issues a start, or a repeated start if SCL is low.
+//
+// Leaves SCL low.
+func (m *Master) start() error {
+	if err := m.sda.SetValue(1); err != nil {
+		return err
+	}
+	m.delay()
+	if err := m.sclHigh(); err != nil {
+		return err
+	}
+	v, err := m.sda.Value()
+	if err != nil {
+		return err
+	}
+	if v == 0 {
+		if err = m.recover(); err != nil {
+			return err
+		}
+		if err = m.sclHigh(); err != nil {
+			return err
+		}
+	}
+	if err = m.sda.SetValue(0); err != nil {
+		return err
+	}
+	m.delay()
+	return m.sclLow()
+}
+
+// stop issues a stop, leaving the bus idle.
+func (m *Master) stop() error {
+	if err := m.sda.SetValue(0); err != nil {
+		return err
+	}
+	m.delay()
+	if err := m.sclHigh(); err != nil {
+		return err
+	}
+	if err := m.sda.SetValue(1); err != nil {
+		return err
+	}
+	m.delay()
+	return nil
+}
+
+// writeAddress writes an address byte, which the target must acknowledge.
+func (m *Master) writeAddress(b byte) error {
+	ack, err := m.writeByte(b)
+	if err != nil {
+		return err
+	}
+	if !ack {
+		return ErrAddressNack
+	}
+	return nil
+}
+
+// writeByte writes the byte, MSB first, and returns true if the target
+// acknowledged it.
+func (m *Master) writeByte(b byte) (bool, error) {
+	for i := 7; i >= 0; i-- {
+		if err := m.writeBit(int(b>>i) & 1); err != nil {
+			return false, err
+		}
+	}
+	v, err := m.readBit()
+	return v == 0, err
+}
+
+// readByte reads a byte, MSB first, and acknowledges it if ack is set.
+func (m *Master) readByte(ack bool) (byte, error) {
+	var b byte
+	for i := 0; i < 8; i++ {
+		v, err := m.readBit()
+		if err != nil {
+			return 0, err
+		}
+		b = b<<1 | byte(v)
+	}
+	nack := 1
+	if ack {
+		nack = 0
+	}
+	return b, m.writeBit(nack)
+}
+
+// writeBit sets SDA to the bit and clocks it out.
+//
+// Expects and leaves SCL low.
+func (m *Master) writeBit(v int) error {
+	if err := m.sda.SetValue(v); err != nil {
+		return err
+	}
+	m.delay()
+	if err := m.sclHigh(); err != nil {
+		return err
+	}
+	return m.sclLow()
+}
+
+// readBit releases SDA and clocks in a bit from the target.
+//
+// Expects and leaves SCL low.
+func (m *Master) readBit() (int, error) {
+	if err := m.sda.SetValue(1); err != nil {
+		return 0, err
+	}
+	m.delay()
+	if err := m.sclHigh(); err != nil {
+		return 0, err
+	}
+	v, err := m.sda.Value()
+	if err != nil {
+		return 0, err
+	}
+	return v, m.sclLow()
+}
+
+// sclHigh releases SCL, waits for any clock stretching by the target, and
+// holds SCL high for half a period.
+func (m *Master) sclHigh() error {
+	if err := m.scl.SetValue(1); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(m.stretchTimeout)
+	for {
+		v, err := m.scl.Value()
+		if err != nil {
+			return err
+		}
+		if v == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return ErrClockStretch
+		}
+		runtime.Gosched()
+	}
+	m.delay()
+	return nil
+}
+
+// sclLow pulls SCL low.
+func (m *Master) sclLow() error {
+	return m.scl.SetValue(0)
+}
+
+// delay waits for half an SCL period.
+//
+// The delay is too short for time.Sleep, so it busy waits, yielding to other
+// goroutines so they are not starved on single core systems.
+func (m *Master) delay() {
+	for start := time.Now(); time.Since(start) < m.half; {
+		runtime.Gosched()
+	}
+}
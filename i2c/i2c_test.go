@@ -0,0 +1,250 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package i2c_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/i2c"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	scl = 1
+	sda = 2
+)
+
+func TestNew(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	_, err = i2c.New(s.ChipName(), scl, sda, i2c.WithFrequency(400000))
+	assert.Equal(t, unix.EINVAL, err)
+
+	_, err = i2c.New(s.ChipName(), scl, 4)
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	m, err := i2c.New(s.ChipName(), scl, sda)
+	require.Nil(t, err)
+	checkIdle(t, s)
+
+	// a stuck SDA cannot be released
+	s.Pulldown(sda)
+	assert.Equal(t, i2c.ErrBusBusy, m.Tx(0x48, nil, nil))
+	s.Pullup(sda)
+	assert.Equal(t, i2c.ErrAddressNack, m.Tx(0x48, nil, nil))
+	checkIdle(t, s)
+
+	assert.Nil(t, m.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, m.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, m.Tx(0x48, nil, nil))
+}
+
+func TestTx(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	m, err := i2c.New(s.ChipName(), scl, sda, i2c.WithFrequency(1000))
+	require.Nil(t, err)
+	defer m.Close()
+
+	tgt := newTarget(s, 0x48)
+	defer tgt.close()
+
+	assert.Equal(t, unix.EINVAL, m.Tx(0x80, nil, nil))
+	assert.Equal(t, unix.EINVAL, m.Tx(i2c.TenBit|0x400, nil, nil))
+
+	// probe
+	assert.Nil(t, m.Tx(0x48, nil, nil))
+	assert.Equal(t, i2c.ErrAddressNack, m.Tx(0x49, nil, nil))
+	assert.Equal(t, i2c.ErrAddressNack, m.Tx(i2c.TenBit|0x48, nil, nil))
+	checkIdle(t, s)
+
+	// write registers
+	assert.Nil(t, m.Tx(0x48, []byte{0x10, 0xa5, 0x3c}, nil))
+	assert.Equal(t, byte(0xa5), tgt.regs[0x10])
+	assert.Equal(t, byte(0x3c), tgt.regs[0x11])
+
+	// read registers, with a repeated start
+	r := make([]byte, 2)
+	assert.Nil(t, m.Tx(0x48, []byte{0x10}, r))
+	assert.Equal(t, []byte{0xa5, 0x3c}, r)
+
+	// read only, continuing from the current register
+	r = make([]byte, 1)
+	tgt.regs[0x12] = 0x81
+	assert.Nil(t, m.Tx(0x48, nil, r))
+	assert.Equal(t, []byte{0x81}, r)
+	checkIdle(t, s)
+}
+
+func TestClockStretch(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	m, err := i2c.New(s.ChipName(), scl, sda,
+		i2c.WithStretchTimeout(20*time.Millisecond))
+	require.Nil(t, err)
+	defer m.Close()
+
+	// a stretch within the timeout
+	s.Pulldown(scl)
+	time.AfterFunc(5*time.Millisecond, func() { s.Pullup(scl) })
+	start := time.Now()
+	assert.Equal(t, i2c.ErrAddressNack, m.Tx(0x48, nil, nil))
+	assert.GreaterOrEqual(t, time.Since(start), 5*time.Millisecond)
+
+	// a stretch beyond the timeout
+	s.Pulldown(scl)
+	assert.Equal(t, i2c.ErrClockStretch, m.Tx(0x48, nil, nil))
+	s.Pullup(scl)
+}
+
+func checkIdle(t *testing.T, s *sim.Chip) {
+	t.Helper()
+	v, _ := s.Level(scl)
+	assert.Equal(t, 1, v)
+	v, _ = s.Level(sda)
+	assert.Equal(t, 1, v)
+}
+
+// target emulates a register based I2C target by polling the bus levels.
+//
+// The target pulls SDA low by pulling down the simulated line.
+type target struct {
+	s    *sim.Chip
+	addr byte
+	regs [256]byte
+
+	done   chan struct{}
+	exited chan struct{}
+}
+
+const (
+	stIdle = iota
+	stAddr
+	stWrite
+	stRead
+)
+
+func newTarget(s *sim.Chip, addr byte) *target {
+	t := &target{
+		s:      s,
+		addr:   addr,
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+func (t *target) close() {
+	close(t.done)
+	<-t.exited
+}
+
+func (t *target) drive(v int) {
+	if v == 0 {
+		t.s.Pulldown(sda)
+	} else {
+		t.s.Pullup(sda)
+	}
+}
+
+func (t *target) run() {
+	defer close(t.exited)
+	state := stIdle
+	next := stIdle
+	// the number of SCL rising edges in the current byte, including the ack.
+	bit := 0
+	var b byte
+	var reg byte
+	// indicates the next written byte is the register address.
+	first := false
+	masterAck := false
+	prevSCL, prevSDA := 1, 1
+	for {
+		select {
+		case <-t.done:
+			return
+		default:
+		}
+		c, _ := t.s.Level(scl)
+		d, _ := t.s.Level(sda)
+		switch {
+		case c == 1 && prevSCL == 1 && prevSDA == 1 && d == 0:
+			// start
+			state = stAddr
+			bit = 0
+			b = 0
+		case c == 1 && prevSCL == 1 && prevSDA == 0 && d == 1:
+			// stop
+			state = stIdle
+		case c == 1 && prevSCL == 0:
+			bit++
+			if bit <= 8 && (state == stAddr || state == stWrite) {
+				b = b<<1 | byte(d)
+			}
+			if bit == 9 && state == stRead {
+				masterAck = d == 0
+			}
+		case c == 0 && prevSCL == 1:
+			switch {
+			case state == stIdle:
+			case bit == 8 && state == stAddr:
+				if b>>1 != t.addr {
+					state = stIdle
+					break
+				}
+				next = stWrite
+				first = true
+				if b&1 == 1 {
+					next = stRead
+				}
+				t.drive(0)
+			case bit == 8 && state == stWrite:
+				if first {
+					reg = b
+					first = false
+				} else {
+					t.regs[reg] = b
+					reg++
+				}
+				t.drive(0)
+			case bit == 8 && state == stRead:
+				// release for the master to ack
+				t.drive(1)
+			case bit == 9:
+				bit = 0
+				b = 0
+				t.drive(1)
+				if state == stAddr {
+					state = next
+				} else if state == stRead && !masterAck {
+					state = stIdle
+					break
+				}
+				if state == stRead {
+					b = t.regs[reg]
+					reg++
+					t.drive(int(b >> 7))
+				}
+			case state == stRead:
+				t.drive(int(b>>(7-bit)) & 1)
+			}
+		}
+		prevSCL, prevSDA = c, d
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,585 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package keypad scans matrix keypads, such as 4x4 and 4x3 membrane keypads.
+//
+// The keypad rows and columns are requested together in one request, with
+// the rows as outputs and the columns as inputs.  The rows are driven low,
+// one at a time, and a pressed key pulls its column low, so both rows and
+// columns are requested active low, and the columns are pulled up.
+// The rows are open drain by default, so pressing several keys in one column
+// cannot short the row outputs.
+//
+//	kp, _ := keypad.New("gpiochip0", []int{5, 6, 13, 19}, []int{12, 16, 20, 21},
+//		keypad.WithKeymap(keypad.Keymap4x4),
+//		keypad.WithInterrupt)
+//	defer kp.Close()
+//	for evt := range kp.Events() {
+//		if evt.Type == keypad.Press {
+//			fmt.Printf("%c\n", evt.Key.Label)
+//		}
+//	}
+package keypad
+
+import (
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+	"golang.org/x/sys/unix"
+)
+
+// Keypad scans a matrix keypad.
+type Keypad struct {
+	ll *gpiocdev.Lines
+
+	rows int
+	cols int
+
+	keymap Keymap
+
+	scanInterval time.Duration
+	debounce     time.Duration
+	settle       time.Duration
+	interrupt    bool
+
+	events chan Event
+
+	// signalled by column edge events in interrupt mode.
+	edges chan struct{}
+
+	// closed to stop the scanner.
+	done chan struct{}
+
+	// closed once the scanner has exited.
+	exited chan struct{}
+
+	// buffers used by the scanner.
+	rowValues []int
+	values    []int
+	raw       []uint64
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	// the debounced state of each key, indexed by row*cols+col.
+	pressed []bool
+
+	// the time the raw state of each key first differed from its debounced
+	// state, or zero if they agree.
+	changed []time.Time
+
+	// the number of scans discarded due to ghosting.
+	ghosts uint64
+
+	// the error that stopped the scanner, if any.
+	err error
+
+	closed bool
+}
+
+// Keymap provides the labels for the keys of a keypad, indexed by row then
+// column.
+type Keymap [][]rune
+
+var (
+	// Keymap4x4 is the layout of a common 4x4 keypad.
+	Keymap4x4 = Keymap{
+		{'1', '2', '3', 'A'},
+		{'4', '5', '6', 'B'},
+		{'7', '8', '9', 'C'},
+		{'*', '0', '#', 'D'},
+	}
+
+	// Keymap4x3 is the layout of a common 4x3 keypad.
+	Keymap4x3 = Keymap{
+		{'1', '2', '3'},
+		{'4', '5', '6'},
+		{'7', '8', '9'},
+		{'*', '0', '#'},
+	}
+)
+
+// Key identifies a key on the keypad.
+type Key struct {
+	// The index of the row containing the key.
+	Row int
+
+	// The index of the column containing the key.
+	Col int
+
+	// The label for the key from the keymap, or 0 if there is no keymap.
+	Label rune
+}
+
+// EventType identifies the type of keypad event.
+type EventType int
+
+const (
+	// Press indicates a key has been pressed.
+	Press EventType = iota
+
+	// Release indicates a key has been released.
+	Release
+)
+
+// Event describes a key being pressed or released.
+type Event struct {
+	// The type of event.
+	Type EventType
+
+	// The key pressed or released.
+	Key Key
+
+	// The time of the scan that first saw the change, before debouncing.
+	Timestamp time.Time
+}
+
+// Option defines the interface required to provide an option to New.
+type Option interface {
+	applyOption(*keypadOptions)
+}
+
+type keypadOptions struct {
+	keymap       Keymap
+	scanInterval time.Duration
+	debounce     time.Duration
+	settle       time.Duration
+	interrupt    bool
+	reqOpts      []gpiocdev.LineReqOption
+	chanSize     int
+}
+
+// KeymapOption sets the keymap of the keypad.
+type KeymapOption Keymap
+
+// WithKeymap sets the labels for the keys.
+//
+// The keymap must have a label for every row and column of the keypad.
+func WithKeymap(keymap Keymap) KeymapOption {
+	return KeymapOption(keymap)
+}
+
+func (o KeymapOption) applyOption(opts *keypadOptions) {
+	opts.keymap = Keymap(o)
+}
+
+// ScanIntervalOption sets the period between scans.
+type ScanIntervalOption time.Duration
+
+// WithScanInterval sets the period between scans of the keypad.
+//
+// The default is 10ms.
+func WithScanInterval(period time.Duration) ScanIntervalOption {
+	return ScanIntervalOption(period)
+}
+
+func (o ScanIntervalOption) applyOption(opts *keypadOptions) {
+	opts.scanInterval = time.Duration(o)
+}
+
+// DebounceOption sets the debounce period.
+type DebounceOption time.Duration
+
+// WithDebounce sets the period a key must remain in a new state, over
+// successive scans, before the change is reported.
+//
+// The default is 20ms.
+func WithDebounce(period time.Duration) DebounceOption {
+	return DebounceOption(period)
+}
+
+func (o DebounceOption) applyOption(opts *keypadOptions) {
+	opts.debounce = time.Duration(o)
+}
+
+// SettleOption sets the settling time after driving a row.
+type SettleOption time.Duration
+
+// WithSettle sets the time allowed for the columns to settle after driving a
+// row, before the columns are read.
+//
+// The default is 0, as the time to read the columns is sufficient for most
+// keypads.
+func WithSettle(period time.Duration) SettleOption {
+	return SettleOption(period)
+}
+
+func (o SettleOption) applyOption(opts *keypadOptions) {
+	opts.settle = time.Duration(o)
+}
+
+// InterruptOption enables interrupt-assisted scanning.
+type InterruptOption bool
+
+// WithInterrupt only scans the keypad after an edge event on a column.
+//
+// While no keys are pressed, all rows are driven, so pressing any key
+// generates an edge event on its column, and the keypad is then scanned until
+// all keys are released.
+const WithInterrupt = InterruptOption(true)
+
+func (o InterruptOption) applyOption(opts *keypadOptions) {
+	opts.interrupt = bool(o)
+}
+
+// RequestOption provides options for the line request.
+type RequestOption []gpiocdev.LineReqOption
+
+// WithRequestOptions provides options for the request of the keypad lines,
+// such as consumer or drive.
+//
+// Direction, active level, column bias, edge detection and event handling
+// options are overridden by the keypad.
+func WithRequestOptions(options ...gpiocdev.LineReqOption) RequestOption {
+	return RequestOption(options)
+}
+
+func (o RequestOption) applyOption(opts *keypadOptions) {
+	opts.reqOpts = append(opts.reqOpts, o...)
+}
+
+// ChannelSizeOption sets the size of the event channel.
+type ChannelSizeOption int
+
+// WithChannelSize sets the buffer size of the event channel.
+//
+// The default is 16.
+func WithChannelSize(size int) ChannelSizeOption {
+	return ChannelSizeOption(size)
+}
+
+func (o ChannelSizeOption) applyOption(opts *keypadOptions) {
+	opts.chanSize = int(o)
+}
+
+// New requests the row and column lines of a keypad from the chip, and starts
+// scanning the keypad.
+//
+// Returns unix.EINVAL if there are no rows or columns, more than 64 columns,
+// the keymap does not match the rows and columns, or any of the timings are
+// invalid.
+func New(chip string, rows, cols []int, options ...Option) (*Keypad, error) {
+	opts := keypadOptions{
+		scanInterval: 10 * time.Millisecond,
+		debounce:     20 * time.Millisecond,
+		chanSize:     16,
+	}
+	for _, option := range options {
+		option.applyOption(&opts)
+	}
+	if len(rows) == 0 || len(cols) == 0 || len(cols) > 64 ||
+		opts.scanInterval <= 0 || opts.debounce < 0 || opts.settle < 0 {
+		return nil, unix.EINVAL
+	}
+	if opts.keymap != nil {
+		if len(opts.keymap) != len(rows) {
+			return nil, unix.EINVAL
+		}
+		for _, r := range opts.keymap {
+			if len(r) != len(cols) {
+				return nil, unix.EINVAL
+			}
+		}
+	}
+	keys := len(rows) * len(cols)
+	k := &Keypad{
+		rows:         len(rows),
+		cols:         len(cols),
+		keymap:       opts.keymap,
+		scanInterval: opts.scanInterval,
+		debounce:     opts.debounce,
+		settle:       opts.settle,
+		interrupt:    opts.interrupt,
+		events:       make(chan Event, opts.chanSize),
+		edges:        make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		exited:       make(chan struct{}),
+		rowValues:    make([]int, len(rows)),
+		values:       make([]int, len(rows)+len(cols)),
+		raw:          make([]uint64, len(rows)),
+		pressed:      make([]bool, keys),
+		changed:      make([]time.Time, keys),
+	}
+	// the rows come first, so the row values can be set without providing
+	// values for the columns, which as inputs are left unchanged by
+	// SetValues.
+	offsets := append(append([]int{}, rows...), cols...)
+	reqOpts := []gpiocdev.LineReqOption{gpiocdev.AsOpenDrain}
+	reqOpts = append(reqOpts, opts.reqOpts...)
+	colOpts := []gpiocdev.SubsetLineConfigOption{gpiocdev.AsInput, gpiocdev.WithPullUp}
+	if k.interrupt {
+		colOpts = append(colOpts, gpiocdev.WithRisingEdge)
+		reqOpts = append(reqOpts, gpiocdev.WithEventHandler(k.handleEdge))
+	}
+	reqOpts = append(reqOpts,
+		gpiocdev.AsActiveLow,
+		gpiocdev.AsOutput(),
+		gpiocdev.WithLines(cols, colOpts...))
+	ll, err := gpiocdev.RequestLines(chip, offsets, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	k.ll = ll
+	go k.run()
+	return k, nil
+}
+
+// Close stops scanning, releases the keypad lines and closes the event
+// channel.
+//
+// Returns the error that stopped the scanner, if any.
+func (k *Keypad) Close() error {
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		return gpiocdev.ErrClosed
+	}
+	k.closed = true
+	k.mu.Unlock()
+	close(k.done)
+	<-k.exited
+	err := k.ll.Close()
+	close(k.events)
+	if kerr := k.Err(); kerr != nil {
+		return kerr
+	}
+	return err
+}
+
+// Err returns the error that stopped the scanner, if any.
+//
+// The scanner stops if setting or reading the lines fails.
+func (k *Keypad) Err() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.err
+}
+
+// Events returns the channel that keypad events are delivered to.
+//
+// If the channel is full then further events are discarded.
+//
+// The channel is closed when the keypad is closed.
+func (k *Keypad) Events() <-chan Event {
+	return k.events
+}
+
+// Ghosts returns the number of scans discarded because of ghosting.
+//
+// Ghosting occurs when three keys at the corners of a rectangle are pressed,
+// so the key at the fourth corner appears to be pressed too.  As the pressed
+// keys cannot be determined, such scans are discarded.
+func (k *Keypad) Ghosts() uint64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.ghosts
+}
+
+// Pressed returns the keys currently pressed, after debouncing.
+func (k *Keypad) Pressed() []Key {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var keys []Key
+	for i, p := range k.pressed {
+		if p {
+			keys = append(keys, k.key(i))
+		}
+	}
+	return keys
+}
+
+// key returns the Key for the key index.
+func (k *Keypad) key(i int) Key {
+	key := Key{Row: i / k.cols, Col: i % k.cols}
+	if k.keymap != nil {
+		key.Label = k.keymap[key.Row][key.Col]
+	}
+	return key
+}
+
+// handleEdge wakes the scanner on an edge event on a column.
+func (k *Keypad) handleEdge(gpiocdev.LineEvent) {
+	select {
+	case k.edges <- struct{}{}:
+	default:
+	}
+}
+
+// run scans the keypad until the keypad is closed or accessing the lines
+// fails.
+func (k *Keypad) run() {
+	defer close(k.exited)
+	ticker := time.NewTicker(k.scanInterval)
+	defer ticker.Stop()
+	for {
+		if k.interrupt && k.idle() {
+			ok, err := k.waitEdge()
+			if err != nil {
+				k.fail(err)
+				return
+			}
+			if !ok {
+				return
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-k.done:
+			return
+		}
+		if err := k.scan(); err != nil {
+			k.fail(err)
+			return
+		}
+	}
+}
+
+// fail records the error that stopped the scanner.
+func (k *Keypad) fail(err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.err = err
+}
+
+// idle returns true if no keys are pressed or changing.
+func (k *Keypad) idle() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for i, p := range k.pressed {
+		if p || !k.changed[i].IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// waitEdge drives all rows and waits for a key to be pressed.
+//
+// Returns false if the keypad is closed while waiting.
+func (k *Keypad) waitEdge() (bool, error) {
+	if err := k.setRows(allRows); err != nil {
+		return false, err
+	}
+	// discard edges generated by scanning.
+	select {
+	case <-k.edges:
+	default:
+	}
+	// a key pressed before the edges were discarded.
+	if err := k.ll.Values(k.values); err != nil {
+		return false, err
+	}
+	for _, v := range k.values[k.rows:] {
+		if v == 1 {
+			return true, nil
+		}
+	}
+	select {
+	case <-k.edges:
+		return true, nil
+	case <-k.done:
+		return false, nil
+	}
+}
+
+// the row arguments to setRows that select more than a single row.
+const (
+	// allRows drives all the rows.
+	allRows = -1
+
+	// noRows releases all the rows.
+	noRows = -2
+)
+
+// setRows drives the row, all rows if row is allRows, or no rows if row is
+// noRows.
+func (k *Keypad) setRows(row int) error {
+	for i := range k.rowValues {
+		k.rowValues[i] = 0
+		if row == allRows || row == i {
+			k.rowValues[i] = 1
+		}
+	}
+	return k.ll.SetValues(k.rowValues)
+}
+
+// scan reads the state of every key, and updates the debounced state of the
+// keys from it.
+func (k *Keypad) scan() error {
+	now := time.Now()
+	for r := range k.raw {
+		if err := k.setRows(r); err != nil {
+			return err
+		}
+		if k.settle > 0 {
+			time.Sleep(k.settle)
+		}
+		if err := k.ll.Values(k.values); err != nil {
+			return err
+		}
+		var mask uint64
+		for c, v := range k.values[k.rows:] {
+			if v == 1 {
+				mask |= 1 << c
+			}
+		}
+		k.raw[r] = mask
+	}
+	if err := k.setRows(noRows); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if ghosted(k.raw) {
+		k.ghosts++
+		return nil
+	}
+	for r, mask := range k.raw {
+		for c := 0; c < k.cols; c++ {
+			k.update(r*k.cols+c, mask&(1<<c) != 0, now)
+		}
+	}
+	return nil
+}
+
+// update applies the raw state of a key to its debounced state.
+//
+// Assumes k is locked.
+func (k *Keypad) update(i int, pressed bool, now time.Time) {
+	if pressed == k.pressed[i] {
+		k.changed[i] = time.Time{}
+		return
+	}
+	if k.changed[i].IsZero() {
+		k.changed[i] = now
+	}
+	if now.Sub(k.changed[i]) < k.debounce {
+		return
+	}
+	k.pressed[i] = pressed
+	evt := Event{Type: Release, Key: k.key(i), Timestamp: k.changed[i]}
+	if pressed {
+		evt.Type = Press
+	}
+	k.changed[i] = time.Time{}
+	select {
+	case k.events <- evt:
+	default:
+	}
+}
+
+// ghosted returns true if any two rows share more than one pressed column,
+// in which case at least one of the keys appearing pressed may be a ghost.
+func ghosted(raw []uint64) bool {
+	for i, r1 := range raw {
+		for _, r2 := range raw[i+1:] {
+			m := r1 & r2
+			if m&(m-1) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package keypad_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/keypad"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"golang.org/x/sys/unix"
+)
+
+// The simulator cannot connect rows to columns, so a key is simulated by
+// pulling its column low, which appears to press that column's key in every
+// driven row.
+
+func TestNew(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	_, err = keypad.New(s.ChipName(), nil, []int{1, 2})
+	assert.Equal(t, unix.EINVAL, err)
+
+	_, err = keypad.New(s.ChipName(), []int{0}, []int{1, 2},
+		keypad.WithKeymap(keypad.Keymap4x3))
+	assert.Equal(t, unix.EINVAL, err)
+
+	_, err = keypad.New(s.ChipName(), []int{0}, []int{1, 8})
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	kp, err := keypad.New(s.ChipName(), []int{0, 1, 2, 3}, []int{4, 5, 6},
+		keypad.WithKeymap(keypad.Keymap4x3))
+	require.Nil(t, err)
+	assert.Empty(t, kp.Pressed())
+	assert.Nil(t, kp.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, kp.Close())
+	_, ok := <-kp.Events()
+	assert.False(t, ok)
+}
+
+func TestScan(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	for _, interrupt := range []bool{false, true} {
+		kp, err := keypad.New(s.ChipName(), []int{0}, []int{1, 2, 3},
+			keypad.WithKeymap(keypad.Keymap{{'a', 'b', 'c'}}),
+			keypad.InterruptOption(interrupt),
+			keypad.WithScanInterval(time.Millisecond),
+			keypad.WithDebounce(5*time.Millisecond))
+		require.Nil(t, err)
+
+		s.Pulldown(2)
+		evt := waitEvent(t, kp)
+		assert.Equal(t, keypad.Press, evt.Type)
+		assert.Equal(t, keypad.Key{Row: 0, Col: 1, Label: 'b'}, evt.Key)
+		assert.Equal(t, []keypad.Key{{Row: 0, Col: 1, Label: 'b'}}, kp.Pressed())
+
+		s.Pullup(2)
+		evt = waitEvent(t, kp)
+		assert.Equal(t, keypad.Release, evt.Type)
+		assert.Equal(t, 'b', evt.Key.Label)
+		assert.Empty(t, kp.Pressed())
+
+		// a bounce shorter than the debounce period is ignored
+		s.Pulldown(3)
+		s.Pullup(3)
+		select {
+		case evt := <-kp.Events():
+			assert.Fail(t, "unexpected event", evt)
+		case <-time.After(50 * time.Millisecond):
+		}
+		assert.Nil(t, kp.Close())
+	}
+}
+
+func TestInterruptIdle(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	kp, err := keypad.New(s.ChipName(), []int{0, 1}, []int{2, 3},
+		keypad.WithInterrupt)
+	require.Nil(t, err)
+	defer kp.Close()
+
+	// all rows are driven low while idle
+	assert.Eventually(t, func() bool {
+		v0, _ := s.Level(0)
+		v1, _ := s.Level(1)
+		return v0 == 0 && v1 == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestGhosting(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	kp, err := keypad.New(s.ChipName(), []int{0, 1}, []int{2, 3},
+		keypad.WithScanInterval(time.Millisecond))
+	require.Nil(t, err)
+	defer kp.Close()
+
+	// every key appears pressed, so any one of them may be a ghost.
+	s.Pulldown(2)
+	s.Pulldown(3)
+	assert.Eventually(t, func() bool { return kp.Ghosts() != 0 },
+		time.Second, time.Millisecond)
+	assert.Empty(t, kp.Pressed())
+}
+
+func waitEvent(t *testing.T, kp *keypad.Keypad) keypad.Event {
+	t.Helper()
+	select {
+	case evt := <-kp.Events():
+		return evt
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for event")
+	}
+	return keypad.Event{}
+}
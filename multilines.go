@@ -0,0 +1,628 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// ChipOffset identifies a line by the name of its chip and its offset within
+// that chip.
+type ChipOffset struct {
+	Chip   string
+	Offset int
+}
+
+// ErrLineNotFound indicates a named line cannot be found.
+//
+// It matches ErrNotFound when tested with errors.Is.
+type ErrLineNotFound struct {
+	Name string
+}
+
+func (e ErrLineNotFound) Error() string {
+	return "line " + e.Name + " not found"
+}
+
+// Is returns true if the target is ErrNotFound.
+func (e ErrLineNotFound) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// FindLines returns the chip and offset of each of the named lines, if found
+// on available chips.
+//
+// If multiple lines have the same name then the first one found (lowest chip
+// and lowest offset) is returned.
+//
+// Returns ErrLineNotFound for the first name that cannot be found.
+func FindLines(names ...string) ([]ChipOffset, error) {
+	lines := make([]ChipOffset, len(names))
+	pending := map[string][]int{}
+	for i, name := range names {
+		pending[name] = append(pending[name], i)
+	}
+	for _, chip := range Chips() {
+		if len(pending) == 0 {
+			break
+		}
+		c, err := NewChip(chip)
+		if err != nil {
+			continue
+		}
+		for o := 0; o < c.lines && len(pending) != 0; o++ {
+			inf, err := c.LineInfo(o)
+			if err != nil {
+				continue
+			}
+			for _, i := range pending[inf.Name] {
+				lines[i] = ChipOffset{Chip: chip, Offset: o}
+			}
+			delete(pending, inf.Name)
+		}
+		c.Close()
+	}
+	for _, name := range names {
+		if _, ok := pending[name]; ok {
+			return nil, ErrLineNotFound{name}
+		}
+	}
+	return lines, nil
+}
+
+// MultiLines represents a collection of requested lines that may span several
+// chips.
+//
+// The lines on each chip are requested from the kernel as a separate request,
+// so operations on the collection are not atomic across chips.
+//
+// Where the Lines API refers to lines by offset, such as the values provided
+// to AsOutput and the offsets provided to WithLines, the MultiLines API refers
+// to lines by their index in the collection.
+type MultiLines struct {
+	lines []ChipOffset
+
+	// the request for each chip, in order of the first line on each chip.
+	reqs []multiReq
+
+	// the loop delivering events, if any.
+	loop *EventLoop
+
+	// indicates the loop was created for, and so is closed with, the lines.
+	private bool
+
+	merger *eventMerger
+	ec     *eventChannel
+
+	// mu covers all that follow - those above are immutable
+	mu sync.Mutex
+
+	// the values, defCfg and lineCfg for the lines, keyed by index.
+	values  map[int]int
+	defCfg  LineConfig
+	lineCfg map[int]*LineConfig
+	closed  bool
+
+	// closed to terminate the context watcher, if any.
+	ctxDone chan struct{}
+}
+
+// multiReq is the request for the lines on one chip.
+type multiReq struct {
+	ll *Lines
+
+	// the index of each of the lines in the collection.
+	idxs []int
+}
+
+// RequestMultiLines requests control of a collection of lines that may span
+// several chips.
+//
+// The lines on each chip are requested using the options, and any event
+// handler or event channel receives the events for all the lines, with the
+// Chip field of each event set.
+//
+// If granted, control is maintained until the MultiLines are closed.
+func RequestMultiLines(lines []ChipOffset, options ...LineReqOption) (*MultiLines, error) {
+	lro := lineReqOptions{
+		lineConfigOptions: lineConfigOptions{
+			offsets: make([]int, len(lines)),
+			values:  map[int]int{},
+		},
+	}
+	for i := range lines {
+		lro.offsets[i] = i
+	}
+	for _, option := range options {
+		option.applyLineReqOption(&lro)
+	}
+	if lro.eventChan != nil {
+		if err := lro.eventChan.validate(); err != nil {
+			return nil, err
+		}
+	}
+	ml := MultiLines{
+		lines:   append([]ChipOffset(nil), lines...),
+		values:  lro.values,
+		defCfg:  lro.defCfg,
+		lineCfg: lro.lineCfg,
+	}
+	if lro.eventChan != nil {
+		ml.ec = newEventChannel(*lro.eventChan)
+		lro.eh = ml.ec.handle
+		lro.errh = ml.ec.errorHandler(lro.errh)
+	}
+	if lro.eh != nil {
+		lro.bh = lro.eh.batchHandler()
+	}
+	if lro.bh != nil {
+		ml.loop = lro.loop
+		if ml.loop == nil {
+			loop, err := NewEventLoop()
+			if err != nil {
+				return nil, err
+			}
+			ml.loop = loop
+			ml.private = true
+		}
+		ml.merger = &eventMerger{bh: lro.bh}
+	}
+	chips := map[string]int{}
+	for i, co := range ml.lines {
+		ci, ok := chips[co.Chip]
+		if !ok {
+			ci = len(ml.reqs)
+			chips[co.Chip] = ci
+			ml.reqs = append(ml.reqs, multiReq{})
+		}
+		ml.reqs[ci].idxs = append(ml.reqs[ci].idxs, i)
+	}
+	for i := range ml.reqs {
+		r := &ml.reqs[i]
+		ll, err := ml.request(ml.lines[r.idxs[0]].Chip, r.idxs, lro)
+		if err != nil {
+			ml.release()
+			return nil, err
+		}
+		r.ll = ll
+	}
+	if ml.merger != nil {
+		// added after the requests, so the merger is flushed after any
+		// mergers of split requests.
+		ml.loop.addFlusher(ml.merger)
+	}
+	return &ml, nil
+}
+
+// RequestMultiLinesContext requests control of a collection of lines that may
+// span several chips, for the lifetime of the context.
+//
+// If granted, control is maintained until the MultiLines are closed or the
+// context is done, whichever comes first.
+func RequestMultiLinesContext(ctx context.Context, lines []ChipOffset, options ...LineReqOption) (*MultiLines, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ml, err := RequestMultiLines(lines, options...)
+	if err != nil {
+		return nil, err
+	}
+	ml.closeWithContext(ctx)
+	return ml, nil
+}
+
+// closeWithContext closes the lines when the context is done.
+//
+// Must be called before the lines are returned to the caller.
+func (ml *MultiLines) closeWithContext(ctx context.Context) {
+	if ctx.Done() == nil {
+		return
+	}
+	ml.ctxDone = make(chan struct{})
+	go func(done <-chan struct{}) {
+		select {
+		case <-ctx.Done():
+			ml.Close()
+		case <-done:
+		}
+	}(ml.ctxDone)
+}
+
+// request requests the lines, identified by index, from the chip.
+func (ml *MultiLines) request(chip string, idxs []int, lro lineReqOptions) (*Lines, error) {
+	c, err := NewChip(chip)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	offsets := make([]int, len(idxs))
+	for i, idx := range idxs {
+		o := ml.lines[idx].Offset
+		if o < 0 || o >= c.lines {
+			return nil, ErrInvalidOffset
+		}
+		offsets[i] = o
+	}
+	clro := lineReqOptions{
+		lineConfigOptions: lro.subset(idxs, offsets),
+		consumer:          lro.consumer,
+		abi:               lro.abi,
+		errh:              lro.errh,
+		loop:              ml.loop,
+		eventBufferSize:   lro.eventBufferSize,
+		overflowEvents:    lro.overflowEvents,
+	}
+	if clro.consumer == "" {
+		clro.consumer = c.options.consumer
+	}
+	if clro.abi == 0 {
+		clro.abi = c.options.abi
+	}
+	if ml.merger != nil {
+		clro.bh = ml.merger.handler(c.Name)
+	}
+	return c.requestLines(clro)
+}
+
+// release closes the requests and stops the delivery of events.
+//
+// Assumes ml is locked, or not yet shared.
+func (ml *MultiLines) release() {
+	if ml.ec != nil {
+		ml.ec.stop()
+	}
+	for _, r := range ml.reqs {
+		if r.ll != nil {
+			r.ll.Close()
+		}
+	}
+	if ml.merger != nil {
+		ml.loop.removeFlusher(ml.merger)
+		if ml.private {
+			ml.loop.Close()
+		}
+	}
+	if ml.ec != nil {
+		ml.ec.close()
+	}
+}
+
+// Close releases all resources held by the requested lines.
+//
+// As with Lines, the Close must not be called from the context of the event
+// handler.
+func (ml *MultiLines) Close() error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if ml.closed {
+		return ErrClosed
+	}
+	ml.closed = true
+	if ml.ctxDone != nil {
+		close(ml.ctxDone)
+	}
+	ml.release()
+	return nil
+}
+
+// Lines returns the chip and offset of the requested lines.
+func (ml *MultiLines) Lines() []ChipOffset {
+	return ml.lines
+}
+
+// Info returns the information about the lines.
+func (ml *MultiLines) Info() ([]*LineInfo, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if ml.closed {
+		return nil, ErrClosed
+	}
+	info := make([]*LineInfo, len(ml.lines))
+	for _, r := range ml.reqs {
+		inf, err := r.ll.Info()
+		if err != nil {
+			return nil, err
+		}
+		for i, idx := range r.idxs {
+			info[idx] = inf[i]
+		}
+	}
+	return info, nil
+}
+
+// Values returns the current values (active state) of the collection of lines.
+//
+// Values are 0 for inactive and 1 for active.
+//
+// Gets as many values from the set, in order, as can be fit in values, up to
+// the full set.
+//
+// The values for each chip are read separately, so the values are not a
+// snapshot of the lines at a single point in time.
+func (ml *MultiLines) Values(values []int) error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if ml.closed {
+		return ErrClosed
+	}
+	return ml.getValues(values)
+}
+
+// getValues gets the values of the lines on each chip in turn.
+//
+// Assumes ml is locked.
+func (ml *MultiLines) getValues(values []int) error {
+	for _, r := range ml.reqs {
+		vv := make([]int, len(r.idxs))
+		if err := r.ll.Values(vv); err != nil {
+			return err
+		}
+		for i, idx := range r.idxs {
+			if idx < len(values) {
+				values[idx] = vv[i]
+			}
+		}
+	}
+	return nil
+}
+
+// SetValues sets the current active state of the collection of lines.
+//
+// Only valid for output lines.  As with Lines.SetValues, the values
+// corresponding to input lines are ignored, and ErrPermissionDenied is
+// returned if none of the lines are outputs.
+//
+// Values are 0 for inactive and 1 for active.
+//
+// If insufficient values are provided then the remaining lines are set to
+// inactive. If too many values are provided then the surplus values are
+// ignored.
+//
+// The lines on each chip are set at once, but the chips are set one after the
+// other, so the change is not atomic across chips.  If setting the lines on
+// one chip fails then the lines on the following chips are not set.
+func (ml *MultiLines) SetValues(values []int) error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if !ml.anyOutput() {
+		return ErrPermissionDenied
+	}
+	if ml.closed {
+		return ErrClosed
+	}
+	return ml.setValues(values)
+}
+
+// setValues sets the values of the lines on each chip in turn.
+//
+// Chips with no output lines are skipped.
+//
+// Assumes ml is locked.
+func (ml *MultiLines) setValues(values []int) error {
+	for _, r := range ml.reqs {
+		outputs := false
+		vv := make([]int, len(r.idxs))
+		for i, idx := range r.idxs {
+			if idx < len(values) {
+				vv[i] = values[idx]
+			}
+			outputs = outputs || ml.isOutput(r, idx)
+		}
+		if !outputs {
+			continue
+		}
+		if err := r.ll.SetValues(vv); err != nil {
+			return err
+		}
+		for i, idx := range r.idxs {
+			if ml.isOutput(r, idx) {
+				ml.values[idx] = vv[i]
+			}
+		}
+	}
+	return nil
+}
+
+// isOutput returns true if the line at the index, which is part of the
+// request, is configured as an output.
+//
+// The per-line configuration is not applied with uAPI v1, so only the default
+// configuration is considered.
+//
+// Assumes ml is locked.
+func (ml *MultiLines) isOutput(r multiReq, idx int) bool {
+	if lc := ml.lineCfg[idx]; lc != nil && r.ll.abi != 1 {
+		return lc.Direction == LineDirectionOutput
+	}
+	return ml.defCfg.Direction == LineDirectionOutput
+}
+
+// anyOutput returns true if any of the lines are configured as outputs.
+//
+// Assumes ml is locked.
+func (ml *MultiLines) anyOutput() bool {
+	for _, r := range ml.reqs {
+		for _, idx := range r.idxs {
+			if ml.isOutput(r, idx) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Reconfigure updates the configuration of the requested lines.
+//
+// Configuration for options other than those passed in remain unchanged.
+//
+// Not valid for lines with edge detection enabled using uAPI v1.
+//
+// The lines on each chip are reconfigured one chip after the other.  If
+// reconfiguring the lines on one chip fails then the lines on the following
+// chips are not reconfigured.
+func (ml *MultiLines) Reconfigure(options ...LineConfigOption) error {
+	if len(options) == 0 {
+		return nil
+	}
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if ml.closed {
+		return ErrClosed
+	}
+	for _, r := range ml.reqs {
+		if r.ll.isEvent {
+			return unix.EINVAL
+		}
+	}
+	lco := lineConfigOptions{
+		offsets: make([]int, len(ml.lines)),
+		values:  ml.values,
+		defCfg:  ml.defCfg,
+		lineCfg: ml.lineCfg,
+	}
+	for i := range lco.offsets {
+		lco.offsets[i] = i
+	}
+	for _, option := range options {
+		option.applyLineConfigOption(&lco)
+	}
+	ml.defCfg = lco.defCfg
+	ml.lineCfg = lco.lineCfg
+	for _, r := range ml.reqs {
+		l := &r.ll.baseLine
+		l.mu.Lock()
+		err := l.reconfigure(lco.subset(r.idxs, l.offsets))
+		l.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadEvents reads edge events on the lines into buf.
+//
+// The events are read directly from the line requests, so the lines must have
+// been requested with edge detection enabled, and without an event handler or
+// event channel.
+//
+// Blocks until at least one event is available, the context is done, or the
+// lines are closed.  Returns the number of events read, which may be fewer
+// than the length of buf.  The events are ordered by timestamp.
+//
+// Requires uAPI v2.
+func (ml *MultiLines) ReadEvents(ctx context.Context, buf []LineEvent) (int, error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	ml.mu.Lock()
+	if ml.closed {
+		ml.mu.Unlock()
+		return 0, ErrClosed
+	}
+	var ll []*baseLine
+	for _, r := range ml.reqs {
+		rll, err := r.ll.startReading()
+		if err != nil {
+			stopReading(ll)
+			ml.mu.Unlock()
+			return 0, err
+		}
+		ll = append(ll, rll...)
+	}
+	ml.mu.Unlock()
+	defer stopReading(ll)
+	n, err := readLineEvents(ctx, ll, buf, true)
+	sortEvents(buf[:n])
+	return n, err
+}
+
+// Err returns the error that stopped the delivery of edge events to the event
+// handler or event channel, if any.
+//
+// If delivery has been stopped for several chips then the error for the first
+// chip is returned.
+func (ml *MultiLines) Err() error {
+	for _, r := range ml.reqs {
+		if err := r.ll.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LostEvents returns the number of edge events lost by the kernel for all the
+// line requests.
+//
+// Requires uAPI v2 - always returns 0 for uAPI v1.
+func (ml *MultiLines) LostEvents() uint64 {
+	var lost uint64
+	for _, r := range ml.reqs {
+		lost += r.ll.LostEvents()
+	}
+	return lost
+}
+
+// Events returns the channel that line events are delivered to.
+//
+// Returns nil unless the lines were requested with the WithEventChannel
+// option.
+//
+// The channel is closed when the lines are closed, or when the delivery of
+// events for any of the chips is stopped by an error, so Err should be checked
+// once the channel is closed.
+func (ml *MultiLines) Events() <-chan LineEvent {
+	if ml.ec == nil {
+		return nil
+	}
+	return ml.ec.ch
+}
+
+// eventMerger merges the events from several line requests sharing an
+// EventLoop into a single stream ordered by timestamp.
+//
+// The events read by the loop are collected, and delivered together once the
+// loop has read all the requests with events available.  So events are only
+// ordered within each such batch, and then only if all lines use the same
+// event clock.
+type eventMerger struct {
+	bh EventBatchHandler
+
+	// the events collected since the last flush.
+	//
+	// Only accessed from the loop goroutine.
+	evts []LineEvent
+}
+
+// handler returns the handler for the events from the named chip.
+func (em *eventMerger) handler(chip string) EventBatchHandler {
+	return func(evts []LineEvent) {
+		for _, evt := range evts {
+			evt.Chip = chip
+			em.evts = append(em.evts, evt)
+		}
+	}
+}
+
+func (em *eventMerger) flush() {
+	if len(em.evts) == 0 {
+		return
+	}
+	sortEvents(em.evts)
+	em.bh(em.evts)
+	em.evts = em.evts[:0]
+}
+
+// sortEvents sorts the events by timestamp, retaining the order of events
+// with the same timestamp.
+func sortEvents(evts []LineEvent) {
+	sort.SliceStable(evts, func(i, j int) bool {
+		return evts[i].Timestamp < evts[j].Timestamp
+	})
+}
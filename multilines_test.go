@@ -0,0 +1,405 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/sim"
+)
+
+// newSimChips creates a pair of simulated chips, closed when the test ends.
+func newSimChips(t *testing.T) (*sim.Chip, *sim.Chip) {
+	t.Helper()
+	s1, err := sim.NewChip(8, sim.WithNamedLine(2, "multi-a"))
+	require.Nil(t, err)
+	t.Cleanup(func() { s1.Close() })
+	s2, err := sim.NewChip(8, sim.WithNamedLine(5, "multi-b"))
+	require.Nil(t, err)
+	t.Cleanup(func() { s2.Close() })
+	return s1, s2
+}
+
+func TestFindLines(t *testing.T) {
+	s1, s2 := newSimChips(t)
+
+	lines, err := gpiocdev.FindLines("multi-b", "multi-a")
+	assert.Nil(t, err)
+	assert.Equal(t, []gpiocdev.ChipOffset{
+		{Chip: s2.ChipName(), Offset: 5},
+		{Chip: s1.ChipName(), Offset: 2},
+	}, lines)
+
+	_, err = gpiocdev.FindLines("multi-a", "nonexistent")
+	assert.Equal(t, gpiocdev.ErrLineNotFound{Name: "nonexistent"}, err)
+	assert.True(t, errors.Is(err, gpiocdev.ErrNotFound))
+}
+
+func TestRequestMultiLines(t *testing.T) {
+	s1, s2 := newSimChips(t)
+	lines := []gpiocdev.ChipOffset{
+		{Chip: s1.ChipName(), Offset: 3},
+		{Chip: s2.ChipName(), Offset: 1},
+		{Chip: s1.ChipName(), Offset: 6},
+	}
+
+	// invalid offset
+	_, err := gpiocdev.RequestMultiLines(append(lines,
+		gpiocdev.ChipOffset{Chip: s2.ChipName(), Offset: 8}))
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	// unknown chip
+	_, err = gpiocdev.RequestMultiLines(append(lines,
+		gpiocdev.ChipOffset{Chip: "nonexistent", Offset: 1}))
+	assert.NotNil(t, err)
+
+	// values by index
+	ml, err := gpiocdev.RequestMultiLines(lines,
+		gpiocdev.AsOutput(1, 0, 1),
+		gpiocdev.WithConsumer("test-multi"))
+	require.Nil(t, err)
+	assert.Equal(t, lines, ml.Lines())
+	checkLevel(t, s1, 3, 1)
+	checkLevel(t, s2, 1, 0)
+	checkLevel(t, s1, 6, 1)
+
+	inf, err := ml.Info()
+	assert.Nil(t, err)
+	require.Equal(t, 3, len(inf))
+	assert.Equal(t, 1, inf[1].Offset)
+	assert.Equal(t, "test-multi", inf[1].Consumer)
+	assert.Equal(t, 6, inf[2].Offset)
+
+	err = ml.SetValues([]int{0, 1})
+	assert.Nil(t, err)
+	checkLevel(t, s1, 3, 0)
+	checkLevel(t, s2, 1, 1)
+	checkLevel(t, s1, 6, 0)
+
+	vv := make([]int, 3)
+	err = ml.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1, 0}, vv)
+
+	// subset by index
+	err = ml.Reconfigure(gpiocdev.WithLines([]int{1, 2}, gpiocdev.AsActiveLow))
+	assert.Nil(t, err)
+	checkLevel(t, s2, 1, 0)
+	checkLevel(t, s1, 6, 1)
+	c, err := gpiocdev.NewChip(s1.ChipName())
+	require.Nil(t, err)
+	li, err := c.LineInfo(3)
+	assert.Nil(t, err)
+	assert.False(t, li.Config.ActiveLow)
+	li, err = c.LineInfo(6)
+	assert.Nil(t, err)
+	assert.True(t, li.Config.ActiveLow)
+	c.Close()
+
+	err = ml.Reconfigure(gpiocdev.AsInput,
+		gpiocdev.WithLines([]int{1, 2}, gpiocdev.AsInput))
+	assert.Nil(t, err)
+	err = ml.SetValues([]int{1, 1, 1})
+	assert.Equal(t, gpiocdev.ErrPermissionDenied, err)
+	s2.Pullup(1)
+	err = ml.Values(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 0, 1}, vv)
+
+	assert.Nil(t, ml.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, ml.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, ml.Values(vv))
+
+	// lines released
+	l, err := gpiocdev.RequestLine(s2.ChipName(), 1)
+	assert.Nil(t, err)
+	l.Close()
+}
+
+func TestMultiLinesEvents(t *testing.T) {
+	s1, s2 := newSimChips(t)
+	lines := []gpiocdev.ChipOffset{
+		{Chip: s1.ChipName(), Offset: 3},
+		{Chip: s2.ChipName(), Offset: 1},
+	}
+
+	ml, err := gpiocdev.RequestMultiLines(lines,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventChannel(8, gpiocdev.EventChannelBlock))
+	require.Nil(t, err)
+
+	s2.Pullup(1)
+	s1.Pullup(3)
+	s2.Pulldown(1)
+	var evts []gpiocdev.LineEvent
+	for len(evts) < 3 {
+		select {
+		case evt := <-ml.Events():
+			evts = append(evts, evt)
+		case <-time.After(time.Second):
+			require.Fail(t, "timeout waiting for event")
+		}
+	}
+	for i := 1; i < len(evts); i++ {
+		assert.LessOrEqual(t, evts[i-1].Timestamp, evts[i].Timestamp)
+	}
+	assert.Equal(t, s2.ChipName(), evts[0].Chip)
+	assert.Equal(t, 1, evts[0].Offset)
+	assert.Equal(t, gpiocdev.LineEventRisingEdge, evts[0].Type)
+	assert.Equal(t, s1.ChipName(), evts[1].Chip)
+	assert.Equal(t, 3, evts[1].Offset)
+	assert.Equal(t, s2.ChipName(), evts[2].Chip)
+	assert.Equal(t, gpiocdev.LineEventFallingEdge, evts[2].Type)
+
+	buf := make([]gpiocdev.LineEvent, 4)
+	_, err = ml.ReadEvents(context.Background(), buf)
+	assert.Equal(t, gpiocdev.ErrAsyncEvents, err)
+
+	assert.Nil(t, ml.Close())
+	_, ok := <-ml.Events()
+	assert.False(t, ok)
+}
+
+func TestMultiLinesReadEvents(t *testing.T) {
+	s1, s2 := newSimChips(t)
+	lines := []gpiocdev.ChipOffset{
+		{Chip: s1.ChipName(), Offset: 3},
+		{Chip: s2.ChipName(), Offset: 1},
+	}
+
+	ml, err := gpiocdev.RequestMultiLines(lines, gpiocdev.WithRisingEdge)
+	require.Nil(t, err)
+	defer ml.Close()
+
+	s2.Pullup(1)
+	s1.Pullup(3)
+	// ensure both are available to be read together
+	time.Sleep(10 * time.Millisecond)
+	buf := make([]gpiocdev.LineEvent, 4)
+	n, err := ml.ReadEvents(context.Background(), buf)
+	assert.Nil(t, err)
+	require.Equal(t, 2, n)
+	assert.Equal(t, s2.ChipName(), buf[0].Chip)
+	assert.Equal(t, 1, buf[0].Offset)
+	assert.Equal(t, s1.ChipName(), buf[1].Chip)
+	assert.Equal(t, 3, buf[1].Offset)
+	assert.Less(t, buf[0].Timestamp, buf[1].Timestamp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = ml.ReadEvents(ctx, buf)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// closing wakes a blocked reader
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ml.Close()
+	}()
+	_, err = ml.ReadEvents(context.Background(), buf)
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+}
+
+func TestMultiLinesSetValuesMixed(t *testing.T) {
+	s1, s2 := newSimChips(t)
+	lines := []gpiocdev.ChipOffset{
+		{Chip: s1.ChipName(), Offset: 3},
+		{Chip: s2.ChipName(), Offset: 1},
+		{Chip: s1.ChipName(), Offset: 6},
+	}
+	s1.Pullup(6)
+
+	// the only output is on the second chip
+	ml, err := gpiocdev.RequestMultiLines(lines,
+		gpiocdev.AsInput,
+		gpiocdev.WithLines([]int{1}, gpiocdev.AsOutput(0)))
+	require.Nil(t, err)
+	defer ml.Close()
+	err = ml.SetValues([]int{1, 1, 0})
+	assert.Nil(t, err)
+	checkLevel(t, s1, 3, 0)
+	checkLevel(t, s2, 1, 1)
+	checkLevel(t, s1, 6, 1)
+
+	err = ml.Reconfigure(gpiocdev.WithLines([]int{1}, gpiocdev.AsInput))
+	assert.Nil(t, err)
+	err = ml.SetValues([]int{1, 1, 0})
+	assert.Equal(t, gpiocdev.ErrPermissionDenied, err)
+	ml.Close()
+
+	nl, err := gpiocdev.RequestLinesByName([]string{"multi-b", "multi-a"},
+		gpiocdev.AsInput,
+		gpiocdev.WithLines([]int{1}, gpiocdev.AsOutput(0)))
+	require.Nil(t, err)
+	defer nl.Close()
+	err = nl.SetNamedValues(map[string]int{"multi-a": 1, "multi-b": 1})
+	assert.Nil(t, err)
+	checkLevel(t, s1, 2, 1)
+	checkLevel(t, s2, 5, 0)
+}
+
+func TestRequestLinesByName(t *testing.T) {
+	s1, s2 := newSimChips(t)
+
+	_, err := gpiocdev.RequestLinesByName([]string{"multi-a", "nonexistent"})
+	assert.Equal(t, gpiocdev.ErrLineNotFound{Name: "nonexistent"}, err)
+
+	nl, err := gpiocdev.RequestLinesByName([]string{"multi-b", "multi-a"},
+		gpiocdev.AsOutput(1, 0))
+	require.Nil(t, err)
+	defer nl.Close()
+	assert.Equal(t, []string{"multi-b", "multi-a"}, nl.Names())
+	checkLevel(t, s2, 5, 1)
+	checkLevel(t, s1, 2, 0)
+
+	idx, ok := nl.Index("multi-a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, idx)
+	_, ok = nl.Index("nonexistent")
+	assert.False(t, ok)
+	co, ok := nl.Line("multi-a")
+	assert.True(t, ok)
+	assert.Equal(t, gpiocdev.ChipOffset{Chip: s1.ChipName(), Offset: 2}, co)
+
+	// unnamed lines retain their values
+	err = nl.SetNamedValues(map[string]int{"multi-a": 1})
+	assert.Nil(t, err)
+	checkLevel(t, s2, 5, 1)
+	checkLevel(t, s1, 2, 1)
+
+	err = nl.SetNamedValues(map[string]int{"multi-b": 0, "nonexistent": 1})
+	assert.Equal(t, gpiocdev.ErrLineNotFound{Name: "nonexistent"}, err)
+	checkLevel(t, s2, 5, 1)
+
+	err = nl.SetNamedValues(map[string]int{"multi-b": 0})
+	assert.Nil(t, err)
+	vv := map[string]int{}
+	err = nl.NamedValues(vv)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"multi-a": 1, "multi-b": 0}, vv)
+
+	assert.Nil(t, nl.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, nl.NamedValues(vv))
+}
+
+func TestSplitLines(t *testing.T) {
+	s, err := sim.NewChip(150)
+	require.Nil(t, err)
+	defer s.Close()
+	offsets := make([]int, 150)
+	for i := range offsets {
+		offsets[i] = i
+	}
+
+	ll, err := gpiocdev.RequestLines(s.ChipName(), offsets,
+		gpiocdev.AsOutput(1, 1),
+		gpiocdev.WithLines([]int{100}, gpiocdev.AsOutput(1)))
+	require.Nil(t, err)
+	checkLevel(t, s, 0, 1)
+	checkLevel(t, s, 1, 1)
+	checkLevel(t, s, 64, 0)
+	checkLevel(t, s, 100, 1)
+
+	vv := make([]int, 150)
+	vv[70] = 1
+	vv[149] = 1
+	err = ll.SetValues(vv)
+	assert.Nil(t, err)
+	checkLevel(t, s, 0, 0)
+	checkLevel(t, s, 70, 1)
+	checkLevel(t, s, 149, 1)
+
+	rv := make([]int, 150)
+	err = ll.Values(rv)
+	assert.Nil(t, err)
+	assert.Equal(t, vv, rv)
+
+	// short values
+	err = ll.SetValues([]int{1})
+	assert.Nil(t, err)
+	checkLevel(t, s, 0, 1)
+	checkLevel(t, s, 70, 0)
+	rv = make([]int, 100)
+	err = ll.Values(rv)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rv[0])
+	assert.Equal(t, 0, rv[99])
+
+	err = ll.Reconfigure(gpiocdev.WithLines([]int{63, 64, 149}, gpiocdev.AsActiveLow))
+	assert.Nil(t, err)
+	checkLevel(t, s, 63, 1)
+	checkLevel(t, s, 64, 1)
+	checkLevel(t, s, 149, 1)
+	checkLevel(t, s, 65, 0)
+	assert.Nil(t, ll.Close())
+
+	// lines released
+	l, err := gpiocdev.RequestLine(s.ChipName(), 149)
+	assert.Nil(t, err)
+	l.Close()
+
+	// mixed, with the first chunk all inputs
+	ll, err = gpiocdev.RequestLines(s.ChipName(), offsets,
+		gpiocdev.AsInput,
+		gpiocdev.WithLines([]int{100}, gpiocdev.AsOutput(0)))
+	require.Nil(t, err)
+	for i := range vv {
+		vv[i] = 1
+	}
+	err = ll.SetValues(vv)
+	assert.Nil(t, err)
+	checkLevel(t, s, 0, 0)
+	checkLevel(t, s, 100, 1)
+	assert.Nil(t, ll.Close())
+
+	// events
+	ech := make(chan gpiocdev.LineEvent, 4)
+	ll, err = gpiocdev.RequestLines(s.ChipName(), offsets,
+		gpiocdev.WithRisingEdge,
+		gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {
+			ech <- evt
+		}))
+	require.Nil(t, err)
+	s.Pullup(140)
+	s.Pullup(3)
+	for _, offset := range []int{140, 3} {
+		select {
+		case evt := <-ech:
+			assert.Equal(t, offset, evt.Offset)
+			assert.Equal(t, "", evt.Chip)
+		case <-time.After(time.Second):
+			assert.Fail(t, "timeout waiting for event")
+		}
+	}
+	assert.Nil(t, ll.Err())
+	assert.Nil(t, ll.Close())
+
+	// synchronous events
+	ll, err = gpiocdev.RequestLines(s.ChipName(), offsets, gpiocdev.WithFallingEdge)
+	require.Nil(t, err)
+	defer ll.Close()
+	s.Pulldown(140)
+	s.Pulldown(3)
+	time.Sleep(10 * time.Millisecond)
+	buf := make([]gpiocdev.LineEvent, 4)
+	n, err := ll.ReadEvents(context.Background(), buf)
+	assert.Nil(t, err)
+	require.Equal(t, 2, n)
+	assert.Equal(t, 140, buf[0].Offset)
+	assert.Equal(t, 3, buf[1].Offset)
+	assert.Zero(t, ll.LostEvents())
+}
+
+func checkLevel(t *testing.T, s *sim.Chip, offset, xv int) {
+	t.Helper()
+	v, err := s.Level(offset)
+	assert.Nil(t, err)
+	assert.Equal(t, xv, v)
+}
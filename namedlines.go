@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package gpiocdev
+
+import (
+	"context"
+)
+
+// NamedLines represents a collection of requested lines identified by name.
+//
+// The lines may span several chips, and provide the MultiLines API, where the
+// index of each line is its position in the names provided to the request.
+type NamedLines struct {
+	*MultiLines
+
+	names []string
+
+	// the index of each line, keyed by name.
+	index map[string]int
+}
+
+// RequestLinesByName requests control of a collection of lines identified by
+// name.
+//
+// The names are resolved across all available chips as per FindLines, and
+// the lines on each chip are requested together, as per RequestMultiLines.
+//
+// Where options refer to lines by index, such as the values provided to
+// AsOutput and the offsets provided to WithLines, the index of a line is its
+// position in names, as returned by Index.
+//
+// If granted, control is maintained until the NamedLines are closed.
+func RequestLinesByName(names []string, options ...LineReqOption) (*NamedLines, error) {
+	lines, err := FindLines(names...)
+	if err != nil {
+		return nil, err
+	}
+	ml, err := RequestMultiLines(lines, options...)
+	if err != nil {
+		return nil, err
+	}
+	nl := NamedLines{
+		MultiLines: ml,
+		names:      append([]string(nil), names...),
+		index:      make(map[string]int, len(names)),
+	}
+	for i, name := range names {
+		nl.index[name] = i
+	}
+	return &nl, nil
+}
+
+// RequestLinesByNameContext requests control of a collection of lines
+// identified by name, for the lifetime of the context.
+//
+// If granted, control is maintained until the NamedLines are closed or the
+// context is done, whichever comes first.
+func RequestLinesByNameContext(ctx context.Context, names []string, options ...LineReqOption) (*NamedLines, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	nl, err := RequestLinesByName(names, options...)
+	if err != nil {
+		return nil, err
+	}
+	nl.closeWithContext(ctx)
+	return nl, nil
+}
+
+// Names returns the names of the requested lines.
+func (nl *NamedLines) Names() []string {
+	return nl.names
+}
+
+// Index returns the index of the named line within the collection.
+//
+// Returns false if the line is not part of the collection.
+func (nl *NamedLines) Index(name string) (int, bool) {
+	idx, ok := nl.index[name]
+	return idx, ok
+}
+
+// Line returns the chip and offset of the named line.
+//
+// Returns false if the line is not part of the collection.
+func (nl *NamedLines) Line(name string) (ChipOffset, bool) {
+	idx, ok := nl.index[name]
+	if !ok {
+		return ChipOffset{}, false
+	}
+	return nl.lines[idx], true
+}
+
+// NamedValues returns the current values (active state) of the collection of
+// lines, keyed by name.
+//
+// Values are 0 for inactive and 1 for active.
+//
+// The values of all the lines are added to the map, replacing any existing
+// values.
+func (nl *NamedLines) NamedValues(values map[string]int) error {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	if nl.closed {
+		return ErrClosed
+	}
+	vv := make([]int, len(nl.names))
+	if err := nl.getValues(vv); err != nil {
+		return err
+	}
+	for i, name := range nl.names {
+		values[name] = vv[i]
+	}
+	return nil
+}
+
+// SetNamedValues sets the current active state of the named lines.
+//
+// Only valid for output lines.  As with SetValues, the values of input lines
+// are ignored, and ErrPermissionDenied is returned if none of the lines are
+// outputs.
+//
+// Values are 0 for inactive and 1 for active.
+//
+// Lines not included in values retain their current value.
+//
+// Returns ErrLineNotFound if any of the names is not part of the collection,
+// in which case no lines are set.
+//
+// As with SetValues, the change is not atomic across chips.
+func (nl *NamedLines) SetNamedValues(values map[string]int) error {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	if !nl.anyOutput() {
+		return ErrPermissionDenied
+	}
+	if nl.closed {
+		return ErrClosed
+	}
+	vv := make([]int, len(nl.names))
+	for i := range vv {
+		vv[i] = nl.values[i]
+	}
+	for name, v := range values {
+		idx, ok := nl.index[name]
+		if !ok {
+			return ErrLineNotFound{name}
+		}
+		vv[idx] = v
+	}
+	return nl.setValues(vv)
+}
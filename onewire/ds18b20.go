@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package onewire
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// FamilyDS18B20 is the family code of DS18B20 temperature sensors.
+const FamilyDS18B20 = 0x28
+
+// DS18B20 function commands.
+const (
+	cmdConvert        = 0x44
+	cmdReadScratchpad = 0xbe
+	cmdWriteScratch   = 0x4e
+)
+
+// ErrConversionTimeout indicates a temperature conversion did not complete in
+// the time allowed.
+var ErrConversionTimeout = errors.New("conversion timeout")
+
+// conversionTimeout is the time allowed for a conversion, which is 750ms at
+// 12-bit resolution.
+const conversionTimeout = time.Second
+
+// DS18B20 is a DS18B20 temperature sensor.
+//
+// The sensor must be externally powered, as the master cannot provide the
+// strong pull-up required by parasite power during conversions.
+type DS18B20 struct {
+	bus  Bus
+	addr Address
+}
+
+// NewDS18B20 creates a driver for the DS18B20 with the address on the bus.
+//
+// Returns unix.EINVAL if the address is not of the DS18B20 family.
+func NewDS18B20(bus Bus, addr Address) (*DS18B20, error) {
+	if addr.Family() != FamilyDS18B20 {
+		return nil, unix.EINVAL
+	}
+	return &DS18B20{bus: bus, addr: addr}, nil
+}
+
+// Address returns the address of the sensor.
+func (s *DS18B20) Address() Address {
+	return s.addr
+}
+
+// Temperature performs a conversion and returns the temperature, in degrees
+// Celsius.
+func (s *DS18B20) Temperature() (float64, error) {
+	if err := s.Convert(); err != nil {
+		return 0, err
+	}
+	return s.ReadTemperature()
+}
+
+// Convert starts a temperature conversion and waits for it to complete.
+func (s *DS18B20) Convert() error {
+	if err := Select(s.bus, s.addr); err != nil {
+		return err
+	}
+	return convert(s.bus)
+}
+
+// ConvertAll starts a temperature conversion on all the DS18B20s on the bus
+// and waits for them to complete.
+//
+// The temperatures can then be read with ReadTemperature.
+func ConvertAll(bus Bus) error {
+	if err := Skip(bus); err != nil {
+		return err
+	}
+	return convert(bus)
+}
+
+// convert issues the convert command and polls until the conversion is
+// complete, which is signalled by reading a 1.
+func convert(bus Bus) error {
+	if err := WriteByte(bus, cmdConvert); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(conversionTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		v, err := bus.ReadBit()
+		if err != nil {
+			return err
+		}
+		if v == 1 {
+			return nil
+		}
+	}
+	return ErrConversionTimeout
+}
+
+// ReadTemperature returns the temperature from the most recent conversion,
+// in degrees Celsius.
+//
+// Until the first conversion after power-up the temperature is 85°C.
+func (s *DS18B20) ReadTemperature() (float64, error) {
+	sp, err := s.readScratchpad()
+	if err != nil {
+		return 0, err
+	}
+	raw := int16(uint16(sp[1])<<8 | uint16(sp[0]))
+	// the low bits are undefined at lower resolutions.
+	res := 9 + int(sp[4]>>5)&3
+	raw &^= 1<<(12-res) - 1
+	return float64(raw) / 16, nil
+}
+
+// Resolution returns the resolution of conversions, in bits.
+func (s *DS18B20) Resolution() (int, error) {
+	sp, err := s.readScratchpad()
+	if err != nil {
+		return 0, err
+	}
+	return 9 + int(sp[4]>>5)&3, nil
+}
+
+// SetResolution sets the resolution of conversions, from 9 to 12 bits.
+//
+// Lower resolutions convert faster, from 94ms for 9 bits to 750ms for 12.
+// The resolution is not saved to EEPROM, so reverts on power-up.
+//
+// Returns unix.EINVAL if the resolution is out of range.
+func (s *DS18B20) SetResolution(bits int) error {
+	if bits < 9 || bits > 12 {
+		return unix.EINVAL
+	}
+	sp, err := s.readScratchpad()
+	if err != nil {
+		return err
+	}
+	if err = Select(s.bus, s.addr); err != nil {
+		return err
+	}
+	// TH and TL alarm thresholds are preserved.
+	return Write(s.bus, []byte{cmdWriteScratch, sp[2], sp[3], byte(bits-9)<<5 | 0x1f})
+}
+
+// readScratchpad reads and checks the scratchpad of the sensor.
+func (s *DS18B20) readScratchpad() ([]byte, error) {
+	if err := Select(s.bus, s.addr); err != nil {
+		return nil, err
+	}
+	if err := WriteByte(s.bus, cmdReadScratchpad); err != nil {
+		return nil, err
+	}
+	sp := make([]byte, 9)
+	if err := Read(s.bus, sp); err != nil {
+		return nil, err
+	}
+	// an all zeros scratchpad, such as from a shorted bus, has a valid CRC,
+	// so the fixed bits of the configuration register are checked too.
+	if CRC8(sp) != 0 || sp[4]&0x9f != 0x1f {
+		return nil, ErrCRC
+	}
+	return sp, nil
+}
@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package onewire
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// Standard speed timings.
+//
+// The reset and write 0 pulses, and the recovery between slots, are easily
+// met from user space, as they are minimums.  The short pulses that start
+// write 1 and read slots, and the sampling of read slots, must complete within
+// 15µs of the start of the slot, which requires that the line can be set and
+// read within a few microseconds.  This is usually achievable on a lightly
+// loaded Raspberry Pi 3 or later, but cannot be guaranteed from user space.
+//
+// So the master measures each time critical phase, and returns ErrTiming if
+// the limits were exceeded, rather than risk returning corrupted data.
+const (
+	// the duration of the reset pulse.
+	resetLow = 480 * time.Microsecond
+
+	// the time after the reset pulse to sample the presence pulse.
+	presenceSample = 70 * time.Microsecond
+
+	// the latest the presence pulse may be sampled.
+	presenceLimit = 120 * time.Microsecond
+
+	// the time after the presence sample to complete the reset.
+	resetRecovery = 410 * time.Microsecond
+
+	// the duration of a write 0 pulse.
+	write0Low = 60 * time.Microsecond
+
+	// the maximum duration of a write 0 pulse.
+	write0Limit = 120 * time.Microsecond
+
+	// the latest a write 1 pulse may end, or a read slot may be sampled.
+	slotLimit = 15 * time.Microsecond
+
+	// the duration of a slot, including recovery.
+	slot = 65 * time.Microsecond
+)
+
+var (
+	// ErrTiming indicates a time critical phase of a slot took too long,
+	// so the data transferred may be corrupted.
+	//
+	// The transaction should be restarted from a reset.
+	ErrTiming = errors.New("timing failure")
+
+	// ErrBusLow indicates the bus is held low, such as by a short circuit
+	// or a missing pull-up.
+	ErrBusLow = errors.New("bus held low")
+)
+
+// Master is a bit-banged 1-Wire bus master using a single open-drain line.
+//
+// The master performs individual slots.  Callers must serialise complete
+// transactions, from reset to the end of the data transfer.
+type Master struct {
+	l *gpiocdev.Line
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	closed bool
+}
+
+var _ Bus = (*Master)(nil)
+
+// Option defines the interface required to provide an option to New.
+type Option interface {
+	applyOption(*masterOptions)
+}
+
+type masterOptions struct {
+	reqOpts []gpiocdev.LineReqOption
+}
+
+// RequestOption provides options for the line request.
+type RequestOption []gpiocdev.LineReqOption
+
+// WithRequestOptions provides options for the request of the line, such as
+// consumer or bias.
+//
+// The line is pulled up by default, but the internal pull-up is weak, so an
+// external pull-up, typically 4.7kΩ, should be fitted.
+//
+// Direction, drive and active level options are overridden by the master.
+func WithRequestOptions(options ...gpiocdev.LineReqOption) RequestOption {
+	return RequestOption(options)
+}
+
+func (o RequestOption) applyOption(opts *masterOptions) {
+	opts.reqOpts = append(opts.reqOpts, o...)
+}
+
+// New requests the line from the chip and creates a master for the bus.
+func New(chip string, offset int, options ...Option) (*Master, error) {
+	var opts masterOptions
+	for _, option := range options {
+		option.applyOption(&opts)
+	}
+	reqOpts := []gpiocdev.LineReqOption{gpiocdev.WithPullUp}
+	reqOpts = append(reqOpts, opts.reqOpts...)
+	reqOpts = append(reqOpts,
+		gpiocdev.AsActiveHigh,
+		gpiocdev.AsOpenDrain,
+		gpiocdev.AsOutput(1))
+	l, err := gpiocdev.RequestLine(chip, offset, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Master{l: l}, nil
+}
+
+// Close releases the line.
+func (m *Master) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return gpiocdev.ErrClosed
+	}
+	m.closed = true
+	return m.l.Close()
+}
+
+// Reset issues a reset pulse and returns true if any device responded with a
+// presence pulse.
+//
+// Returns ErrBusLow if the bus does not return high after the reset, and
+// ErrTiming if the presence pulse could not be sampled in time.
+func (m *Master) Reset() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return false, gpiocdev.ErrClosed
+	}
+	if err := m.l.SetValue(0); err != nil {
+		return false, err
+	}
+	spin(time.Now().Add(resetLow))
+	start := time.Now()
+	if err := m.l.SetValue(1); err != nil {
+		return false, err
+	}
+	spin(time.Now().Add(presenceSample))
+	v, err := m.l.Value()
+	if err != nil {
+		return false, err
+	}
+	late := time.Since(start) > presenceLimit
+	spin(time.Now().Add(resetRecovery))
+	idle, err := m.l.Value()
+	if err != nil {
+		return false, err
+	}
+	if idle == 0 {
+		return false, ErrBusLow
+	}
+	if late {
+		return false, ErrTiming
+	}
+	return v == 0, nil
+}
+
+// WriteBit writes a bit to the bus.
+//
+// Returns ErrTiming if the slot timing could not be met.
+func (m *Master) WriteBit(bit int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return gpiocdev.ErrClosed
+	}
+	start := time.Now()
+	if err := m.l.SetValue(0); err != nil {
+		return err
+	}
+	limit := slotLimit
+	if bit == 0 {
+		limit = write0Limit
+		spin(start.Add(write0Low))
+	}
+	if err := m.l.SetValue(1); err != nil {
+		return err
+	}
+	late := time.Since(start) > limit
+	spin(start.Add(slot))
+	if late {
+		return ErrTiming
+	}
+	return nil
+}
+
+// ReadBit reads a bit from the bus.
+//
+// Returns ErrTiming if the slot timing could not be met.
+func (m *Master) ReadBit() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return 0, gpiocdev.ErrClosed
+	}
+	start := time.Now()
+	if err := m.l.SetValue(0); err != nil {
+		return 0, err
+	}
+	if err := m.l.SetValue(1); err != nil {
+		return 0, err
+	}
+	v, err := m.l.Value()
+	if err != nil {
+		return 0, err
+	}
+	late := time.Since(start) > slotLimit
+	spin(start.Add(slot))
+	if late {
+		return 0, ErrTiming
+	}
+	return v, nil
+}
+
+// spin busy waits until the deadline.
+//
+// The slots are too short and time critical to yield to the scheduler.
+func spin(deadline time.Time) {
+	for time.Now().Before(deadline) {
+	}
+}
@@ -0,0 +1,268 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package onewire provides a Dallas 1-Wire bus master using a single GPIO
+// line, along with the ROM search and a driver for DS18B20 temperature
+// sensors.
+//
+// The protocol functions and the DS18B20 driver operate on the Bus interface,
+// so they may be used with other bus masters.
+//
+//	m, _ := onewire.New("gpiochip0", 4)
+//	defer m.Close()
+//	addrs, _ := onewire.Search(m)
+//	for _, addr := range addrs {
+//		if addr.Family() == onewire.FamilyDS18B20 {
+//			s, _ := onewire.NewDS18B20(m, addr)
+//			t, err := s.Temperature()
+//			...
+//		}
+//	}
+package onewire
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Bus is the interface to a 1-Wire bus master.
+type Bus interface {
+	// Reset issues a reset pulse and returns true if any device responded
+	// with a presence pulse.
+	Reset() (bool, error)
+
+	// WriteBit writes a bit to the bus.
+	WriteBit(bit int) error
+
+	// ReadBit reads a bit from the bus.
+	ReadBit() (int, error)
+}
+
+// Address is the 64-bit ROM code that uniquely identifies a device.
+//
+// The least significant byte is the family code, and the most significant
+// byte is the CRC of the other bytes.
+type Address uint64
+
+// Family returns the family code of the device.
+func (a Address) Family() byte {
+	return byte(a)
+}
+
+// Bytes returns the ROM code in the order it is transferred on the bus.
+func (a Address) Bytes() []byte {
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = byte(a >> (8 * i))
+	}
+	return b
+}
+
+// String returns the address in the family-serial form used by the Linux
+// w1 subsystem, e.g. 28-0316a2794cff.
+func (a Address) String() string {
+	return fmt.Sprintf("%02x-%012x", a.Family(), uint64(a>>8)&0xffffffffffff)
+}
+
+// ROM commands.
+const (
+	cmdSearchROM = 0xf0
+	cmdMatchROM  = 0x55
+	cmdSkipROM   = 0xcc
+	cmdAlarm     = 0xec
+)
+
+var (
+	// ErrNoPresence indicates no device responded to a reset.
+	ErrNoPresence = errors.New("no device present")
+
+	// ErrCRC indicates data read from a device failed its CRC, or other
+	// integrity, check.
+	ErrCRC = errors.New("CRC mismatch")
+
+	// ErrSearch indicates the devices stopped responding during a ROM
+	// search.
+	ErrSearch = errors.New("search failed")
+)
+
+// CRC8 returns the Dallas/Maxim CRC8 of the data.
+//
+// The CRC of data that includes its own CRC as the last byte is 0.
+func CRC8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		for i := 0; i < 8; i++ {
+			mix := (crc ^ b) & 1
+			crc >>= 1
+			if mix != 0 {
+				crc ^= 0x8c
+			}
+			b >>= 1
+		}
+	}
+	return crc
+}
+
+// WriteByte writes a byte to the bus, LSB first.
+func WriteByte(b Bus, v byte) error {
+	for i := 0; i < 8; i++ {
+		if err := b.WriteBit(int(v>>i) & 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadByte reads a byte from the bus, LSB first.
+func ReadByte(b Bus) (byte, error) {
+	var v byte
+	for i := 0; i < 8; i++ {
+		bit, err := b.ReadBit()
+		if err != nil {
+			return 0, err
+		}
+		v |= byte(bit) << i
+	}
+	return v, nil
+}
+
+// Write writes the bytes to the bus.
+func Write(b Bus, p []byte) error {
+	for _, v := range p {
+		if err := WriteByte(b, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read reads len(p) bytes from the bus into p.
+func Read(b Bus, p []byte) error {
+	for i := range p {
+		v, err := ReadByte(b)
+		if err != nil {
+			return err
+		}
+		p[i] = v
+	}
+	return nil
+}
+
+// Select resets the bus and selects the device with the address, so it will
+// respond to the following function command.
+//
+// Returns ErrNoPresence if no device responds to the reset.
+func Select(b Bus, addr Address) error {
+	if err := reset(b); err != nil {
+		return err
+	}
+	if err := WriteByte(b, cmdMatchROM); err != nil {
+		return err
+	}
+	return Write(b, addr.Bytes())
+}
+
+// Skip resets the bus and selects all devices, so they will all respond to
+// the following function command.
+//
+// Returns ErrNoPresence if no device responds to the reset.
+func Skip(b Bus) error {
+	if err := reset(b); err != nil {
+		return err
+	}
+	return WriteByte(b, cmdSkipROM)
+}
+
+// Search returns the addresses of all the devices on the bus.
+func Search(b Bus) ([]Address, error) {
+	return search(b, cmdSearchROM)
+}
+
+// AlarmSearch returns the addresses of the devices on the bus with an alarm
+// condition.
+func AlarmSearch(b Bus) ([]Address, error) {
+	return search(b, cmdAlarm)
+}
+
+// reset resets the bus and checks a device is present.
+func reset(b Bus) error {
+	present, err := b.Reset()
+	if err != nil {
+		return err
+	}
+	if !present {
+		return ErrNoPresence
+	}
+	return nil
+}
+
+// search performs the ROM search described in Maxim application note 187.
+//
+// Each pass walks the tree of ROM codes, taking the 0 branch at each new
+// discrepancy, and the 1 branch at the last discrepancy of the previous pass,
+// so each pass finds one device until no discrepancies remain.
+func search(b Bus, cmd byte) ([]Address, error) {
+	var addrs []Address
+	var last Address
+	// the bit index of the last discrepancy where the 0 branch was taken.
+	lastDiscrepancy := -1
+	for {
+		present, err := b.Reset()
+		if err != nil {
+			return addrs, err
+		}
+		if !present {
+			return addrs, nil
+		}
+		if err = WriteByte(b, cmd); err != nil {
+			return addrs, err
+		}
+		var addr Address
+		discrepancy := -1
+		for i := 0; i < 64; i++ {
+			id, err := b.ReadBit()
+			if err != nil {
+				return addrs, err
+			}
+			cmp, err := b.ReadBit()
+			if err != nil {
+				return addrs, err
+			}
+			dir := id
+			switch {
+			case id == 1 && cmp == 1:
+				if cmd == cmdAlarm && i == 0 && len(addrs) == 0 {
+					// no devices in alarm.
+					return nil, nil
+				}
+				return addrs, ErrSearch
+			case id == 0 && cmp == 0:
+				switch {
+				case i < lastDiscrepancy:
+					dir = int(last>>i) & 1
+				case i == lastDiscrepancy:
+					dir = 1
+				default:
+					dir = 0
+				}
+				if dir == 0 {
+					discrepancy = i
+				}
+			}
+			addr |= Address(dir) << i
+			if err = b.WriteBit(dir); err != nil {
+				return addrs, err
+			}
+		}
+		if CRC8(addr.Bytes()) != 0 {
+			return addrs, ErrCRC
+		}
+		addrs = append(addrs, addr)
+		if discrepancy == -1 {
+			return addrs, nil
+		}
+		last = addr
+		lastDiscrepancy = discrepancy
+	}
+}
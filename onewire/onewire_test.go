@@ -0,0 +1,369 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package onewire_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/onewire"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"golang.org/x/sys/unix"
+)
+
+func TestCRC8(t *testing.T) {
+	// the example from Maxim application note 27.
+	data := []byte{0x02, 0x1c, 0xb8, 0x01, 0x00, 0x00, 0x00}
+	assert.Equal(t, byte(0xa2), onewire.CRC8(data))
+	assert.Equal(t, byte(0), onewire.CRC8(append(data, 0xa2)))
+	assert.Equal(t, byte(0), onewire.CRC8(nil))
+}
+
+func TestAddress(t *testing.T) {
+	a := address(0x28, 0x0316a2794cff)
+	assert.Equal(t, byte(0x28), a.Family())
+	assert.Equal(t, "28-0316a2794cff", a.String())
+	b := a.Bytes()
+	assert.Equal(t, 8, len(b))
+	assert.Equal(t, byte(0x28), b[0])
+	assert.Equal(t, byte(0xff), b[1])
+	assert.Equal(t, byte(0), onewire.CRC8(b))
+}
+
+func TestSearch(t *testing.T) {
+	b := &bus{}
+	addrs, err := onewire.Search(b)
+	assert.Nil(t, err)
+	assert.Empty(t, addrs)
+	assert.Equal(t, onewire.ErrNoPresence, onewire.Select(b, address(0x28, 1)))
+	assert.Equal(t, onewire.ErrNoPresence, onewire.Skip(b))
+
+	xaddrs := []onewire.Address{
+		address(0x28, 1),
+		address(0x28, 2),
+		address(0x28, 3),
+		address(0x28, 0x800000000000),
+		address(0x10, 1),
+	}
+	for _, a := range xaddrs {
+		b.devices = append(b.devices, newDevice(a, 20))
+	}
+	addrs, err = onewire.Search(b)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, xaddrs, addrs)
+
+	addrs, err = onewire.AlarmSearch(b)
+	assert.Nil(t, err)
+	assert.Empty(t, addrs)
+
+	b.devices[1].alarm = true
+	b.devices[4].alarm = true
+	addrs, err = onewire.AlarmSearch(b)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []onewire.Address{xaddrs[1], xaddrs[4]}, addrs)
+}
+
+func TestDS18B20(t *testing.T) {
+	_, err := onewire.NewDS18B20(&bus{}, address(0x10, 1))
+	assert.Equal(t, unix.EINVAL, err)
+
+	d1 := newDevice(address(onewire.FamilyDS18B20, 1), 25.0625)
+	d2 := newDevice(address(onewire.FamilyDS18B20, 2), -10.125)
+	b := &bus{devices: []*device{d1, d2}}
+
+	s1, err := onewire.NewDS18B20(b, d1.addr)
+	require.Nil(t, err)
+	assert.Equal(t, d1.addr, s1.Address())
+	s2, err := onewire.NewDS18B20(b, d2.addr)
+	require.Nil(t, err)
+
+	// power-up value
+	temp, err := s1.ReadTemperature()
+	assert.Nil(t, err)
+	assert.Equal(t, 85.0, temp)
+
+	temp, err = s1.Temperature()
+	assert.Nil(t, err)
+	assert.Equal(t, 25.0625, temp)
+	temp, err = s2.ReadTemperature()
+	assert.Nil(t, err)
+	assert.Equal(t, 85.0, temp)
+
+	assert.Nil(t, onewire.ConvertAll(b))
+	temp, err = s2.ReadTemperature()
+	assert.Nil(t, err)
+	assert.Equal(t, -10.125, temp)
+
+	res, err := s1.Resolution()
+	assert.Nil(t, err)
+	assert.Equal(t, 12, res)
+	assert.Equal(t, unix.EINVAL, s1.SetResolution(8))
+	assert.Equal(t, unix.EINVAL, s1.SetResolution(13))
+	assert.Nil(t, s1.SetResolution(9))
+	res, err = s1.Resolution()
+	assert.Nil(t, err)
+	assert.Equal(t, 9, res)
+	assert.Equal(t, []byte{0x4b, 0x46}, d1.sp[2:4], "alarm thresholds")
+	temp, err = s1.Temperature()
+	assert.Nil(t, err)
+	assert.Equal(t, 25.0, temp)
+	res, err = s2.Resolution()
+	assert.Nil(t, err)
+	assert.Equal(t, 12, res)
+
+	d2.corrupt = true
+	_, err = s2.ReadTemperature()
+	assert.Equal(t, onewire.ErrCRC, err)
+
+	d1.busy = 1000
+	_, err = s1.Temperature()
+	assert.Equal(t, onewire.ErrConversionTimeout, err)
+}
+
+func TestMaster(t *testing.T) {
+	s, err := sim.NewChip(2)
+	require.Nil(t, err)
+	defer s.Close()
+
+	_, err = onewire.New(s.ChipName(), 2)
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	m, err := onewire.New(s.ChipName(), 1)
+	require.Nil(t, err)
+	v, err := s.Level(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	// the timing of the sim cannot be guaranteed, so ErrTiming is acceptable.
+	present, err := m.Reset()
+	if err != onewire.ErrTiming {
+		assert.Nil(t, err)
+	}
+	assert.False(t, present)
+	bit, err := m.ReadBit()
+	if err != onewire.ErrTiming {
+		assert.Nil(t, err)
+		assert.Equal(t, 1, bit)
+	}
+	err = m.WriteBit(0)
+	if err != onewire.ErrTiming {
+		assert.Nil(t, err)
+	}
+
+	s.SetPull(1, 0)
+	_, err = m.Reset()
+	assert.Equal(t, onewire.ErrBusLow, err)
+
+	assert.Nil(t, m.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, m.Close())
+	_, err = m.Reset()
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+	assert.Equal(t, gpiocdev.ErrClosed, m.WriteBit(1))
+	_, err = m.ReadBit()
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+}
+
+// address returns the address with the family and serial number and a valid
+// CRC.
+func address(family byte, serial uint64) onewire.Address {
+	a := onewire.Address(serial<<8 | uint64(family))
+	crc := onewire.CRC8(a.Bytes()[:7])
+	return a | onewire.Address(crc)<<56
+}
+
+// bus emulates a bus with a set of devices, where reads are the wired-AND of
+// the devices.
+type bus struct {
+	devices []*device
+}
+
+func (b *bus) Reset() (bool, error) {
+	for _, d := range b.devices {
+		d.reset()
+	}
+	return len(b.devices) > 0, nil
+}
+
+func (b *bus) WriteBit(bit int) error {
+	for _, d := range b.devices {
+		d.write(bit)
+	}
+	return nil
+}
+
+func (b *bus) ReadBit() (int, error) {
+	v := 1
+	for _, d := range b.devices {
+		v &= d.read()
+	}
+	return v, nil
+}
+
+// device states
+const (
+	stateROM = iota
+	stateSearch
+	stateMatch
+	stateFunction
+	stateWriteScratchpad
+	stateIdle
+)
+
+// device emulates a DS18B20, or any other device for ROM commands.
+type device struct {
+	addr  onewire.Address
+	temp  float64
+	alarm bool
+	sp    []byte
+
+	// corrupt corrupts the CRC of the scratchpad.
+	corrupt bool
+
+	// the number of polls for which a conversion remains busy.
+	busy int
+
+	state int
+
+	// the received bits
+	rx  uint64
+	nrx int
+
+	// bits being transmitted.
+	tx []int
+
+	// the search phase, 0 for the id bit, 1 for its complement, and 2 for
+	// the master's direction.
+	phase int
+
+	converting int
+}
+
+func newDevice(addr onewire.Address, temp float64) *device {
+	d := &device{
+		addr: addr,
+		temp: temp,
+		sp:   []byte{0x50, 0x05, 0x4b, 0x46, 0x7f, 0xff, 0x0c, 0x10, 0},
+	}
+	d.updateCRC()
+	return d
+}
+
+func (d *device) updateCRC() {
+	d.sp[8] = onewire.CRC8(d.sp[:8])
+	if d.corrupt {
+		d.sp[8]++
+	}
+}
+
+func (d *device) reset() {
+	d.state = stateROM
+	d.rx = 0
+	d.nrx = 0
+	d.tx = nil
+	d.phase = 0
+	d.converting = 0
+}
+
+func (d *device) read() int {
+	switch {
+	case d.state == stateSearch:
+		bit := int(d.addr>>d.nrx) & 1
+		if d.phase == 1 {
+			bit ^= 1
+		}
+		d.phase++
+		return bit
+	case len(d.tx) > 0:
+		bit := d.tx[0]
+		d.tx = d.tx[1:]
+		return bit
+	case d.converting > 0:
+		d.converting--
+		return 0
+	}
+	return 1
+}
+
+func (d *device) write(bit int) {
+	switch d.state {
+	case stateIdle:
+		return
+	case stateSearch:
+		if bit != int(d.addr>>d.nrx)&1 {
+			d.state = stateIdle
+			return
+		}
+		d.phase = 0
+		d.nrx++
+		if d.nrx == 64 {
+			d.state = stateFunction
+			d.nrx = 0
+		}
+		return
+	}
+	d.rx |= uint64(bit) << d.nrx
+	d.nrx++
+	switch {
+	case d.state == stateMatch && d.nrx == 64:
+		d.state = stateFunction
+		if onewire.Address(d.rx) != d.addr {
+			d.state = stateIdle
+		}
+	case d.state == stateWriteScratchpad && d.nrx == 24:
+		copy(d.sp[2:5], []byte{byte(d.rx), byte(d.rx >> 8), byte(d.rx >> 16)})
+		d.updateCRC()
+		d.state = stateIdle
+	case d.state == stateROM && d.nrx == 8:
+		d.command(byte(d.rx))
+	case d.state == stateFunction && d.nrx == 8:
+		d.function(byte(d.rx))
+	default:
+		return
+	}
+	d.rx = 0
+	d.nrx = 0
+}
+
+func (d *device) command(cmd byte) {
+	switch cmd {
+	case 0xf0:
+		d.state = stateSearch
+	case 0xec:
+		d.state = stateIdle
+		if d.alarm {
+			d.state = stateSearch
+		}
+	case 0x55:
+		d.state = stateMatch
+	case 0xcc:
+		d.state = stateFunction
+	default:
+		d.state = stateIdle
+	}
+}
+
+func (d *device) function(cmd byte) {
+	d.state = stateIdle
+	switch cmd {
+	case 0x44:
+		// the low bits are set regardless of resolution.
+		raw := uint16(int16(d.temp * 16))
+		d.sp[0] = byte(raw)
+		d.sp[1] = byte(raw >> 8)
+		d.updateCRC()
+		d.converting = 3 + d.busy
+	case 0xbe:
+		d.updateCRC()
+		for _, v := range d.sp {
+			for i := 0; i < 8; i++ {
+				d.tx = append(d.tx, int(v>>i)&1)
+			}
+		}
+	case 0x4e:
+		d.state = stateWriteScratchpad
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/warthog618/go-gpiocdev/uapi"
+	"golang.org/x/sys/unix"
 )
 
 // ChipOption defines the interface required to provide a Chip option.
@@ -21,6 +22,9 @@ type ChipOptions struct {
 	config   LineConfig
 	abi      int
 	eh       EventHandler
+	bh       EventBatchHandler
+	errh     ErrorHandler
+	loop     *EventLoop
 }
 
 // ConsumerOption defines the consumer label for a line.
@@ -66,7 +70,12 @@ type lineReqOptions struct {
 	consumer        string
 	abi             int
 	eh              EventHandler
+	bh              EventBatchHandler
+	errh            ErrorHandler
+	loop            *EventLoop
+	eventChan       *EventChannelOption
 	eventBufferSize int
+	overflowEvents  bool
 }
 
 // lineConfigOptions contains the configuration options for a Line(s) reconfigure.
@@ -90,6 +99,26 @@ func (lco *lineConfigOptions) lineConfig(offset int) *LineConfig {
 	return lc
 }
 
+// subset returns the configuration for a subset of the lines, where the
+// lines are identified by their index into lco.offsets, and are renumbered to
+// the corresponding offsets.
+func (lco lineConfigOptions) subset(idxs []int, offsets []int) lineConfigOptions {
+	sub := lineConfigOptions{
+		offsets: offsets,
+		values:  map[int]int{},
+		defCfg:  lco.defCfg,
+	}
+	for i, idx := range idxs {
+		if v, ok := lco.values[idx]; ok {
+			sub.values[offsets[i]] = v
+		}
+		if lc := lco.lineCfg[idx]; lc != nil {
+			*sub.lineConfig(offsets[i]) = *lc
+		}
+	}
+	return sub
+}
+
 func (lco lineConfigOptions) outputValues() uapi.OutputValues {
 	ov := uapi.LineBitmap(0)
 	for idx, val := range lco.offsets {
@@ -181,6 +210,32 @@ func (lco lineConfigOptions) toULineConfig() (ulc uapi.LineConfig, err error) {
 // EventHandler is a receiver for line events.
 type EventHandler func(LineEvent)
 
+// batchHandler returns an EventBatchHandler that forwards each event in the
+// batch to the EventHandler.
+func (eh EventHandler) batchHandler() EventBatchHandler {
+	return func(evts []LineEvent) {
+		for _, evt := range evts {
+			eh(evt)
+		}
+	}
+}
+
+// EventBatchHandler is a receiver for batches of line events.
+//
+// The batch is only valid for the duration of the call, so the handler must
+// copy any events it wishes to retain.
+type EventBatchHandler func([]LineEvent)
+
+// eventHandler returns an EventHandler that forwards each event to the
+// EventBatchHandler as a batch of one.
+func (bh EventBatchHandler) eventHandler() EventHandler {
+	var batch [1]LineEvent
+	return func(evt LineEvent) {
+		batch[0] = evt
+		bh(batch[:])
+	}
+}
+
 // AsIsOption indicates the line direction should be left as is.
 type AsIsOption int
 
@@ -393,10 +448,13 @@ const WithPullUp = LineBiasPullUp
 
 func (o EventHandler) applyChipOption(c *ChipOptions) {
 	c.eh = o
+	c.bh = nil
 }
 
 func (o EventHandler) applyLineReqOption(lro *lineReqOptions) {
 	lro.eh = o
+	lro.bh = nil
+	lro.eventChan = nil
 }
 
 // WithEventHandler indicates that a line will generate events when its active
@@ -412,10 +470,136 @@ func (o EventHandler) applyLineReqOption(lro *lineReqOptions) {
 // Note that calling Close on the requested line from within the event handler
 // will result in deadlock, as the Close waits for the event handler to
 // return.  Therefore the Close must be called from a different goroutine.
+//
+// This option overrides and clears any previous WithEventBatchHandler or
+// WithEventChannel options.
 func WithEventHandler(e EventHandler) EventHandler {
 	return e
 }
 
+func (o EventBatchHandler) applyChipOption(c *ChipOptions) {
+	c.eh = nil
+	c.bh = o
+}
+
+func (o EventBatchHandler) applyLineReqOption(lro *lineReqOptions) {
+	lro.eh = nil
+	lro.bh = o
+	lro.eventChan = nil
+}
+
+// WithEventBatchHandler indicates that line events are to be forwarded to the
+// provided handler function in batches.
+//
+// Events are read from the kernel in batches, rather than one at a time, and
+// each batch read is passed to the handler in a single call.  This reduces the
+// overhead of handling bursts of events.
+//
+// The batch passed to the handler is only valid for the duration of the call,
+// as the underlying buffer is reused for subsequent batches, so the handler
+// must copy any events it wishes to retain.
+//
+// The same constraints as WithEventHandler apply - the handler is called
+// serially and should return as soon as possible, and calling Close on the
+// requested line from within the handler will result in deadlock.
+//
+// With uAPI v1 each batch contains a single event.
+//
+// This option overrides and clears any previous WithEventHandler or
+// WithEventChannel options.
+func WithEventBatchHandler(bh EventBatchHandler) EventBatchHandler {
+	return bh
+}
+
+// ErrorHandler is a receiver for errors that stop the delivery of events.
+type ErrorHandler func(error)
+
+func (o ErrorHandler) applyChipOption(c *ChipOptions) {
+	c.errh = o
+}
+
+func (o ErrorHandler) applyLineReqOption(lro *lineReqOptions) {
+	lro.errh = o
+}
+
+// WithErrorHandler provides a handler for errors that stop the delivery of
+// events.
+//
+// Such errors occur when reading events from the kernel fails, such as when
+// the chip is removed, in which case the error is ENODEV.
+// Once the error occurs no further events are delivered, and the error is
+// also available from the Err method of the requested line(s) or chip.
+//
+// When applied to a chip it provides the handler for errors reading line info
+// changes, and the default handler for lines requested from the chip.
+//
+// The handler is called from the goroutine that delivers the events, so the
+// same constraints apply as for the event handler.
+func WithErrorHandler(eh ErrorHandler) ErrorHandler {
+	return eh
+}
+
+// EventChannelPolicy determines the behaviour when an event is to be delivered
+// to an event channel that is full.
+type EventChannelPolicy int
+
+const (
+	// EventChannelBlock indicates that delivery of the event blocks until the
+	// channel has room for it.
+	//
+	// While blocked no further events are read from the kernel, so the kernel
+	// event buffer may overflow.
+	EventChannelBlock EventChannelPolicy = iota
+
+	// EventChannelDropOldest indicates that the oldest event in the channel is
+	// discarded to make room for the new event.
+	EventChannelDropOldest
+
+	// EventChannelDropNewest indicates that the new event is discarded.
+	EventChannelDropNewest
+)
+
+// EventChannelOption specifies that line events be delivered via a channel.
+type EventChannelOption struct {
+	size   int
+	policy EventChannelPolicy
+}
+
+func (o EventChannelOption) applyLineReqOption(lro *lineReqOptions) {
+	lro.eh = nil
+	lro.bh = nil
+	lro.eventChan = &o
+}
+
+// WithEventChannel indicates that line events are to be delivered via a
+// channel, rather than to an event handler.
+//
+// The channel is created when the lines are requested and is available from
+// the Events method of the requested line(s).  The channel is closed when the
+// line(s) are closed, or when the delivery of events is stopped by an error,
+// which is then available from the Err method of the requested line(s).
+//
+// The size specifies the channel buffer size, and the policy determines the
+// behaviour when an event is to be delivered and that buffer is full.
+// The size may be zero, for an unbuffered channel, only with the
+// EventChannelBlock policy, as the drop policies require room to buffer at
+// least one event.  The request fails with unix.EINVAL if the size is
+// negative, or zero with a drop policy.
+//
+// This option overrides and clears any previous WithEventHandler or
+// WithEventBatchHandler options.
+func WithEventChannel(size int, policy EventChannelPolicy) EventChannelOption {
+	return EventChannelOption{size: size, policy: policy}
+}
+
+// validate checks the channel can be created and used with the policy.
+func (o EventChannelOption) validate() error {
+	if o.size < 0 || (o.size == 0 && o.policy != EventChannelBlock) {
+		return unix.EINVAL
+	}
+	return nil
+}
+
 func (o LineEdge) applyLineConfig(lc *LineConfig) {
 	lc.EdgeDetection = o
 	lc.Direction = LineDirectionInput
@@ -639,6 +823,31 @@ func (o EventBufferSizeOption) applyLineReqOption(lro *lineReqOptions) {
 	lro.eventBufferSize = int(o)
 }
 
+const (
+	// the minimum number of events read from the kernel in one read.
+	minEventBatchSize = 16
+
+	// the maximum number of events read from the kernel in one read.
+	maxEventBatchSize = 256
+)
+
+// eventBatchSize returns the number of events to read from the kernel in one
+// read, sized to match the kernel event buffer.
+func (lro *lineReqOptions) eventBatchSize() int {
+	size := lro.eventBufferSize
+	if size == 0 {
+		// kernel default
+		size = len(lro.offsets) * 16
+	}
+	if size < minEventBatchSize {
+		return minEventBatchSize
+	}
+	if size > maxEventBatchSize {
+		return maxEventBatchSize
+	}
+	return size
+}
+
 // WithEventBufferSize suggests a minimum number of events the kernel will
 // buffer for the line request.
 //
@@ -652,3 +861,25 @@ func (o EventBufferSizeOption) applyLineReqOption(lro *lineReqOptions) {
 func WithEventBufferSize(size int) EventBufferSizeOption {
 	return EventBufferSizeOption(size)
 }
+
+// OverflowEventsOption indicates that lost events are to be reported as
+// LineEventOverflow events.
+type OverflowEventsOption bool
+
+func (o OverflowEventsOption) applyLineReqOption(lro *lineReqOptions) {
+	lro.overflowEvents = bool(o)
+}
+
+// WithOverflowEvents indicates that events lost due to the kernel event buffer
+// overflowing are to be reported to the event handler or event channel as
+// LineEventOverflow events.
+//
+// Lost events are detected from gaps in the sequence numbers of the events
+// subsequently received, so the overflow event for a line is delivered
+// immediately before the next event received for that line.
+//
+// Lost events are always counted, independent of this option, and that count
+// is available from LostEvents.
+//
+// Requires uAPI v2.
+const WithOverflowEvents = OverflowEventsOption(true)
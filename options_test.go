@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/sim"
 	"github.com/warthog618/go-gpiocdev/uapi"
 	"github.com/warthog618/go-gpiosim"
 	"golang.org/x/sys/unix"
@@ -732,6 +733,493 @@ func TestWithEventHandler(t *testing.T) {
 	waitNoEvent(t, ich)
 }
 
+func TestWithEventBatchHandler(t *testing.T) {
+	offset := 4
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(t, err)
+	defer s.Close()
+
+	s.SetPull(offset, 0)
+
+	ich := make(chan gpiocdev.LineEvent, 10)
+	bh := func(evts []gpiocdev.LineEvent) {
+		for _, evt := range evts {
+			ich <- evt
+		}
+	}
+
+	// via chip options
+	chipOpts := []gpiocdev.ChipOption{gpiocdev.WithEventBatchHandler(bh)}
+	if kernelAbiVersion != 0 {
+		chipOpts = append(chipOpts, gpiocdev.ABIVersionOption(kernelAbiVersion))
+	}
+	c := getChip(t, s.DevPath(), chipOpts...)
+	defer c.Close()
+
+	r, err := c.RequestLine(offset, gpiocdev.WithBothEdges)
+	require.Nil(t, err)
+	require.NotNil(t, r)
+	evtSeqno = 0
+	waitNoEvent(t, ich)
+	s.SetPull(offset, 1)
+	waitEvent(t, ich, nextEvent(r, 1))
+	s.SetPull(offset, 0)
+	waitEvent(t, ich, nextEvent(r, 0))
+	waitNoEvent(t, ich)
+	r.Close()
+
+	// via line options - with batching
+	blockCh := make(chan struct{})
+	bch := make(chan []gpiocdev.LineEvent, 10)
+	r, err = c.RequestLine(offset,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventBatchHandler(func(evts []gpiocdev.LineEvent) {
+			<-blockCh
+			bch <- append([]gpiocdev.LineEvent(nil), evts...)
+		}))
+	require.Nil(t, err)
+	require.NotNil(t, r)
+	defer r.Close()
+	evtSeqno = 0
+	s.SetPull(offset, 1)
+	time.Sleep(10 * time.Millisecond)
+	s.SetPull(offset, 0)
+	s.SetPull(offset, 1)
+	s.SetPull(offset, 0)
+	time.Sleep(10 * time.Millisecond)
+	close(blockCh)
+	var batch []gpiocdev.LineEvent
+	select {
+	case batch = <-bch:
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for batch")
+	}
+	require.Len(t, batch, 1)
+	assert.Equal(t, nextEvent(r, 1).Seqno, batch[0].Seqno)
+	select {
+	case batch = <-bch:
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for batch")
+	}
+	if r.UapiAbiVersion() == 1 {
+		// uAPI v1 events are delivered individually
+		require.Len(t, batch, 1)
+		return
+	}
+	require.Len(t, batch, 3)
+	for i, evt := range batch {
+		xevt := nextEvent(r, i&1)
+		assert.Equal(t, xevt.Type, evt.Type)
+		assert.Equal(t, xevt.Seqno, evt.Seqno)
+	}
+
+	// overridden by event handler
+	r.Close()
+	r, err = c.RequestLine(offset,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {
+			ich <- evt
+		}))
+	require.Nil(t, err)
+	require.NotNil(t, r)
+	evtSeqno = 0
+	s.SetPull(offset, 1)
+	waitEvent(t, ich, nextEvent(r, 1))
+	waitNoEvent(t, ich)
+}
+
+func TestWithEventLoop(t *testing.T) {
+	requireKernel(t, infoWatchKernel)
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(t, err)
+	defer s.Close()
+
+	el, err := gpiocdev.NewEventLoop()
+	require.Nil(t, err)
+	require.NotNil(t, el)
+
+	// via chip options
+	c := getChip(t, s.DevPath(), gpiocdev.WithEventLoop(el))
+	defer c.Close()
+	wch := make(chan gpiocdev.LineInfoChangeEvent, 5)
+	_, err = c.WatchLineInfo(1, func(info gpiocdev.LineInfoChangeEvent) {
+		wch <- info
+	})
+	require.Nil(t, err)
+
+	ich1 := make(chan gpiocdev.LineEvent, 3)
+	r1, err := c.RequestLine(1,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {
+			ich1 <- evt
+		}))
+	require.Nil(t, err)
+	require.NotNil(t, r1)
+	defer r1.Close()
+	waitInfoEvent(t, wch, gpiocdev.LineRequested)
+
+	// via line options
+	c2 := getChip(t, s.DevPath())
+	defer c2.Close()
+	ich2 := make(chan gpiocdev.LineEvent, 3)
+	r2, err := c2.RequestLine(2,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventLoop(el),
+		gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {
+			ich2 <- evt
+		}))
+	require.Nil(t, err)
+	require.NotNil(t, r2)
+
+	evtSeqno = 0
+	s.SetPull(1, 1)
+	waitEvent(t, ich1, nextEvent(r1, 1))
+	waitNoEvent(t, ich2)
+	evtSeqno = 0
+	s.SetPull(2, 1)
+	waitEvent(t, ich2, nextEvent(r2, 1))
+	waitNoEvent(t, ich1)
+
+	// removal of one request leaves the others
+	r2.Close()
+	s.SetPull(2, 0)
+	waitNoEvent(t, ich2)
+	evtSeqno = 1
+	s.SetPull(1, 0)
+	waitEvent(t, ich1, nextEvent(r1, 0))
+
+	r1.Close()
+	waitInfoEvent(t, wch, gpiocdev.LineReleased)
+
+	err = el.Close()
+	assert.Nil(t, err)
+	err = el.Close()
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+
+	// requests with a closed loop fail
+	r1, err = c.RequestLine(1,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {}))
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+	assert.Nil(t, r1)
+}
+
+func TestWithErrorHandler(t *testing.T) {
+	requireKernel(t, infoWatchKernel)
+	offset := 2
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(t, err)
+
+	ech := make(chan error, 3)
+	c := getChip(t, s.DevPath(),
+		gpiocdev.WithErrorHandler(func(err error) {
+			ech <- err
+		}))
+	defer c.Close()
+	_, err = c.WatchLineInfo(offset, func(gpiocdev.LineInfoChangeEvent) {})
+	require.Nil(t, err)
+
+	ech2 := make(chan error, 3)
+	r, err := c.RequestLine(offset,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(func(gpiocdev.LineEvent) {}),
+		gpiocdev.WithErrorHandler(func(err error) {
+			ech2 <- err
+		}))
+	require.Nil(t, err)
+	require.NotNil(t, r)
+	defer r.Close()
+	assert.Nil(t, r.Err())
+	assert.Nil(t, c.Err())
+
+	// remove the chip
+	s.Close()
+
+	select {
+	case err = <-ech2:
+		assert.Equal(t, unix.ENODEV, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for request error")
+	}
+	assert.Equal(t, unix.ENODEV, r.Err())
+
+	select {
+	case err = <-ech:
+		assert.NotNil(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for chip error")
+	}
+	assert.NotNil(t, c.Err())
+}
+
+func TestWithEventChannel(t *testing.T) {
+	offset := 4
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(t, err)
+	defer s.Close()
+
+	s.SetPull(offset, 0)
+	c := getChip(t, s.DevPath())
+	defer c.Close()
+
+	// block
+	r, err := c.RequestLine(offset,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventChannel(2, gpiocdev.EventChannelBlock))
+	require.Nil(t, err)
+	require.NotNil(t, r)
+	ch := r.Events()
+	require.NotNil(t, ch)
+	evtSeqno = 0
+	waitNoEvent(t, ch)
+	s.SetPull(offset, 1)
+	waitEvent(t, ch, nextEvent(r, 1))
+	s.SetPull(offset, 0)
+	waitEvent(t, ch, nextEvent(r, 0))
+	waitNoEvent(t, ch)
+	// fill the channel and block the watcher
+	s.SetPull(offset, 1)
+	s.SetPull(offset, 0)
+	s.SetPull(offset, 1)
+	time.Sleep(20 * time.Millisecond)
+	// close releases the blocked watcher and closes the channel
+	r.Close()
+	n := 0
+	for range ch {
+		n++
+	}
+	assert.Equal(t, 2, n)
+
+	// drop newest
+	r, err = c.RequestLine(offset,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventChannel(2, gpiocdev.EventChannelDropNewest))
+	require.Nil(t, err)
+	require.NotNil(t, r)
+	ch = r.Events()
+	require.NotNil(t, ch)
+	evtSeqno = 0
+	s.SetPull(offset, 0)
+	s.SetPull(offset, 1)
+	s.SetPull(offset, 0)
+	time.Sleep(20 * time.Millisecond)
+	waitEvent(t, ch, nextEvent(r, 0))
+	waitEvent(t, ch, nextEvent(r, 1))
+	waitNoEvent(t, ch)
+	r.Close()
+
+	// drop oldest
+	r, err = c.RequestLine(offset,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventChannel(2, gpiocdev.EventChannelDropOldest))
+	require.Nil(t, err)
+	require.NotNil(t, r)
+	ch = r.Events()
+	require.NotNil(t, ch)
+	evtSeqno = 0
+	s.SetPull(offset, 1)
+	s.SetPull(offset, 0)
+	s.SetPull(offset, 1)
+	time.Sleep(20 * time.Millisecond)
+	nextEvent(r, 1)
+	waitEvent(t, ch, nextEvent(r, 0))
+	waitEvent(t, ch, nextEvent(r, 1))
+	waitNoEvent(t, ch)
+	r.Close()
+
+	// overridden by event handler
+	r, err = c.RequestLine(offset,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventChannel(2, gpiocdev.EventChannelBlock),
+		gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {}))
+	require.Nil(t, err)
+	require.NotNil(t, r)
+	assert.Nil(t, r.Events())
+	r.Close()
+}
+
+func TestWithOverflowEventsBatch(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	type event struct {
+		offset int
+		typ    gpiocdev.LineEventType
+		lost   uint32
+	}
+	rising := gpiocdev.LineEventRisingEdge
+	overflow := gpiocdev.LineEventOverflow
+	patterns := []struct {
+		name string
+		// the lines to toggle, in order.
+		toggles []int
+		// the events expected in the batch.
+		batch []event
+	}{
+		{
+			"gap at start",
+			[]int{0, 0, 0, 0, 1},
+			[]event{{0, overflow, 1}, {0, rising, 0}, {0, rising, 0}, {0, rising, 0}, {1, rising, 0}},
+		},
+		{
+			"gap in middle",
+			[]int{0, 1, 1, 0, 1},
+			[]event{{1, rising, 0}, {1, rising, 0}, {0, overflow, 1}, {0, rising, 0}, {1, rising, 0}},
+		},
+		{
+			"two gaps",
+			[]int{0, 1, 2, 2, 0, 1},
+			[]event{{2, rising, 0}, {2, rising, 0}, {0, overflow, 1}, {0, rising, 0}, {1, overflow, 1}, {1, rising, 0}},
+		},
+	}
+	for _, p := range patterns {
+		t.Run(p.name, func(t *testing.T) {
+			el, err := gpiocdev.NewEventLoop()
+			require.Nil(t, err)
+			defer el.Close()
+
+			// block the loop so the events queue up in the buffer.
+			block := make(chan struct{})
+			bl, err := gpiocdev.RequestLine(s.ChipName(), 3,
+				gpiocdev.WithRisingEdge,
+				gpiocdev.WithEventLoop(el),
+				gpiocdev.WithEventHandler(func(gpiocdev.LineEvent) { <-block }))
+			require.Nil(t, err)
+			defer bl.Close()
+
+			batches := make(chan []gpiocdev.LineEvent, 4)
+			l, err := gpiocdev.RequestLines(s.ChipName(), []int{0, 1, 2},
+				gpiocdev.WithRisingEdge,
+				gpiocdev.WithEventBufferSize(4),
+				gpiocdev.WithOverflowEvents,
+				gpiocdev.WithEventLoop(el),
+				gpiocdev.WithEventBatchHandler(func(evts []gpiocdev.LineEvent) {
+					batches <- append([]gpiocdev.LineEvent(nil), evts...)
+				}))
+			require.Nil(t, err)
+			defer l.Close()
+
+			s.Pullup(3)
+			time.Sleep(10 * time.Millisecond)
+			for _, o := range p.toggles {
+				s.Pullup(o)
+				s.Pulldown(o)
+			}
+			close(block)
+
+			var batch []event
+			select {
+			case evts := <-batches:
+				for _, evt := range evts {
+					batch = append(batch, event{evt.Offset, evt.Type, evt.Lost})
+				}
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for batch")
+			}
+			assert.Equal(t, p.batch, batch)
+			s.Pulldown(3)
+		})
+	}
+}
+
+func TestWithEventChannelSize(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	patterns := []struct {
+		name   string
+		size   int
+		policy gpiocdev.EventChannelPolicy
+		err    error
+	}{
+		{"block", 0, gpiocdev.EventChannelBlock, nil},
+		{"block negative", -1, gpiocdev.EventChannelBlock, unix.EINVAL},
+		{"drop oldest", 1, gpiocdev.EventChannelDropOldest, nil},
+		{"drop oldest zero", 0, gpiocdev.EventChannelDropOldest, unix.EINVAL},
+		{"drop oldest negative", -1, gpiocdev.EventChannelDropOldest, unix.EINVAL},
+		{"drop newest", 1, gpiocdev.EventChannelDropNewest, nil},
+		{"drop newest zero", 0, gpiocdev.EventChannelDropNewest, unix.EINVAL},
+		{"drop newest negative", -1, gpiocdev.EventChannelDropNewest, unix.EINVAL},
+	}
+	for _, p := range patterns {
+		t.Run(p.name, func(t *testing.T) {
+			l, err := gpiocdev.RequestLine(s.ChipName(), 1,
+				gpiocdev.WithBothEdges,
+				gpiocdev.WithEventChannel(p.size, p.policy))
+			assert.Equal(t, p.err, err)
+			if err == nil {
+				l.Close()
+			} else {
+				assert.Nil(t, l)
+			}
+			ml, err := gpiocdev.RequestMultiLines(
+				[]gpiocdev.ChipOffset{{Chip: s.ChipName(), Offset: 2}},
+				gpiocdev.WithBothEdges,
+				gpiocdev.WithEventChannel(p.size, p.policy))
+			assert.Equal(t, p.err, err)
+			if err == nil {
+				ml.Close()
+			} else {
+				assert.Nil(t, ml)
+			}
+		})
+	}
+}
+
+func TestWithEventChannelError(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+	s2, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s2.Close()
+
+	l, err := gpiocdev.RequestLine(s.ChipName(), 1,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventChannel(2, gpiocdev.EventChannelBlock))
+	require.Nil(t, err)
+	defer l.Close()
+	ml, err := gpiocdev.RequestMultiLines(
+		[]gpiocdev.ChipOffset{{Chip: s.ChipName(), Offset: 2}, {Chip: s2.ChipName(), Offset: 2}},
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventChannel(2, gpiocdev.EventChannelDropOldest))
+	require.Nil(t, err)
+	defer ml.Close()
+
+	s.Pullup(1)
+	evt := <-l.Events()
+	assert.Equal(t, 1, evt.Offset)
+	assert.Nil(t, l.Err())
+
+	// removing the chip stops the watchers, which closes the channels
+	s.Close()
+	done := make(chan int)
+	go func() {
+		n := 0
+		for range l.Events() {
+			n++
+		}
+		for range ml.Events() {
+			n++
+		}
+		done <- n
+	}()
+	select {
+	case n := <-done:
+		assert.Zero(t, n)
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout waiting for channels to close")
+	}
+	assert.Equal(t, unix.ENODEV, l.Err())
+	assert.Equal(t, unix.ENODEV, ml.Err())
+
+	// and close is still safe
+	assert.Nil(t, l.Close())
+	assert.Nil(t, ml.Close())
+}
+
 func TestWithFallingEdge(t *testing.T) {
 	offset := 4
 	s, err := gpiosim.NewSimpleton(6)
@@ -1465,3 +1953,73 @@ func TestWithEventBufferSize(t *testing.T) {
 		}
 	}
 }
+
+func TestWithOverflowEvents(t *testing.T) {
+	requireKernel(t, uapiV2Kernel)
+	offset := 2
+	s, err := gpiosim.NewSimpleton(6)
+	require.Nil(t, err)
+	defer s.Close()
+	s.SetPull(offset, 0)
+	c := getChip(t, s.DevPath())
+	defer c.Close()
+	requireABI(t, c, 2)
+
+	patterns := []struct {
+		name     string
+		overflow bool
+	}{
+		{"with", true},
+		{"without", false},
+	}
+	for _, p := range patterns {
+		tf := func(t *testing.T) {
+			blockCh := make(chan struct{})
+			ich := make(chan gpiocdev.LineEvent, 64)
+			l, err := c.RequestLine(offset,
+				gpiocdev.WithBothEdges,
+				gpiocdev.WithEventBufferSize(2),
+				gpiocdev.OverflowEventsOption(p.overflow),
+				gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {
+					<-blockCh
+					ich <- evt
+				}))
+			require.Nil(t, err)
+			require.NotNil(t, l)
+			defer l.Close()
+			for i := 0; i < 10; i++ {
+				s.SetPull(offset, (i+1)&1)
+			}
+			time.Sleep(20 * time.Millisecond)
+			close(blockCh)
+			time.Sleep(20 * time.Millisecond)
+			lostEvents := l.LostEvents()
+			assert.NotZero(t, lostEvents)
+			var lost uint64
+			var overflows int
+			events := 0
+			for done := false; !done; {
+				select {
+				case evt := <-ich:
+					if evt.Type == gpiocdev.LineEventOverflow {
+						overflows++
+						assert.Equal(t, offset, evt.Offset)
+						lost += uint64(evt.Lost)
+					} else {
+						events++
+					}
+				default:
+					done = true
+				}
+			}
+			assert.Equal(t, 10, events+int(lostEvents))
+			if p.overflow {
+				assert.Equal(t, 1, overflows)
+				assert.Equal(t, lostEvents, lost)
+			} else {
+				assert.Zero(t, overflows)
+			}
+		}
+		t.Run(p.name, tf)
+	}
+}
@@ -0,0 +1,399 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package pulse measures the width and period of pulses on an input line, such
+// as PWM signals, hall sensor outputs, or the echo from ultrasonic
+// rangefinders.
+//
+// Measurements are taken from the edge event timestamps, not from when the
+// events are read, so delays in reading events do not distort the
+// measurements.
+//
+//	m, _ := pulse.New("gpiochip0", 17, pulse.WithAverage(16))
+//	defer m.Close()
+//	...
+//	avg, err := m.Average()
+//	fmt.Println(avg.Frequency(), avg.DutyCycle())
+//
+// High and low refer to the active and inactive levels of the line, so for a
+// line requested as active low the high time is the time the line is
+// physically low.
+package pulse
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// ErrTimeout indicates no edge was detected within the timeout, so the
+	// signal has stopped or is not present.
+	ErrTimeout = errors.New("timeout")
+
+	// ErrNoMeasurement indicates a complete cycle has not yet been measured.
+	ErrNoMeasurement = errors.New("no measurement")
+)
+
+// Measurement describes the timing of a signal.
+type Measurement struct {
+	// The time the signal was high.
+	High time.Duration
+
+	// The time the signal was low.
+	Low time.Duration
+
+	// The time from one rising edge to the next.
+	Period time.Duration
+
+	// The number of cycles included in the measurement.
+	Cycles int
+}
+
+// Frequency returns the frequency of the signal, in Hz.
+func (m Measurement) Frequency() float64 {
+	if m.Period <= 0 {
+		return 0
+	}
+	return float64(time.Second) / float64(m.Period)
+}
+
+// DutyCycle returns the fraction of the period the signal was high, in the
+// range 0 to 1.
+func (m Measurement) DutyCycle() float64 {
+	if m.Period <= 0 {
+		return 0
+	}
+	return float64(m.High) / float64(m.Period)
+}
+
+// cycle is the timing of a single cycle.
+type cycle struct {
+	high time.Duration
+	low  time.Duration
+}
+
+// Meter measures the pulses on an input line.
+type Meter struct {
+	l *gpiocdev.Line
+
+	timeout time.Duration
+
+	// the clock used for the event timestamps.
+	clock int32
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	// the most recent cycles, as a ring buffer.
+	cycles []cycle
+
+	// the index in cycles to write the next cycle.
+	next int
+
+	// the number of valid entries in cycles.
+	count int
+
+	// the timestamps of the most recent edges, or 0 if not valid.
+	riseTs time.Duration
+	fallTs time.Duration
+
+	// the timestamp of the most recent edge, or of the start of the meter.
+	lastTs time.Duration
+
+	lineSeqno uint32
+
+	// the number of rising edges detected.
+	rises uint64
+
+	// the width of the most recent high pulse, and the number of the rising
+	// edge that started it.
+	pulse     time.Duration
+	pulseRise uint64
+
+	// closed and replaced when a high pulse completes, to wake WaitPulse.
+	pulseDone chan struct{}
+
+	// closed when the meter is closed.
+	done chan struct{}
+
+	closed bool
+}
+
+// Option defines the interface required to provide an option to New or
+// NewRangefinder.
+type Option interface {
+	applyOption(*pulseOptions)
+}
+
+type pulseOptions struct {
+	average      int
+	timeout      time.Duration
+	speedOfSound float64
+	reqOpts      []gpiocdev.LineReqOption
+}
+
+// AverageOption sets the number of cycles averaged.
+type AverageOption int
+
+// WithAverage sets the number of the most recent cycles included in the
+// rolling average returned by Average.
+//
+// The default is 8.
+func WithAverage(cycles int) AverageOption {
+	return AverageOption(cycles)
+}
+
+func (o AverageOption) applyOption(opts *pulseOptions) {
+	opts.average = int(o)
+}
+
+// TimeoutOption sets the timeout.
+type TimeoutOption time.Duration
+
+// WithTimeout sets the time without an edge after which the signal is
+// considered to have stopped.
+//
+// For a Meter a timeout of 0 disables the timeout.  For a Rangefinder this is
+// the time to wait for the echo.
+//
+// The default is 1s for a Meter, and 100ms for a Rangefinder.
+func WithTimeout(period time.Duration) TimeoutOption {
+	return TimeoutOption(period)
+}
+
+func (o TimeoutOption) applyOption(opts *pulseOptions) {
+	opts.timeout = time.Duration(o)
+}
+
+// RequestOption provides options for the line request.
+type RequestOption []gpiocdev.LineReqOption
+
+// WithRequestOptions provides options for the request of the input line, such
+// as active level, bias, debounce or consumer.
+//
+// Direction, edge detection and event handling options are overridden.
+func WithRequestOptions(options ...gpiocdev.LineReqOption) RequestOption {
+	return RequestOption(options)
+}
+
+func (o RequestOption) applyOption(opts *pulseOptions) {
+	opts.reqOpts = append(opts.reqOpts, o...)
+}
+
+// New requests the input line from the chip and starts measuring pulses.
+//
+// Returns unix.EINVAL if the average is not positive or the timeout is
+// negative.
+func New(chip string, offset int, options ...Option) (*Meter, error) {
+	opts := pulseOptions{average: 8, timeout: time.Second}
+	for _, option := range options {
+		option.applyOption(&opts)
+	}
+	return newMeter(chip, offset, opts)
+}
+
+func newMeter(chip string, offset int, opts pulseOptions) (*Meter, error) {
+	if opts.average <= 0 || opts.timeout < 0 {
+		return nil, unix.EINVAL
+	}
+	m := &Meter{
+		timeout:   opts.timeout,
+		clock:     unix.CLOCK_MONOTONIC,
+		cycles:    make([]cycle, opts.average),
+		pulseDone: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	reqOpts := append([]gpiocdev.LineReqOption{}, opts.reqOpts...)
+	reqOpts = append(reqOpts,
+		gpiocdev.AsInput,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(m.handle))
+	// hold the lock so events are not handled until the event clock is known.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, err := gpiocdev.RequestLine(chip, offset, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	m.l = l
+	inf, err := l.Info()
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	if inf.Config.EventClock == gpiocdev.LineEventClockRealtime {
+		m.clock = unix.CLOCK_REALTIME
+	}
+	m.lastTs = m.now()
+	return m, nil
+}
+
+// Close releases the line.
+func (m *Meter) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return gpiocdev.ErrClosed
+	}
+	m.closed = true
+	close(m.done)
+	m.mu.Unlock()
+	return m.l.Close()
+}
+
+// Value returns the current value of the line.
+//
+// This is the level of a stopped signal.
+func (m *Meter) Value() (int, error) {
+	return m.l.Value()
+}
+
+// Last returns the measurement of the most recent complete cycle.
+//
+// Returns ErrTimeout if the signal has stopped, and ErrNoMeasurement if a
+// complete cycle has not been measured since the meter was started or the
+// signal restarted.
+func (m *Meter) Last() (Measurement, error) {
+	return m.measure(1)
+}
+
+// Average returns the mean of the most recent cycles, up to the number set by
+// WithAverage.
+//
+// Returns ErrTimeout if the signal has stopped, and ErrNoMeasurement if a
+// complete cycle has not been measured since the meter was started or the
+// signal restarted.
+func (m *Meter) Average() (Measurement, error) {
+	return m.measure(len(m.cycles))
+}
+
+// measure returns the mean of up to n of the most recent cycles.
+func (m *Meter) measure(n int) (Measurement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return Measurement{}, gpiocdev.ErrClosed
+	}
+	if m.timeout > 0 && m.now()-m.lastTs > m.timeout {
+		return Measurement{}, ErrTimeout
+	}
+	if m.count == 0 {
+		return Measurement{}, ErrNoMeasurement
+	}
+	if n > m.count {
+		n = m.count
+	}
+	var high, low time.Duration
+	idx := m.next
+	for i := 0; i < n; i++ {
+		idx--
+		if idx < 0 {
+			idx = len(m.cycles) - 1
+		}
+		high += m.cycles[idx].high
+		low += m.cycles[idx].low
+	}
+	high /= time.Duration(n)
+	low /= time.Duration(n)
+	return Measurement{High: high, Low: low, Period: high + low, Cycles: n}, nil
+}
+
+// WaitPulse waits for the next complete high pulse, that starts after the call,
+// and returns its width.
+//
+// Returns ErrTimeout if the pulse does not complete within the timeout.
+func (m *Meter) WaitPulse(timeout time.Duration) (time.Duration, error) {
+	return m.waitPulse(m.mark(), timeout)
+}
+
+// mark returns the number of rising edges detected so far.
+func (m *Meter) mark() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rises
+}
+
+// waitPulse waits for the next complete high pulse started by a rising edge
+// after the mark, and returns its width.
+func (m *Meter) waitPulse(rises uint64, timeout time.Duration) (time.Duration, error) {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	for {
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			return 0, gpiocdev.ErrClosed
+		}
+		if m.pulseRise > rises {
+			p := m.pulse
+			m.mu.Unlock()
+			return p, nil
+		}
+		pulseDone := m.pulseDone
+		m.mu.Unlock()
+		select {
+		case <-pulseDone:
+		case <-m.done:
+		case <-t.C:
+			return 0, ErrTimeout
+		}
+	}
+}
+
+// handle updates the measurements from the edge event.
+func (m *Meter) handle(evt gpiocdev.LineEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	ts := evt.Timestamp
+	stopped := m.timeout > 0 && ts-m.lastTs > m.timeout
+	if stopped {
+		// the signal has restarted, so discard the old cycles.
+		m.count = 0
+	}
+	// sequence numbers are only available with uAPI v2, so gaps cannot be
+	// detected with v1.
+	gap := evt.Seqno != 0 && evt.LineSeqno != m.lineSeqno+1
+	if stopped || gap {
+		// the partial cycle is invalid.
+		m.riseTs = 0
+		m.fallTs = 0
+	}
+	m.lineSeqno = evt.LineSeqno
+	m.lastTs = ts
+	switch evt.Type {
+	case gpiocdev.LineEventRisingEdge:
+		m.rises++
+		if m.riseTs != 0 && m.fallTs > m.riseTs {
+			m.cycles[m.next] = cycle{high: m.fallTs - m.riseTs, low: ts - m.fallTs}
+			m.next = (m.next + 1) % len(m.cycles)
+			if m.count < len(m.cycles) {
+				m.count++
+			}
+		}
+		m.riseTs = ts
+		m.fallTs = 0
+	case gpiocdev.LineEventFallingEdge:
+		if m.riseTs != 0 {
+			m.fallTs = ts
+			m.pulse = ts - m.riseTs
+			m.pulseRise = m.rises
+			close(m.pulseDone)
+			m.pulseDone = make(chan struct{})
+		}
+	}
+}
+
+// now returns the current time from the event clock.
+func (m *Meter) now() time.Duration {
+	var ts unix.Timespec
+	unix.ClockGettime(m.clock, &ts)
+	return time.Duration(ts.Nano())
+}
@@ -0,0 +1,250 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package pulse_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/pulse"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"github.com/warthog618/go-gpiosim"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	offset  = 1
+	trigger = 2
+	echo    = 3
+)
+
+// tolerance allows for the scheduling of the test signal.
+const tolerance = 10 * time.Millisecond
+
+func TestNew(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	_, err = pulse.New(s.ChipName(), offset, pulse.WithAverage(0))
+	assert.Equal(t, unix.EINVAL, err)
+	_, err = pulse.New(s.ChipName(), offset, pulse.WithTimeout(-1))
+	assert.Equal(t, unix.EINVAL, err)
+	_, err = pulse.New(s.ChipName(), 4)
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	m, err := pulse.New(s.ChipName(), offset)
+	require.Nil(t, err)
+	_, err = m.Last()
+	assert.Equal(t, pulse.ErrNoMeasurement, err)
+	assert.Nil(t, m.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, m.Close())
+	_, err = m.Average()
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+	_, err = m.WaitPulse(time.Millisecond)
+	assert.Equal(t, gpiocdev.ErrClosed, err)
+}
+
+func TestMeasurement(t *testing.T) {
+	m := pulse.Measurement{
+		High:   25 * time.Millisecond,
+		Low:    75 * time.Millisecond,
+		Period: 100 * time.Millisecond,
+	}
+	assert.Equal(t, 10.0, m.Frequency())
+	assert.Equal(t, 0.25, m.DutyCycle())
+	m = pulse.Measurement{}
+	assert.Equal(t, 0.0, m.Frequency())
+	assert.Equal(t, 0.0, m.DutyCycle())
+}
+
+func TestMeter(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	m, err := pulse.New(s.ChipName(), offset,
+		pulse.WithAverage(2),
+		pulse.WithTimeout(150*time.Millisecond))
+	require.Nil(t, err)
+	defer m.Close()
+
+	high := 20 * time.Millisecond
+	low := 40 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		s.Pullup(offset)
+		time.Sleep(high)
+		s.Pulldown(offset)
+		time.Sleep(low)
+		high *= 2
+	}
+	s.Pullup(offset)
+	time.Sleep(time.Millisecond)
+
+	last, err := m.Last()
+	require.Nil(t, err)
+	assert.Equal(t, 1, last.Cycles)
+	assert.InDelta(t, 80*time.Millisecond, last.High, float64(tolerance))
+	assert.InDelta(t, 40*time.Millisecond, last.Low, float64(tolerance))
+	assert.Equal(t, last.High+last.Low, last.Period)
+
+	avg, err := m.Average()
+	require.Nil(t, err)
+	assert.Equal(t, 2, avg.Cycles)
+	assert.InDelta(t, 60*time.Millisecond, avg.High, float64(tolerance))
+	assert.InDelta(t, 100*time.Millisecond, avg.Period, float64(tolerance))
+
+	// the signal stops
+	time.Sleep(200 * time.Millisecond)
+	_, err = m.Last()
+	assert.Equal(t, pulse.ErrTimeout, err)
+	v, err := m.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	// and restarts, discarding the old cycles
+	s.Pulldown(offset)
+	time.Sleep(20 * time.Millisecond)
+	_, err = m.Last()
+	assert.Equal(t, pulse.ErrNoMeasurement, err)
+}
+
+func TestWaitPulse(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	m, err := pulse.New(s.ChipName(), offset)
+	require.Nil(t, err)
+	defer m.Close()
+
+	_, err = m.WaitPulse(20 * time.Millisecond)
+	assert.Equal(t, pulse.ErrTimeout, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.Pullup(offset)
+		time.Sleep(30 * time.Millisecond)
+		s.Pulldown(offset)
+	}()
+	width, err := m.WaitPulse(time.Second)
+	assert.Nil(t, err)
+	assert.InDelta(t, 30*time.Millisecond, width, float64(tolerance))
+}
+
+func TestRangefinder(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	_, err = pulse.NewRangefinder(s.ChipName(), trigger, echo, pulse.WithSpeedOfSound(0))
+	assert.Equal(t, unix.EINVAL, err)
+	_, err = pulse.NewRangefinder(s.ChipName(), trigger, echo, pulse.WithTimeout(0))
+	assert.Equal(t, unix.EINVAL, err)
+	_, err = pulse.NewRangefinder(s.ChipName(), trigger, 4)
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	r, err := pulse.NewRangefinder(s.ChipName(), trigger, echo,
+		pulse.WithSpeedOfSound(200))
+	require.Nil(t, err)
+
+	// no sensor
+	_, err = r.Distance()
+	assert.Equal(t, pulse.ErrTimeout, err)
+
+	e := newHCSR04(s, 20*time.Millisecond)
+	d, err := r.Distance()
+	e.close()
+	assert.Nil(t, err)
+	// 20ms at 200m/s is 2m there and back
+	assert.InDelta(t, 2.0, d, 0.1*2)
+
+	assert.Nil(t, r.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, r.Close())
+}
+
+func TestABIv1(t *testing.T) {
+	// the sim does not support uAPI v1, so this requires gpio-sim.
+	s, err := gpiosim.NewSimpleton(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	v1 := pulse.WithRequestOptions(gpiocdev.ABIVersionOption(1))
+	m, err := pulse.New(s.ChipName(), offset, v1)
+	require.Nil(t, err)
+	defer m.Close()
+
+	for i := 0; i < 2; i++ {
+		s.Pullup(offset)
+		time.Sleep(20 * time.Millisecond)
+		s.Pulldown(offset)
+		time.Sleep(40 * time.Millisecond)
+	}
+	s.Pullup(offset)
+	time.Sleep(time.Millisecond)
+
+	last, err := m.Last()
+	require.Nil(t, err)
+	assert.InDelta(t, 20*time.Millisecond, last.High, float64(tolerance))
+	assert.InDelta(t, 40*time.Millisecond, last.Low, float64(tolerance))
+
+	r, err := pulse.NewRangefinder(s.ChipName(), trigger, echo,
+		pulse.WithSpeedOfSound(200), v1)
+	require.Nil(t, err)
+	defer r.Close()
+
+	e := newHCSR04(s, 20*time.Millisecond)
+	d, err := r.Distance()
+	e.close()
+	assert.Nil(t, err)
+	assert.InDelta(t, 2.0, d, 0.1*2)
+}
+
+// emulatedChip is the subset of the sim and gpiosim chips used by the
+// emulators.
+type emulatedChip interface {
+	Level(offset int) (int, error)
+	Pullup(offset int) error
+	Pulldown(offset int) error
+}
+
+// hcsr04 emulates an HC-SR04 by polling the trigger line.
+type hcsr04 struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (e *hcsr04) close() {
+	close(e.done)
+	e.wg.Wait()
+}
+
+// newHCSR04 emulates an HC-SR04 returning an echo of the given width.
+func newHCSR04(s emulatedChip, width time.Duration) *hcsr04 {
+	e := &hcsr04{done: make(chan struct{})}
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		for {
+			select {
+			case <-e.done:
+				return
+			default:
+			}
+			if v, _ := s.Level(trigger); v == 1 {
+				s.Pullup(echo)
+				time.Sleep(width)
+				s.Pulldown(echo)
+			}
+			runtime.Gosched()
+		}
+	}()
+	return e
+}
@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package pulse
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+	"golang.org/x/sys/unix"
+)
+
+// triggerPulse is the width of the trigger pulse, which must be at least 10µs.
+const triggerPulse = 10 * time.Microsecond
+
+// SpeedOfSoundOption sets the speed of sound.
+type SpeedOfSoundOption float64
+
+// WithSpeedOfSound sets the speed of sound used to convert the echo time to
+// distance, in metres per second.
+//
+// The default is 343, the speed in dry air at 20°C.
+func WithSpeedOfSound(speed float64) SpeedOfSoundOption {
+	return SpeedOfSoundOption(speed)
+}
+
+func (o SpeedOfSoundOption) applyOption(opts *pulseOptions) {
+	opts.speedOfSound = float64(o)
+}
+
+// Rangefinder is an ultrasonic rangefinder with separate trigger and echo
+// lines, such as the HC-SR04.
+//
+// The rangefinder is triggered by a high pulse on the trigger line, and
+// responds with a high pulse on the echo line for the time taken by the sound
+// to reach the target and return.
+type Rangefinder struct {
+	trigger *gpiocdev.Line
+
+	m *Meter
+
+	speed float64
+
+	timeout time.Duration
+
+	// mutex serialises measurements.
+	mu sync.Mutex
+}
+
+// NewRangefinder requests the trigger and echo lines from the chip and creates
+// a rangefinder.
+//
+// The request options apply to both lines.
+//
+// Returns unix.EINVAL if the timeout or speed of sound is not positive.
+func NewRangefinder(chip string, trigger, echo int, options ...Option) (*Rangefinder, error) {
+	opts := pulseOptions{
+		average:      1,
+		timeout:      100 * time.Millisecond,
+		speedOfSound: 343,
+	}
+	for _, option := range options {
+		option.applyOption(&opts)
+	}
+	if opts.timeout <= 0 || opts.speedOfSound <= 0 {
+		return nil, unix.EINVAL
+	}
+	reqOpts := append([]gpiocdev.LineReqOption{}, opts.reqOpts...)
+	reqOpts = append(reqOpts, gpiocdev.AsOutput(0))
+	t, err := gpiocdev.RequestLine(chip, trigger, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMeter(chip, echo, opts)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	return &Rangefinder{
+		trigger: t,
+		m:       m,
+		speed:   opts.speedOfSound,
+		timeout: opts.timeout,
+	}, nil
+}
+
+// Close releases the lines.
+func (r *Rangefinder) Close() error {
+	err := r.m.Close()
+	if err != nil {
+		return err
+	}
+	return r.trigger.Close()
+}
+
+// Distance triggers a measurement and returns the distance to the target, in
+// metres.
+//
+// Returns ErrTimeout if no echo is received within the timeout.  Note that the
+// HC-SR04 returns an echo of around 38ms, or 6.5m, if no target is detected,
+// which is well beyond its rated range of 4m.
+func (r *Rangefinder) Distance() (float64, error) {
+	echo, err := r.Echo()
+	if err != nil {
+		return 0, err
+	}
+	return echo.Seconds() * r.speed / 2, nil
+}
+
+// Echo triggers a measurement and returns the width of the echo pulse.
+//
+// Returns ErrTimeout if no echo is received within the timeout.
+func (r *Rangefinder) Echo() (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mark := r.m.mark()
+	if err := r.trigger.SetValue(1); err != nil {
+		return 0, err
+	}
+	for start := time.Now(); time.Since(start) < triggerPulse; {
+		runtime.Gosched()
+	}
+	if err := r.trigger.SetValue(0); err != nil {
+		return 0, err
+	}
+	return r.m.waitPulse(mark, r.timeout)
+}
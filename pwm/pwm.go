@@ -0,0 +1,373 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package pwm provides a software PWM generator that drives requested output
+// lines.
+//
+// The generator is timed using the Go runtime timers, so the edges jitter by
+// tens of microseconds or more, depending on system load.  This is suitable
+// for dimming LEDs and driving slow fans, but not for servos or other loads
+// sensitive to the pulse width.  The jitter actually achieved is available
+// from Stats.
+//
+//	l, _ := gpiocdev.RequestLine("gpiochip0", 4, gpiocdev.AsOutput(0))
+//	p, _ := pwm.New(l, pwm.WithFrequency(200), pwm.WithDutyCycle(0.25))
+//	...
+//	p.SetDutyCycle(0.75)
+//	...
+//	p.Close()
+//	l.Close()
+package pwm
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// PWM generates a PWM signal on one or more output lines.
+type PWM struct {
+	// sets the value of the driven line(s).
+	set func(value int) error
+
+	// the value set when the generator is stopped.
+	stopLevel int
+
+	// closed to stop the generator.
+	done chan struct{}
+
+	// closed once the generator has exited.
+	exited chan struct{}
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	// the period of the signal.
+	period time.Duration
+
+	// the fraction of the period the signal is active.
+	duty float64
+
+	stats Stats
+
+	// the sum of the jitter for all edges included in stats.
+	totalJitter time.Duration
+
+	// the error that stopped the generator, if any.
+	err error
+
+	closed bool
+}
+
+// Stats contains statistics describing the timing of the generated signal.
+type Stats struct {
+	// The number of edges generated.
+	Edges uint64
+
+	// The mean delay between when each edge was scheduled and when it was
+	// set.
+	MeanJitter time.Duration
+
+	// The maximum delay between when an edge was scheduled and when it was
+	// set.
+	MaxJitter time.Duration
+
+	// The number of times the generator fell more than a period behind
+	// schedule, and so skipped periods to resynchronise.
+	Overruns uint64
+}
+
+// Option defines the interface required to provide an option to New.
+type Option interface {
+	applyOption(*PWM)
+}
+
+// FrequencyOption sets the frequency of the signal.
+type FrequencyOption float64
+
+// WithFrequency sets the frequency of the signal, in Hz.
+//
+// The default is 100Hz.
+func WithFrequency(hz float64) FrequencyOption {
+	return FrequencyOption(hz)
+}
+
+func (o FrequencyOption) applyOption(p *PWM) {
+	p.period = frequencyToPeriod(float64(o))
+}
+
+// DutyCycleOption sets the duty cycle of the signal.
+type DutyCycleOption float64
+
+// WithDutyCycle sets the fraction of each period the signal is active, in the
+// range 0 to 1.
+//
+// The default is 0, so the line(s) are inactive.
+func WithDutyCycle(duty float64) DutyCycleOption {
+	return DutyCycleOption(duty)
+}
+
+func (o DutyCycleOption) applyOption(p *PWM) {
+	p.duty = float64(o)
+}
+
+// StopLevelOption sets the value of the line(s) once the generator is closed.
+type StopLevelOption int
+
+// WithStopLevel sets the value the line(s) are set to when the generator is
+// closed.
+//
+// The default is 0 (inactive).
+func WithStopLevel(value int) StopLevelOption {
+	return StopLevelOption(value)
+}
+
+func (o StopLevelOption) applyOption(p *PWM) {
+	p.stopLevel = int(o)
+}
+
+var (
+	// ErrInvalidFrequency indicates the frequency is not positive, or is
+	// too high to be represented as a period.
+	ErrInvalidFrequency = errors.New("invalid frequency")
+
+	// ErrInvalidDutyCycle indicates the duty cycle is outside the range 0
+	// to 1.
+	ErrInvalidDutyCycle = errors.New("invalid duty cycle")
+)
+
+// New creates a PWM generator driving the line, and starts it.
+//
+// The line must have been requested as an output.  The generator does not
+// take ownership of the line, so the line should be closed after the
+// generator.
+func New(l *gpiocdev.Line, options ...Option) (*PWM, error) {
+	return newPWM(l.SetValue, options...)
+}
+
+// NewLines creates a PWM generator driving the lines, and starts it.
+//
+// All the lines are driven with the same signal, and are set together.
+//
+// The lines must have been requested as outputs.  The generator does not take
+// ownership of the lines, so the lines should be closed after the generator.
+func NewLines(ll *gpiocdev.Lines, options ...Option) (*PWM, error) {
+	values := make([]int, len(ll.Offsets()))
+	return newPWM(func(value int) error {
+		for i := range values {
+			values[i] = value
+		}
+		return ll.SetValues(values)
+	}, options...)
+}
+
+func newPWM(set func(int) error, options ...Option) (*PWM, error) {
+	p := PWM{
+		set:    set,
+		period: frequencyToPeriod(100),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	for _, option := range options {
+		option.applyOption(&p)
+	}
+	if p.period <= 0 {
+		return nil, ErrInvalidFrequency
+	}
+	if p.duty < 0 || p.duty > 1 {
+		return nil, ErrInvalidDutyCycle
+	}
+	go p.run()
+	return &p, nil
+}
+
+// Close stops the generator and sets the line(s) to the stop level.
+//
+// Returns the error that stopped the generator, if any.
+func (p *PWM) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return gpiocdev.ErrClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+	close(p.done)
+	<-p.exited
+	err := p.set(p.stopLevel)
+	if perr := p.Err(); perr != nil {
+		return perr
+	}
+	return err
+}
+
+// Err returns the error that stopped the generator, if any.
+//
+// The generator stops if setting the line(s) fails.
+func (p *PWM) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Frequency returns the frequency of the signal, in Hz.
+func (p *PWM) Frequency() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return float64(time.Second) / float64(p.period)
+}
+
+// SetFrequency sets the frequency of the signal, in Hz.
+//
+// The change takes effect from the start of the next period, so the current
+// period is not truncated or extended.
+func (p *PWM) SetFrequency(hz float64) error {
+	period := frequencyToPeriod(hz)
+	if period <= 0 {
+		return ErrInvalidFrequency
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.period = period
+	return nil
+}
+
+// DutyCycle returns the fraction of each period the signal is active.
+func (p *PWM) DutyCycle() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.duty
+}
+
+// SetDutyCycle sets the fraction of each period the signal is active, in the
+// range 0 to 1.
+//
+// The change takes effect from the start of the next period, so the current
+// period is not truncated or extended.
+func (p *PWM) SetDutyCycle(duty float64) error {
+	if duty < 0 || duty > 1 {
+		return ErrInvalidDutyCycle
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.duty = duty
+	return nil
+}
+
+// Stats returns the timing statistics for the signal generated so far.
+func (p *PWM) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// ResetStats clears the timing statistics.
+func (p *PWM) ResetStats() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats = Stats{}
+	p.totalJitter = 0
+}
+
+func frequencyToPeriod(hz float64) time.Duration {
+	if hz <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / hz)
+}
+
+// run generates the signal until the generator is closed or setting the lines
+// fails.
+func (p *PWM) run() {
+	defer close(p.exited)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	// the current value of the lines, or -1 if unknown.
+	level := -1
+	start := time.Now()
+	for {
+		p.mu.Lock()
+		period := p.period
+		active := time.Duration(float64(period) * p.duty)
+		p.mu.Unlock()
+
+		if active > 0 {
+			if !p.edge(&level, 1, start) {
+				return
+			}
+			if active < period {
+				if !p.sleepUntil(timer, start.Add(active)) {
+					return
+				}
+			}
+		}
+		if active < period {
+			if !p.edge(&level, 0, start.Add(active)) {
+				return
+			}
+		}
+		start = start.Add(period)
+		if !p.sleepUntil(timer, start) {
+			return
+		}
+		if time.Since(start) > period {
+			// fallen behind - skip the missed periods.
+			p.mu.Lock()
+			p.stats.Overruns++
+			p.mu.Unlock()
+			start = time.Now()
+		}
+	}
+}
+
+// edge sets the lines to the value, if not already set, and records the
+// resulting jitter.
+//
+// Returns false if setting the lines fails.
+func (p *PWM) edge(level *int, value int, scheduled time.Time) bool {
+	if *level == value {
+		return true
+	}
+	err := p.set(value)
+	jitter := time.Since(scheduled)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.err = err
+		return false
+	}
+	*level = value
+	p.stats.Edges++
+	p.totalJitter += jitter
+	p.stats.MeanJitter = p.totalJitter / time.Duration(p.stats.Edges)
+	if jitter > p.stats.MaxJitter {
+		p.stats.MaxJitter = jitter
+	}
+	return true
+}
+
+// sleepUntil waits until the deadline.
+//
+// Returns false if the generator is closed while waiting.
+func (p *PWM) sleepUntil(timer *time.Timer, deadline time.Time) bool {
+	d := time.Until(deadline)
+	if d <= 0 {
+		select {
+		case <-p.done:
+			return false
+		default:
+			return true
+		}
+	}
+	timer.Reset(d)
+	select {
+	case <-timer.C:
+		return true
+	case <-p.done:
+		timer.Stop()
+		return false
+	}
+}
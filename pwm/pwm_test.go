@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package pwm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/pwm"
+	"github.com/warthog618/go-gpiocdev/sim"
+)
+
+func TestNew(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+	l, err := gpiocdev.RequestLine(s.ChipName(), 1, gpiocdev.AsOutput(0))
+	require.Nil(t, err)
+	defer l.Close()
+
+	_, err = pwm.New(l, pwm.WithFrequency(0))
+	assert.Equal(t, pwm.ErrInvalidFrequency, err)
+	_, err = pwm.New(l, pwm.WithDutyCycle(1.5))
+	assert.Equal(t, pwm.ErrInvalidDutyCycle, err)
+
+	p, err := pwm.New(l, pwm.WithFrequency(50), pwm.WithDutyCycle(0.5))
+	require.Nil(t, err)
+	assert.Equal(t, 50.0, p.Frequency())
+	assert.Equal(t, 0.5, p.DutyCycle())
+	assert.Nil(t, p.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, p.Close())
+}
+
+func TestDutyCycle(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+	l, err := gpiocdev.RequestLine(s.ChipName(), 1, gpiocdev.AsOutput(0))
+	require.Nil(t, err)
+	defer l.Close()
+
+	p, err := pwm.New(l, pwm.WithFrequency(200), pwm.WithDutyCycle(0.5))
+	require.Nil(t, err)
+	defer p.Close()
+
+	// both levels are seen
+	seen := [2]bool{}
+	for i := 0; i < 200 && !(seen[0] && seen[1]); i++ {
+		v, _ := s.Level(1)
+		seen[v] = true
+		time.Sleep(time.Millisecond / 2)
+	}
+	assert.True(t, seen[0])
+	assert.True(t, seen[1])
+
+	// steady states
+	assert.Nil(t, p.SetDutyCycle(1))
+	time.Sleep(20 * time.Millisecond)
+	checkSteady(t, s, 1, 1)
+	assert.Nil(t, p.SetDutyCycle(0))
+	time.Sleep(20 * time.Millisecond)
+	checkSteady(t, s, 1, 0)
+
+	assert.Equal(t, pwm.ErrInvalidDutyCycle, p.SetDutyCycle(-0.1))
+	assert.Equal(t, pwm.ErrInvalidFrequency, p.SetFrequency(-1))
+	assert.Nil(t, p.SetFrequency(100))
+	assert.Equal(t, 100.0, p.Frequency())
+
+	stats := p.Stats()
+	assert.NotZero(t, stats.Edges)
+	assert.LessOrEqual(t, stats.MeanJitter, stats.MaxJitter)
+	p.ResetStats()
+	assert.Zero(t, p.Stats().Edges)
+}
+
+func TestStopLevel(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+	ll, err := gpiocdev.RequestLines(s.ChipName(), []int{1, 2}, gpiocdev.AsOutput(0, 0))
+	require.Nil(t, err)
+	defer ll.Close()
+
+	p, err := pwm.NewLines(ll, pwm.WithDutyCycle(0), pwm.WithStopLevel(1))
+	require.Nil(t, err)
+	time.Sleep(20 * time.Millisecond)
+	checkSteady(t, s, 1, 0)
+	checkSteady(t, s, 2, 0)
+	assert.Nil(t, p.Close())
+	checkSteady(t, s, 1, 1)
+	checkSteady(t, s, 2, 1)
+}
+
+func TestErr(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+	l, err := gpiocdev.RequestLine(s.ChipName(), 1, gpiocdev.AsOutput(0))
+	require.Nil(t, err)
+
+	p, err := pwm.New(l, pwm.WithFrequency(1000), pwm.WithDutyCycle(0.5))
+	require.Nil(t, err)
+	l.Close()
+	assert.Eventually(t, func() bool { return p.Err() == gpiocdev.ErrClosed },
+		time.Second, time.Millisecond)
+	assert.Equal(t, gpiocdev.ErrClosed, p.Close())
+}
+
+func checkSteady(t *testing.T, s *sim.Chip, offset, xv int) {
+	t.Helper()
+	for i := 0; i < 10; i++ {
+		v, err := s.Level(offset)
+		assert.Nil(t, err)
+		assert.Equal(t, xv, v)
+		time.Sleep(time.Millisecond)
+	}
+}
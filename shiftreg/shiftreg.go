@@ -0,0 +1,454 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package shiftreg exposes chains of shift registers as virtual lines.
+//
+// Output is a chain of serial-in parallel-out registers, such as the 74HC595,
+// and Input is a chain of parallel-in serial-out registers, such as the
+// 74HC165.
+//
+// The registers are numbered from 0, the register connected directly to the
+// GPIO lines, and virtual line n is pin n%8 of register n/8, where pin 0 is
+// QA, or A for the 74HC165, unless the bit order is LSBFirst.
+//
+//	leds, _ := shiftreg.NewOutput("gpiochip0", 17, 27, 22, shiftreg.WithChainLength(2))
+//	leds.SetValue(9, 1)
+//	leds.Update(func(values []int) {
+//		values[0] = 1
+//		values[15] = 0
+//	})
+package shiftreg
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+	"golang.org/x/sys/unix"
+)
+
+// BitOrder determines the order of the virtual lines within each register.
+type BitOrder int
+
+const (
+	// MSBFirst maps the lowest virtual line of each register to QA (or A),
+	// so the highest is shifted first.
+	MSBFirst BitOrder = iota
+
+	// LSBFirst maps the lowest virtual line of each register to QH (or H),
+	// so the lowest is shifted first.
+	LSBFirst
+)
+
+// Option defines the interface required to provide an option to NewOutput or
+// NewInput.
+type Option interface {
+	applyOption(*shiftOptions)
+}
+
+type shiftOptions struct {
+	registers int
+	order     BitOrder
+	delay     time.Duration
+	reqOpts   []gpiocdev.LineReqOption
+}
+
+// ChainLengthOption sets the number of registers in the chain.
+type ChainLengthOption int
+
+// WithChainLength sets the number of registers in the chain.
+//
+// The default is 1.
+func WithChainLength(registers int) ChainLengthOption {
+	return ChainLengthOption(registers)
+}
+
+func (o ChainLengthOption) applyOption(opts *shiftOptions) {
+	opts.registers = int(o)
+}
+
+// BitOrderOption sets the bit order.
+type BitOrderOption BitOrder
+
+// WithBitOrder sets the order of the virtual lines within each register.
+//
+// The default is MSBFirst.
+func WithBitOrder(order BitOrder) BitOrderOption {
+	return BitOrderOption(order)
+}
+
+func (o BitOrderOption) applyOption(opts *shiftOptions) {
+	opts.order = BitOrder(o)
+}
+
+// DelayOption sets the delay after each change to the lines.
+type DelayOption time.Duration
+
+// WithDelay sets a minimum time between changes to the lines, for long or
+// heavily loaded connections to the registers.
+//
+// The default is 0, as the registers are much faster than the lines can be
+// set from user space.
+func WithDelay(period time.Duration) DelayOption {
+	return DelayOption(period)
+}
+
+func (o DelayOption) applyOption(opts *shiftOptions) {
+	opts.delay = time.Duration(o)
+}
+
+// RequestOption provides options for the line request.
+type RequestOption []gpiocdev.LineReqOption
+
+// WithRequestOptions provides options for the request of the lines, such as
+// consumer or drive.
+//
+// Direction and active level options are overridden.
+func WithRequestOptions(options ...gpiocdev.LineReqOption) RequestOption {
+	return RequestOption(options)
+}
+
+func (o RequestOption) applyOption(opts *shiftOptions) {
+	opts.reqOpts = append(opts.reqOpts, o...)
+}
+
+// chain contains the attributes common to input and output chains.
+type chain struct {
+	ll *gpiocdev.Lines
+
+	// the number of registers in the chain.
+	registers int
+
+	order BitOrder
+
+	delay time.Duration
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	// the values of the request lines.
+	lv []int
+
+	closed bool
+}
+
+// init applies the options to the chain, and returns the common options for
+// the line request.
+func (c *chain) init(options []Option) ([]gpiocdev.LineReqOption, error) {
+	opts := shiftOptions{registers: 1}
+	for _, option := range options {
+		option.applyOption(&opts)
+	}
+	if opts.registers <= 0 ||
+		(opts.order != MSBFirst && opts.order != LSBFirst) ||
+		opts.delay < 0 {
+		return nil, unix.EINVAL
+	}
+	c.registers = opts.registers
+	c.order = opts.order
+	c.delay = opts.delay
+	c.lv = make([]int, 3)
+	reqOpts := append([]gpiocdev.LineReqOption{}, opts.reqOpts...)
+	return append(reqOpts, gpiocdev.AsActiveHigh), nil
+}
+
+// close releases the lines.
+func (c *chain) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return gpiocdev.ErrClosed
+	}
+	c.closed = true
+	return c.ll.Close()
+}
+
+// lines returns the number of virtual lines in the chain.
+func (c *chain) lines() int {
+	return c.registers * 8
+}
+
+// line returns the virtual line corresponding to pin p of the chain, where p
+// is the register*8 + the pin within the register.
+func (c *chain) line(p int) int {
+	if c.order == LSBFirst {
+		return p&^7 | (7 - p&7)
+	}
+	return p
+}
+
+// set sets the request lines.
+//
+// Assumes c is locked.
+func (c *chain) set() error {
+	if err := c.ll.SetValues(c.lv); err != nil {
+		return err
+	}
+	if c.delay > 0 {
+		for start := time.Now(); time.Since(start) < c.delay; {
+			runtime.Gosched()
+		}
+	}
+	return nil
+}
+
+// Output is a chain of serial-in parallel-out shift registers, such as the
+// 74HC595.
+type Output struct {
+	chain
+
+	// the values of the virtual lines.
+	values []int
+}
+
+// the request line indices for an output chain.
+const (
+	outData = iota
+	outClock
+	outLatch
+)
+
+// NewOutput requests the data, clock and latch lines from the chip, and
+// creates an output chain with all virtual lines set to 0.
+//
+// The data line connects to SER, the clock to SRCLK, and the latch to RCLK
+// of the first register.
+//
+// Returns unix.EINVAL if the chain length, bit order or delay is invalid.
+func NewOutput(chip string, data, clock, latch int, options ...Option) (*Output, error) {
+	o := &Output{}
+	reqOpts, err := o.init(options)
+	if err != nil {
+		return nil, err
+	}
+	o.values = make([]int, o.lines())
+	reqOpts = append(reqOpts, gpiocdev.AsOutput(0, 0, 0))
+	o.ll, err = gpiocdev.RequestLines(chip, []int{data, clock, latch}, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err = o.shift(); err != nil {
+		o.ll.Close()
+		return nil, err
+	}
+	return o, nil
+}
+
+// Close releases the lines.
+//
+// The registers retain their outputs.
+func (o *Output) Close() error {
+	return o.close()
+}
+
+// Lines returns the number of virtual lines in the chain.
+func (o *Output) Lines() int {
+	return o.lines()
+}
+
+// Value returns the value of the virtual line.
+//
+// Returns unix.EINVAL if the line is out of range.
+func (o *Output) Value(n int) (int, error) {
+	if n < 0 || n >= len(o.values) {
+		return 0, unix.EINVAL
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.values[n], nil
+}
+
+// Values returns the values of all the virtual lines.
+func (o *Output) Values() []int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]int(nil), o.values...)
+}
+
+// SetValue sets the value of the virtual line, leaving the other lines
+// unchanged.
+//
+// Returns unix.EINVAL if the line is out of range.
+func (o *Output) SetValue(n, value int) error {
+	if n < 0 || n >= len(o.values) {
+		return unix.EINVAL
+	}
+	return o.Update(func(values []int) {
+		values[n] = value
+	})
+}
+
+// SetValues sets the values of all the virtual lines in one shift cycle.
+//
+// If insufficient values are provided then the remaining lines are set to 0.
+// If too many values are provided then the surplus values are ignored.
+func (o *Output) SetValues(values []int) error {
+	return o.Update(func(vv []int) {
+		for i := range vv {
+			vv[i] = 0
+			if i < len(values) {
+				vv[i] = values[i]
+			}
+		}
+	})
+}
+
+// Update calls the update function with the current values of the virtual
+// lines, then sets the lines to the updated values in one shift cycle.
+//
+// This allows several lines to be changed together without changing the
+// others.
+func (o *Output) Update(update func(values []int)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return gpiocdev.ErrClosed
+	}
+	update(o.values)
+	for i, v := range o.values {
+		if v != 0 {
+			o.values[i] = 1
+		}
+	}
+	// if the shift fails the registers are left in an unknown state, but
+	// the values are retained and applied by the next update.
+	return o.shift()
+}
+
+// shift shifts the values into the chain and latches them to the outputs.
+//
+// Assumes o is locked.
+func (o *Output) shift() error {
+	// the first bit shifted ends up in QH of the last register.
+	for p := len(o.values) - 1; p >= 0; p-- {
+		o.lv[outData] = o.values[o.line(p)]
+		o.lv[outClock] = 0
+		if err := o.set(); err != nil {
+			return err
+		}
+		o.lv[outClock] = 1
+		if err := o.set(); err != nil {
+			return err
+		}
+	}
+	o.lv[outClock] = 0
+	o.lv[outLatch] = 1
+	if err := o.set(); err != nil {
+		return err
+	}
+	o.lv[outLatch] = 0
+	return o.set()
+}
+
+// Input is a chain of parallel-in serial-out shift registers, such as the
+// 74HC165.
+type Input struct {
+	chain
+
+	// buffer for reading the request lines.
+	rv []int
+}
+
+// the request line indices for an input chain.
+const (
+	inLoad = iota
+	inClock
+	inData
+)
+
+// NewInput requests the load, clock and data lines from the chip, and creates
+// an input chain.
+//
+// The load line connects to SH/LD, the clock to CLK, and the data line to QH
+// of the first register.  The clock inhibit should be tied low.
+//
+// Returns unix.EINVAL if the chain length, bit order or delay is invalid.
+func NewInput(chip string, load, clock, data int, options ...Option) (*Input, error) {
+	i := &Input{rv: make([]int, 3)}
+	reqOpts, err := i.init(options)
+	if err != nil {
+		return nil, err
+	}
+	reqOpts = append(reqOpts,
+		gpiocdev.AsOutput(0, 0),
+		gpiocdev.WithLines([]int{load}, gpiocdev.AsActiveLow),
+		gpiocdev.WithLines([]int{data}, gpiocdev.AsInput))
+	i.ll, err = gpiocdev.RequestLines(chip, []int{load, clock, data}, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Close releases the lines.
+func (i *Input) Close() error {
+	return i.close()
+}
+
+// Lines returns the number of virtual lines in the chain.
+func (i *Input) Lines() int {
+	return i.lines()
+}
+
+// Value reads the chain and returns the value of the virtual line.
+//
+// Returns unix.EINVAL if the line is out of range.
+func (i *Input) Value(n int) (int, error) {
+	if n < 0 || n >= i.Lines() {
+		return 0, unix.EINVAL
+	}
+	values := make([]int, i.Lines())
+	if err := i.Values(values); err != nil {
+		return 0, err
+	}
+	return values[n], nil
+}
+
+// Values reads the values of all the virtual lines, in one shift cycle, into
+// values.
+//
+// If values is shorter than the number of lines then only the leading lines
+// are returned.
+func (i *Input) Values(values []int) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		return gpiocdev.ErrClosed
+	}
+	// load the inputs into the registers.
+	i.lv[inLoad] = 1
+	i.lv[inClock] = 0
+	if err := i.set(); err != nil {
+		return err
+	}
+	i.lv[inLoad] = 0
+	if err := i.set(); err != nil {
+		return err
+	}
+	// QH of the first register is read first.
+	for r := 0; r < i.registers; r++ {
+		for q := 7; q >= 0; q-- {
+			if r != 0 || q != 7 {
+				i.lv[inClock] = 1
+				if err := i.set(); err != nil {
+					return err
+				}
+				i.lv[inClock] = 0
+				if err := i.set(); err != nil {
+					return err
+				}
+			}
+			if err := i.ll.Values(i.rv); err != nil {
+				return err
+			}
+			if n := i.line(r*8 + q); n < len(values) {
+				values[n] = i.rv[inData]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package shiftreg_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/shiftreg"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	data  = 1
+	clock = 2
+	latch = 3
+	load  = 3
+)
+
+// delay gives the emulated registers time to see each change.
+const delay = 100 * time.Microsecond
+
+func TestNew(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	_, err = shiftreg.NewOutput(s.ChipName(), data, clock, latch, shiftreg.WithChainLength(0))
+	assert.Equal(t, unix.EINVAL, err)
+	_, err = shiftreg.NewInput(s.ChipName(), load, clock, data, shiftreg.WithBitOrder(2))
+	assert.Equal(t, unix.EINVAL, err)
+	_, err = shiftreg.NewOutput(s.ChipName(), data, clock, 4)
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	o, err := shiftreg.NewOutput(s.ChipName(), data, clock, latch, shiftreg.WithChainLength(3))
+	require.Nil(t, err)
+	assert.Equal(t, 24, o.Lines())
+	_, err = o.Value(24)
+	assert.Equal(t, unix.EINVAL, err)
+	assert.Equal(t, unix.EINVAL, o.SetValue(-1, 1))
+	assert.Nil(t, o.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, o.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, o.SetValue(0, 1))
+
+	i, err := shiftreg.NewInput(s.ChipName(), load, clock, data)
+	require.Nil(t, err)
+	assert.Equal(t, 8, i.Lines())
+	assert.Nil(t, i.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, i.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, i.Values(make([]int, 8)))
+}
+
+func TestOutput(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	for _, order := range []shiftreg.BitOrder{shiftreg.MSBFirst, shiftreg.LSBFirst} {
+		r := newHC595(s, 2)
+		o, err := shiftreg.NewOutput(s.ChipName(), data, clock, latch,
+			shiftreg.WithChainLength(2),
+			shiftreg.WithBitOrder(order),
+			shiftreg.WithDelay(delay))
+		require.Nil(t, err)
+
+		require.Nil(t, o.SetValue(9, 1))
+		require.Nil(t, o.Update(func(values []int) {
+			values[0] = 1
+			values[15] = 1
+		}))
+		xv := make([]int, 16)
+		xv[0] = 1
+		xv[9] = 1
+		xv[15] = 1
+		assert.Equal(t, xv, o.Values())
+		v, err := o.Value(9)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, v)
+
+		out := r.close()
+		for p, v := range out {
+			n := p
+			if order == shiftreg.LSBFirst {
+				n = p&^7 | (7 - p&7)
+			}
+			assert.Equal(t, xv[n], v, "order %d, pin %d", order, p)
+		}
+		assert.Nil(t, o.Close())
+	}
+}
+
+func TestInput(t *testing.T) {
+	s, err := sim.NewChip(4)
+	require.Nil(t, err)
+	defer s.Close()
+
+	for _, order := range []shiftreg.BitOrder{shiftreg.MSBFirst, shiftreg.LSBFirst} {
+		in := make([]int, 16)
+		for _, p := range []int{0, 3, 8, 14} {
+			in[p] = 1
+		}
+		r := newHC165(s, in)
+		i, err := shiftreg.NewInput(s.ChipName(), load, clock, data,
+			shiftreg.WithChainLength(2),
+			shiftreg.WithBitOrder(order),
+			shiftreg.WithDelay(delay))
+		require.Nil(t, err)
+
+		values := make([]int, 16)
+		assert.Nil(t, i.Values(values))
+		v, err := i.Value(3)
+		assert.Nil(t, err)
+		r.close()
+		for p, v := range in {
+			n := p
+			if order == shiftreg.LSBFirst {
+				n = p&^7 | (7 - p&7)
+			}
+			assert.Equal(t, v, values[n], "order %d, pin %d", order, p)
+		}
+		assert.Equal(t, values[3], v)
+		assert.Nil(t, i.Close())
+	}
+}
+
+// emulator emulates a chain of shift registers by polling the line levels.
+type emulator struct {
+	s    *sim.Chip
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	// the registers, indexed by register*8 + pin.
+	sr []int
+
+	// the latched outputs of a 74HC595 chain.
+	out []int
+}
+
+func (e *emulator) close() []int {
+	close(e.done)
+	e.wg.Wait()
+	return e.out
+}
+
+// newHC595 emulates a chain of 74HC595 registers.
+func newHC595(s *sim.Chip, registers int) *emulator {
+	e := &emulator{
+		s:    s,
+		done: make(chan struct{}),
+		sr:   make([]int, registers*8),
+		out:  make([]int, registers*8),
+	}
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		prevClock, prevLatch := 0, 0
+		for {
+			select {
+			case <-e.done:
+				return
+			default:
+			}
+			c, _ := s.Level(clock)
+			l, _ := s.Level(latch)
+			d, _ := s.Level(data)
+			if c == 1 && prevClock == 0 {
+				copy(e.sr[1:], e.sr)
+				e.sr[0] = d
+			}
+			if l == 1 && prevLatch == 0 {
+				copy(e.out, e.sr)
+			}
+			prevClock, prevLatch = c, l
+			runtime.Gosched()
+		}
+	}()
+	return e
+}
+
+// newHC165 emulates a chain of 74HC165 registers with the given inputs.
+func newHC165(s *sim.Chip, in []int) *emulator {
+	e := &emulator{
+		s:    s,
+		done: make(chan struct{}),
+		sr:   make([]int, len(in)),
+	}
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		prevClock := 0
+		for {
+			select {
+			case <-e.done:
+				return
+			default:
+			}
+			c, _ := s.Level(clock)
+			l, _ := s.Level(load)
+			if l == 0 {
+				copy(e.sr, in)
+			} else if c == 1 && prevClock == 0 {
+				// each register shifts towards QH, and is fed from the QH of
+				// the next register.
+				prev := append([]int(nil), e.sr...)
+				for p := range e.sr {
+					switch {
+					case p&7 != 0:
+						e.sr[p] = prev[p-1]
+					case p+15 < len(e.sr):
+						e.sr[p] = prev[p+15]
+					default:
+						e.sr[p] = 0
+					}
+				}
+			}
+			s.SetPull(data, e.sr[7])
+			prevClock = c
+			runtime.Gosched()
+		}
+	}()
+	return e
+}
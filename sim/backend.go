@@ -0,0 +1,502 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package sim
+
+import (
+	"time"
+	"unsafe"
+
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// handle is the gpiocdev.Backend for an open simulated chip.
+//
+// Events are delivered to gpiocdev through pipes, so they can be read and
+// polled in the same way as those from a GPIO character device.
+type handle struct {
+	chip *Chip
+
+	// the following are covered by the chip mutex.
+
+	// the watched lines
+	watched map[int]bool
+
+	// the pipe for line info changed events, created on first use.
+	rfd int
+	wfd int
+
+	closed bool
+}
+
+func (h *handle) ChipInfo() (ci uapi.ChipInfo, err error) {
+	c := h.chip
+	copy(ci.Name[:len(ci.Name)-1], c.name)
+	copy(ci.Label[:len(ci.Label)-1], c.label)
+	ci.Lines = uint32(len(c.lines))
+	return
+}
+
+func (h *handle) LineInfo(offset int) (uapi.LineInfoV2, error) {
+	c := h.chip
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return uapi.LineInfoV2{}, unix.ENODEV
+	}
+	if offset < 0 || offset >= len(c.lines) {
+		return uapi.LineInfoV2{}, unix.EINVAL
+	}
+	return c.lineInfo(offset), nil
+}
+
+func (h *handle) WatchLineInfo(info *uapi.LineInfoV2) error {
+	c := h.chip
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return unix.ENODEV
+	}
+	offset := int(info.Offset)
+	if offset >= len(c.lines) {
+		return unix.EINVAL
+	}
+	if h.watched[offset] {
+		return unix.EBUSY
+	}
+	h.watched[offset] = true
+	*info = c.lineInfo(offset)
+	return nil
+}
+
+func (h *handle) UnwatchLineInfo(offset int) error {
+	c := h.chip
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return unix.ENODEV
+	}
+	if offset < 0 || offset >= len(c.lines) {
+		return unix.EINVAL
+	}
+	if !h.watched[offset] {
+		return unix.EBUSY
+	}
+	delete(h.watched, offset)
+	return nil
+}
+
+func (h *handle) InfoFd() int {
+	c := h.chip
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h.rfd == -1 {
+		var p [2]int
+		if err := unix.Pipe2(p[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+			return -1
+		}
+		h.rfd = p[0]
+		h.wfd = p[1]
+		if c.closed {
+			h.closeInfo()
+		}
+	}
+	return h.rfd
+}
+
+func (h *handle) GetLine(lr *uapi.LineRequest) (gpiocdev.LineBackend, error) {
+	c := h.chip
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, unix.ENODEV
+	}
+	n := int(lr.Lines)
+	if n == 0 || n > uapi.LinesMax {
+		return nil, unix.EINVAL
+	}
+	offsets := make([]int, n)
+	for i := range offsets {
+		o := int(lr.Offsets[i])
+		if o >= len(c.lines) {
+			return nil, unix.EINVAL
+		}
+		if c.lines[o].req != nil {
+			return nil, unix.EBUSY
+		}
+		for _, p := range offsets[:i] {
+			if p == o {
+				return nil, unix.EBUSY
+			}
+		}
+		offsets[i] = o
+	}
+	cfgs, err := decodeConfig(&lr.Config, n)
+	if err != nil {
+		return nil, err
+	}
+	var p [2]int
+	if err = unix.Pipe2(p[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		return nil, err
+	}
+	r := &request{
+		chip:      c,
+		offsets:   offsets,
+		rfd:       p[0],
+		wfd:       p[1],
+		bufSize:   eventBufferSize(lr),
+		lineSeqno: make([]uint32, n),
+	}
+	consumer := uapi.BytesToString(lr.Consumer[:])
+	for i, o := range offsets {
+		// configured before being attached to the request, so any change
+		// in level due to bias does not generate an edge event.
+		c.configure(o, cfgs[i], false)
+		l := &c.lines[o]
+		l.req = r
+		l.consumer = consumer
+	}
+	for _, o := range offsets {
+		c.notify(o, uapi.LineChangedRequested)
+	}
+	c.reqs[r] = struct{}{}
+	lr.Fd = int32(r.rfd)
+	return r, nil
+}
+
+func (h *handle) Close() error {
+	c := h.chip
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h.closed {
+		return gpiocdev.ErrClosed
+	}
+	h.closed = true
+	delete(c.handles, h)
+	h.closeInfo()
+	if h.rfd != -1 {
+		unix.Close(h.rfd)
+	}
+	return nil
+}
+
+// closeInfo closes the write end of the info pipe, if open.
+//
+// Assumes the chip is locked.
+func (h *handle) closeInfo() {
+	if h.wfd != -1 {
+		unix.Close(h.wfd)
+		h.wfd = -1
+	}
+}
+
+// notify writes the info changed event to the info pipe, if the line is
+// watched.
+//
+// Assumes the chip is locked.
+func (h *handle) notify(lic *uapi.LineInfoChangedV2) {
+	if h.wfd == -1 || !h.watched[int(lic.Info.Offset)] {
+		return
+	}
+	// as per the kernel, the event is dropped if the buffer is full.
+	unix.Write(h.wfd, unsafe.Slice((*byte)(unsafe.Pointer(lic)), unsafe.Sizeof(*lic)))
+}
+
+// lineConfig is the configuration for a single line.
+type lineConfig struct {
+	flags    uapi.LineFlagV2
+	debounce time.Duration
+	value    int
+}
+
+// decodeConfig extracts the configuration for each of n lines from the
+// uAPI line config.
+func decodeConfig(lc *uapi.LineConfig, n int) ([]lineConfig, error) {
+	if lc.NumAttrs > uint32(len(lc.Attrs)) {
+		return nil, unix.EINVAL
+	}
+	cfgs := make([]lineConfig, n)
+	for i := range cfgs {
+		cfg := lineConfig{flags: lc.Flags}
+		for _, attr := range lc.Attrs[:lc.NumAttrs] {
+			if attr.Mask.Get(i) == 0 {
+				continue
+			}
+			switch attr.Attr.ID {
+			case uapi.LineAttributeIDFlags:
+				cfg.flags.Decode(attr.Attr)
+			case uapi.LineAttributeIDOutputValues:
+				cfg.value = uapi.LineBitmap(attr.Attr.Value64()).Get(i)
+			case uapi.LineAttributeIDDebounce:
+				var d uapi.DebouncePeriod
+				d.Decode(attr.Attr)
+				cfg.debounce = time.Duration(d)
+			default:
+				return nil, unix.EINVAL
+			}
+		}
+		if err := validateFlags(cfg.flags); err != nil {
+			return nil, err
+		}
+		cfgs[i] = cfg
+	}
+	return cfgs, nil
+}
+
+// validateFlags performs the same checks on the flags as the kernel.
+func validateFlags(flags uapi.LineFlagV2) error {
+	const validFlags = uapi.LineFlagV2ActiveLow |
+		uapi.LineFlagV2DirectionMask |
+		uapi.LineFlagV2EdgeMask |
+		uapi.LineFlagV2DriveMask |
+		uapi.LineFlagV2BiasMask |
+		uapi.LineFlagV2EventClockRealtime
+
+	if flags&^validFlags != 0 {
+		return unix.EINVAL
+	}
+	if flags&uapi.LineFlagV2DirectionMask == uapi.LineFlagV2DirectionMask {
+		return unix.EINVAL
+	}
+	// edge detection requires explicit input
+	if flags&uapi.LineFlagV2EdgeMask != 0 && flags&uapi.LineFlagV2Input == 0 {
+		return unix.EINVAL
+	}
+	// drive requires explicit output
+	if flags&uapi.LineFlagV2DriveMask != 0 && flags&uapi.LineFlagV2Output == 0 {
+		return unix.EINVAL
+	}
+	if flags&uapi.LineFlagV2DriveMask == uapi.LineFlagV2DriveMask {
+		return unix.EINVAL
+	}
+	bias := flags & uapi.LineFlagV2BiasMask
+	if bias&(bias-1) != 0 {
+		return unix.EINVAL
+	}
+	return nil
+}
+
+// configure applies the configuration to a requested line.
+//
+// Assumes c is locked.
+func (c *Chip) configure(offset int, cfg lineConfig, notify bool) {
+	l := &c.lines[offset]
+	if cfg.flags&uapi.LineFlagV2DirectionMask == 0 {
+		// direction is left as-is
+		if l.isOutput() {
+			cfg.flags |= uapi.LineFlagV2Output
+		} else {
+			cfg.flags |= uapi.LineFlagV2Input
+		}
+	}
+	c.stopDebounce(offset)
+	l.flags = cfg.flags
+	l.debounce = cfg.debounce
+	l.value = cfg.value
+	if l.flags&uapi.LineFlagV2ActiveLow != 0 {
+		l.value ^= 1
+	}
+	// bias simulates a pull on the line
+	switch {
+	case l.flags&uapi.LineFlagV2BiasPullUp != 0:
+		l.pull = 1
+	case l.flags&uapi.LineFlagV2BiasPullDown != 0:
+		l.pull = 0
+	}
+	c.update(offset)
+	if notify {
+		c.notify(offset, uapi.LineChangedConfig)
+	}
+}
+
+// eventBufferSize returns the number of events the kernel would buffer for
+// the request.
+func eventBufferSize(lr *uapi.LineRequest) int {
+	size := int(lr.EventBufferSize)
+	if size == 0 {
+		size = int(lr.Lines) * 16
+	}
+	if size > uapi.LinesMax*16 {
+		size = uapi.LinesMax * 16
+	}
+	// the kernel kfifo is sized to a power of 2
+	pow := 1
+	for pow < size {
+		pow <<= 1
+	}
+	return pow
+}
+
+// request is the gpiocdev.LineBackend for lines requested from a simulated
+// chip.
+type request struct {
+	chip *Chip
+
+	offsets []int
+
+	// the pipe for edge events.
+	//
+	// The read end is the request fd returned to gpiocdev.
+	rfd int
+	wfd int
+
+	// the maximum number of events buffered in the pipe.
+	bufSize int
+
+	// the following are covered by the chip mutex.
+
+	// the seqno of the last event on the request.
+	seqno uint32
+
+	// the seqno of the last event on each line, by index in the request.
+	lineSeqno []uint32
+
+	closed bool
+}
+
+// the size of an event in the event pipe.
+const eventSize = int(unsafe.Sizeof(uapi.LineEvent{}))
+
+func (r *request) Fd() uintptr {
+	return uintptr(r.rfd)
+}
+
+func (r *request) Values(lv *uapi.LineValues) error {
+	c := r.chip
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := r.check(); err != nil {
+		return err
+	}
+	for i, o := range r.offsets {
+		if lv.Mask.Get(i) == 0 {
+			continue
+		}
+		l := &c.lines[o]
+		v := l.level
+		if l.flags&uapi.LineFlagV2ActiveLow != 0 {
+			v ^= 1
+		}
+		lv.Bits = lv.Bits.Set(i, v)
+	}
+	return nil
+}
+
+func (r *request) SetValues(lv uapi.LineValues) error {
+	c := r.chip
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := r.check(); err != nil {
+		return err
+	}
+	for i, o := range r.offsets {
+		if lv.Mask.Get(i) != 0 && !c.lines[o].isOutput() {
+			return unix.EPERM
+		}
+	}
+	for i, o := range r.offsets {
+		if lv.Mask.Get(i) == 0 {
+			continue
+		}
+		l := &c.lines[o]
+		l.value = lv.Get(i)
+		if l.flags&uapi.LineFlagV2ActiveLow != 0 {
+			l.value ^= 1
+		}
+		c.update(o)
+	}
+	return nil
+}
+
+func (r *request) SetConfig(lc *uapi.LineConfig) error {
+	c := r.chip
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := r.check(); err != nil {
+		return err
+	}
+	cfgs, err := decodeConfig(lc, len(r.offsets))
+	if err != nil {
+		return err
+	}
+	for i, o := range r.offsets {
+		c.configure(o, cfgs[i], true)
+	}
+	return nil
+}
+
+func (r *request) Close() error {
+	c := r.chip
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r.closed {
+		return gpiocdev.ErrClosed
+	}
+	r.closed = true
+	unix.Close(r.rfd)
+	if c.closed {
+		// lines already released and wfd closed
+		return nil
+	}
+	unix.Close(r.wfd)
+	delete(c.reqs, r)
+	for _, o := range r.offsets {
+		l := &c.lines[o]
+		c.stopDebounce(o)
+		l.req = nil
+		l.consumer = ""
+		l.flags = 0
+		l.debounce = 0
+		c.update(o)
+	}
+	for _, o := range r.offsets {
+		c.notify(o, uapi.LineChangedReleased)
+	}
+	return nil
+}
+
+// check returns an error if the request can no longer be used.
+//
+// Assumes the chip is locked.
+func (r *request) check() error {
+	if r.closed {
+		return gpiocdev.ErrClosed
+	}
+	if r.chip.closed {
+		return unix.ENODEV
+	}
+	return nil
+}
+
+// edge writes an edge event for the line to the event pipe.
+//
+// Assumes the chip is locked.
+func (r *request) edge(offset int, id uapi.LineEventID, ts uint64) {
+	idx := 0
+	for i, o := range r.offsets {
+		if o == offset {
+			idx = i
+			break
+		}
+	}
+	r.seqno++
+	r.lineSeqno[idx]++
+	evt := uapi.LineEvent{
+		Timestamp: ts,
+		ID:        id,
+		Offset:    uint32(offset),
+		Seqno:     r.seqno,
+		LineSeqno: r.lineSeqno[idx],
+	}
+	// TIOCINQ is FIONREAD - the number of bytes buffered in the pipe.
+	if n, err := unix.IoctlGetInt(r.rfd, unix.TIOCINQ); err == nil && n >= r.bufSize*eventSize {
+		// as per the kernel, the oldest event is discarded when the buffer
+		// overflows.
+		var discard [eventSize]byte
+		unix.Read(r.rfd, discard[:])
+	}
+	unix.Write(r.wfd, unsafe.Slice((*byte)(unsafe.Pointer(&evt)), eventSize))
+}
@@ -0,0 +1,415 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package sim provides simulated GPIO chips for testing code that uses
+// gpiocdev, without requiring the gpio-sim kernel module or root permissions.
+//
+// A simulated chip is registered with gpiocdev by name, so it is accessed using
+// the same API as a GPIO character device:
+//
+//	s, _ := sim.NewChip(8, sim.WithNamedLine(3, "LED"))
+//	defer s.Close()
+//	l, _ := gpiocdev.RequestLine(s.ChipName(), 3, gpiocdev.AsOutput(1))
+//	level, _ := s.Level(3) // level == 1
+//
+// The simulated chips only support the uAPI v2 semantics.
+package sim
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/uapi"
+	"golang.org/x/sys/unix"
+)
+
+// Chip is a simulated GPIO chip.
+//
+// The level of each line is determined by its pull, which simulates the
+// external circuit, unless the line is requested as an output and is driving
+// the line.
+type Chip struct {
+	name  string
+	label string
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
+
+	lines []line
+
+	// the open backends for the chip.
+	handles map[*handle]struct{}
+
+	// the active line requests.
+	reqs map[*request]struct{}
+
+	// indicates the chip has been removed.
+	closed bool
+}
+
+type line struct {
+	name string
+
+	// the level the line is pulled to when not driven.
+	pull int
+
+	// the physical level of the line, after debouncing.
+	level int
+
+	// the request holding the line, if requested.
+	req *request
+
+	// the consumer of the request holding the line.
+	consumer string
+
+	// the flags for the line, while requested.
+	flags uapi.LineFlagV2
+
+	// the debounce period for the line, while requested.
+	debounce time.Duration
+
+	// the physical level driven by the line, while requested as an output.
+	value int
+
+	// pending debounce, if any.
+	debouncer *time.Timer
+
+	// identifies the current debouncer, so a stale debouncer can be ignored.
+	gen uint64
+}
+
+// ChipOption defines the interface required to provide an option to NewChip.
+type ChipOption interface {
+	applyChipOption(*Chip)
+}
+
+// NameOption sets the name of the chip.
+type NameOption string
+
+// WithName sets the name of the chip.
+//
+// The name must be unique among simulated chips, and should not clash with
+// the names of GPIO character devices.
+//
+// By default chips are named simchipN, where N is a unique number.
+func WithName(name string) NameOption {
+	return NameOption(name)
+}
+
+func (o NameOption) applyChipOption(c *Chip) {
+	c.name = string(o)
+}
+
+// LabelOption sets the label of the chip.
+type LabelOption string
+
+// WithLabel sets the label of the chip.
+//
+// By default the label is "sim".
+func WithLabel(label string) LabelOption {
+	return LabelOption(label)
+}
+
+func (o LabelOption) applyChipOption(c *Chip) {
+	c.label = string(o)
+}
+
+// NamedLineOption sets the name of a line.
+type NamedLineOption struct {
+	offset int
+	name   string
+}
+
+// WithNamedLine sets the name of a line.
+//
+// By default lines are unnamed.
+func WithNamedLine(offset int, name string) NamedLineOption {
+	return NamedLineOption{offset, name}
+}
+
+func (o NamedLineOption) applyChipOption(c *Chip) {
+	if o.offset >= 0 && o.offset < len(c.lines) {
+		c.lines[o.offset].name = o.name
+	}
+}
+
+// the number used to generate the next default chip name.
+var chipNum uint32
+
+// NewChip creates a simulated chip with the given number of lines.
+//
+// The chip is available to gpiocdev until it is closed.
+func NewChip(lines int, options ...ChipOption) (*Chip, error) {
+	if lines <= 0 {
+		return nil, unix.EINVAL
+	}
+	c := &Chip{
+		label:   "sim",
+		lines:   make([]line, lines),
+		handles: map[*handle]struct{}{},
+		reqs:    map[*request]struct{}{},
+	}
+	for _, option := range options {
+		option.applyChipOption(c)
+	}
+	if c.name == "" {
+		c.name = "simchip" + strconv.Itoa(int(atomic.AddUint32(&chipNum, 1)-1))
+	}
+	if err := gpiocdev.RegisterBackend(c.name, c.open); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close removes the chip.
+//
+// Subsequent operations on lines requested from the chip fail with ENODEV,
+// and the delivery of events for those lines, and for chips with watched
+// lines, is stopped with ENODEV.
+func (c *Chip) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return gpiocdev.ErrClosed
+	}
+	c.closed = true
+	gpiocdev.UnregisterBackend(c.name)
+	for i := range c.lines {
+		c.stopDebounce(i)
+		c.lines[i].req = nil
+		c.lines[i].flags = 0
+		c.lines[i].debounce = 0
+	}
+	// closing the write ends signals the removal to readers.
+	for r := range c.reqs {
+		unix.Close(r.wfd)
+	}
+	for h := range c.handles {
+		h.closeInfo()
+	}
+	return nil
+}
+
+// ChipName returns the name of the chip, as used to open the chip with
+// gpiocdev.
+func (c *Chip) ChipName() string {
+	return c.name
+}
+
+// Label returns the label of the chip.
+func (c *Chip) Label() string {
+	return c.label
+}
+
+// Lines returns the number of lines on the chip.
+func (c *Chip) Lines() int {
+	return len(c.lines)
+}
+
+// Level returns the physical level of the line.
+//
+// For requested input lines this is the level after any debouncing.
+func (c *Chip) Level(offset int) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset < 0 || offset >= len(c.lines) {
+		return 0, gpiocdev.ErrInvalidOffset
+	}
+	return c.lines[offset].level, nil
+}
+
+// Pull returns the level the line is pulled to when not driven.
+func (c *Chip) Pull(offset int) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset < 0 || offset >= len(c.lines) {
+		return 0, gpiocdev.ErrInvalidOffset
+	}
+	return c.lines[offset].pull, nil
+}
+
+// Pulldown pulls the line low.
+func (c *Chip) Pulldown(offset int) error {
+	return c.SetPull(offset, 0)
+}
+
+// Pullup pulls the line high.
+func (c *Chip) Pullup(offset int) error {
+	return c.SetPull(offset, 1)
+}
+
+// SetPull sets the level the line is pulled to when not driven.
+//
+// This simulates a change to the external circuit, and generates edge events
+// on requested input lines with edge detection enabled.
+func (c *Chip) SetPull(offset int, level int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset < 0 || offset >= len(c.lines) {
+		return gpiocdev.ErrInvalidOffset
+	}
+	if level != 0 {
+		level = 1
+	}
+	c.lines[offset].pull = level
+	c.update(offset)
+	return nil
+}
+
+// Toggle inverts the pull of the line.
+func (c *Chip) Toggle(offset int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset < 0 || offset >= len(c.lines) {
+		return gpiocdev.ErrInvalidOffset
+	}
+	c.lines[offset].pull ^= 1
+	c.update(offset)
+	return nil
+}
+
+// open creates a new Backend for the chip.
+func (c *Chip) open() (gpiocdev.Backend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, unix.ENODEV
+	}
+	h := &handle{chip: c, watched: map[int]bool{}, rfd: -1, wfd: -1}
+	c.handles[h] = struct{}{}
+	return h, nil
+}
+
+func (l *line) isOutput() bool {
+	return l.flags&uapi.LineFlagV2Output != 0
+}
+
+// rawLevel returns the physical level of the line, before debouncing.
+func (l *line) rawLevel() int {
+	if !l.isOutput() {
+		return l.pull
+	}
+	if l.flags&uapi.LineFlagV2OpenDrain != 0 && l.value == 1 {
+		return l.pull
+	}
+	if l.flags&uapi.LineFlagV2OpenSource != 0 && l.value == 0 {
+		return l.pull
+	}
+	return l.value
+}
+
+// update applies any change in the raw level of the line to the line level.
+//
+// Assumes c is locked.
+func (c *Chip) update(offset int) {
+	l := &c.lines[offset]
+	raw := l.rawLevel()
+	if l.debounce == 0 || l.isOutput() {
+		c.setLevel(offset, raw)
+		return
+	}
+	// the level must be stable for the debounce period to be recognised.
+	c.stopDebounce(offset)
+	if raw == l.level {
+		return
+	}
+	gen := l.gen
+	l.debouncer = time.AfterFunc(l.debounce, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		l := &c.lines[offset]
+		if l.gen != gen {
+			return
+		}
+		l.debouncer = nil
+		c.setLevel(offset, l.rawLevel())
+	})
+}
+
+// stopDebounce cancels any pending debounce on the line.
+//
+// Assumes c is locked.
+func (c *Chip) stopDebounce(offset int) {
+	l := &c.lines[offset]
+	if l.debouncer != nil {
+		l.debouncer.Stop()
+		l.debouncer = nil
+	}
+	l.gen++
+}
+
+// setLevel sets the line level and generates any resulting edge event.
+//
+// Assumes c is locked.
+func (c *Chip) setLevel(offset int, level int) {
+	l := &c.lines[offset]
+	if l.level == level {
+		return
+	}
+	l.level = level
+	if l.req == nil || l.isOutput() {
+		return
+	}
+	active := level
+	if l.flags&uapi.LineFlagV2ActiveLow != 0 {
+		active ^= 1
+	}
+	id := uapi.LineEventFallingEdge
+	edge := uapi.LineFlagV2EdgeFalling
+	if active == 1 {
+		id = uapi.LineEventRisingEdge
+		edge = uapi.LineFlagV2EdgeRising
+	}
+	if l.flags&edge != 0 {
+		l.req.edge(offset, id, timestamp(l.flags))
+	}
+}
+
+// lineInfo returns the info for the line.
+//
+// Assumes c is locked.
+func (c *Chip) lineInfo(offset int) uapi.LineInfoV2 {
+	l := &c.lines[offset]
+	li := uapi.LineInfoV2{Offset: uint32(offset)}
+	copy(li.Name[:len(li.Name)-1], l.name)
+	if l.req == nil {
+		li.Flags = uapi.LineFlagV2Input
+		return li
+	}
+	li.Flags = l.flags | uapi.LineFlagV2Used
+	copy(li.Consumer[:len(li.Consumer)-1], l.consumer)
+	if l.debounce != 0 {
+		li.Attrs[0] = uapi.DebouncePeriod(l.debounce).Encode()
+		li.NumAttrs = 1
+	}
+	return li
+}
+
+// notify sends an info changed event for the line to all backends watching it.
+//
+// Assumes c is locked.
+func (c *Chip) notify(offset int, ct uapi.ChangeType) {
+	lic := uapi.LineInfoChangedV2{
+		Info:      c.lineInfo(offset),
+		Timestamp: timestamp(0),
+		Type:      ct,
+	}
+	for h := range c.handles {
+		h.notify(&lic)
+	}
+}
+
+// timestamp returns the current time from the clock selected by the flags.
+func timestamp(flags uapi.LineFlagV2) uint64 {
+	clk := unix.CLOCK_MONOTONIC
+	if flags&uapi.LineFlagV2EventClockRealtime != 0 {
+		clk = unix.CLOCK_REALTIME
+	}
+	var ts unix.Timespec
+	unix.ClockGettime(int32(clk), &ts)
+	return uint64(ts.Nano())
+}
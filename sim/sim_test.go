@@ -0,0 +1,380 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package sim_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"golang.org/x/sys/unix"
+)
+
+func TestNewChip(t *testing.T) {
+	_, err := sim.NewChip(0)
+	assert.Equal(t, unix.EINVAL, err)
+
+	s, err := sim.NewChip(8,
+		sim.WithLabel("test"),
+		sim.WithNamedLine(3, "LED"),
+		sim.WithNamedLine(5, "BUTTON"))
+	require.Nil(t, err)
+	require.NotNil(t, s)
+	assert.Equal(t, 8, s.Lines())
+	assert.Equal(t, "test", s.Label())
+	assert.Contains(t, gpiocdev.Chips(), s.ChipName())
+	assert.Nil(t, gpiocdev.IsChip(s.ChipName()))
+
+	c, err := gpiocdev.NewChip(s.ChipName())
+	require.Nil(t, err)
+	assert.Equal(t, s.ChipName(), c.Name)
+	assert.Equal(t, "test", c.Label)
+	assert.Equal(t, 8, c.Lines())
+	assert.Equal(t, 2, c.UapiAbiVersion())
+	inf, err := c.LineInfo(3)
+	assert.Nil(t, err)
+	assert.Equal(t, "LED", inf.Name)
+	assert.False(t, inf.Used)
+	assert.Equal(t, gpiocdev.LineDirectionInput, inf.Config.Direction)
+	_, err = c.LineInfo(8)
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+	c.Close()
+
+	chip, offset, err := gpiocdev.FindLine("BUTTON")
+	assert.Nil(t, err)
+	assert.Equal(t, s.ChipName(), chip)
+	assert.Equal(t, 5, offset)
+
+	_, err = gpiocdev.NewChip(s.ChipName(), gpiocdev.WithABIVersion(1))
+	assert.Equal(t, gpiocdev.ErrUapiIncompatibility{Feature: "alternative backend", AbiVersion: 1}, err)
+
+	_, err = sim.NewChip(4, sim.WithName(s.ChipName()))
+	assert.Equal(t, unix.EEXIST, err)
+
+	assert.Nil(t, s.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, s.Close())
+	assert.NotContains(t, gpiocdev.Chips(), s.ChipName())
+	_, err = gpiocdev.NewChip(s.ChipName())
+	assert.NotNil(t, err)
+}
+
+func TestOutput(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	l, err := gpiocdev.RequestLine(s.ChipName(), 3,
+		gpiocdev.AsOutput(1),
+		gpiocdev.WithConsumer("test-output"))
+	require.Nil(t, err)
+	defer l.Close()
+	checkLevel(t, s, 3, 1)
+
+	inf, err := l.Info()
+	assert.Nil(t, err)
+	assert.True(t, inf.Used)
+	assert.Equal(t, "test-output", inf.Consumer)
+	assert.Equal(t, gpiocdev.LineDirectionOutput, inf.Config.Direction)
+
+	assert.Nil(t, l.SetValue(0))
+	checkLevel(t, s, 3, 0)
+	v, err := l.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, v)
+
+	// external pull is overridden by push-pull output
+	s.Pullup(3)
+	checkLevel(t, s, 3, 0)
+
+	assert.Nil(t, l.Reconfigure(gpiocdev.AsActiveLow))
+	checkLevel(t, s, 3, 1)
+	assert.Nil(t, l.SetValue(1))
+	checkLevel(t, s, 3, 0)
+
+	// busy
+	_, err = gpiocdev.RequestLine(s.ChipName(), 3, gpiocdev.AsInput)
+	assert.Equal(t, unix.EBUSY, err)
+
+	// released lines revert to the pull
+	l.Close()
+	checkLevel(t, s, 3, 1)
+}
+
+func TestDrive(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	ll, err := gpiocdev.RequestLines(s.ChipName(), []int{1, 2},
+		gpiocdev.AsOutput(1, 0),
+		gpiocdev.WithLines([]int{1}, gpiocdev.AsOpenDrain),
+		gpiocdev.WithLines([]int{2}, gpiocdev.AsOpenSource))
+	require.Nil(t, err)
+	defer ll.Close()
+
+	// floating lines follow the pull
+	checkLevel(t, s, 1, 0)
+	checkLevel(t, s, 2, 0)
+	s.Pullup(1)
+	s.Pullup(2)
+	checkLevel(t, s, 1, 1)
+	checkLevel(t, s, 2, 1)
+
+	// driven lines ignore the pull
+	assert.Nil(t, ll.SetValues([]int{0, 1}))
+	s.Pullup(1)
+	s.Pulldown(2)
+	checkLevel(t, s, 1, 0)
+	checkLevel(t, s, 2, 1)
+}
+
+func TestInput(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	ll, err := gpiocdev.RequestLines(s.ChipName(), []int{1, 2, 3},
+		gpiocdev.AsInput,
+		gpiocdev.WithLines([]int{2}, gpiocdev.AsActiveLow),
+		gpiocdev.WithLines([]int{3}, gpiocdev.WithPullUp))
+	require.Nil(t, err)
+	defer ll.Close()
+
+	vv := make([]int, 3)
+	assert.Nil(t, ll.Values(vv))
+	assert.Equal(t, []int{0, 1, 1}, vv)
+
+	s.Pullup(1)
+	s.Pullup(2)
+	s.Pulldown(3)
+	assert.Nil(t, ll.Values(vv))
+	assert.Equal(t, []int{1, 0, 0}, vv)
+
+	assert.Equal(t, gpiocdev.ErrPermissionDenied, ll.SetValues([]int{1, 1, 1}))
+
+	inf, err := ll.Info()
+	assert.Nil(t, err)
+	assert.True(t, inf[1].Config.ActiveLow)
+	assert.Equal(t, gpiocdev.LineBiasPullUp, inf[2].Config.Bias)
+}
+
+func TestEdgeEvents(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	ech := make(chan gpiocdev.LineEvent, 5)
+	ll, err := gpiocdev.RequestLines(s.ChipName(), []int{1, 2},
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithLines([]int{2}, gpiocdev.WithRisingEdge, gpiocdev.AsActiveLow),
+		gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {
+			ech <- evt
+		}))
+	require.Nil(t, err)
+	defer ll.Close()
+
+	s.Pullup(1)
+	checkEvent(t, ech, 1, gpiocdev.LineEventRisingEdge, 1, 1)
+	s.Pulldown(1)
+	checkEvent(t, ech, 1, gpiocdev.LineEventFallingEdge, 2, 2)
+
+	// active low - physical rising edge is a falling edge, so filtered
+	s.Pullup(2)
+	checkNoEvent(t, ech)
+	s.Pulldown(2)
+	checkEvent(t, ech, 2, gpiocdev.LineEventRisingEdge, 3, 1)
+
+	// no change - no event
+	s.Pulldown(1)
+	checkNoEvent(t, ech)
+}
+
+func TestDebounce(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	period := 20 * time.Millisecond
+	ech := make(chan gpiocdev.LineEvent, 5)
+	l, err := gpiocdev.RequestLine(s.ChipName(), 4,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithDebounce(period),
+		gpiocdev.WithEventHandler(func(evt gpiocdev.LineEvent) {
+			ech <- evt
+		}))
+	require.Nil(t, err)
+	defer l.Close()
+
+	inf, err := l.Info()
+	assert.Nil(t, err)
+	assert.True(t, inf.Config.Debounced)
+	assert.Equal(t, period, inf.Config.DebouncePeriod)
+
+	// bounces are filtered
+	for i := 0; i < 5; i++ {
+		s.Toggle(4)
+		time.Sleep(period / 4)
+	}
+	checkLevel(t, s, 4, 0)
+	time.Sleep(2 * period)
+	checkLevel(t, s, 4, 1)
+	checkEvent(t, ech, 4, gpiocdev.LineEventRisingEdge, 1, 1)
+	checkNoEvent(t, ech)
+
+	// a bounce back within the period is ignored
+	s.Pulldown(4)
+	time.Sleep(period / 4)
+	s.Pullup(4)
+	time.Sleep(2 * period)
+	checkLevel(t, s, 4, 1)
+	checkNoEvent(t, ech)
+}
+
+func TestReadEvents(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	l, err := gpiocdev.RequestLines(s.ChipName(), []int{2},
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventBufferSize(4))
+	require.Nil(t, err)
+	defer l.Close()
+
+	// overflow the event buffer - the oldest events are discarded
+	for i := 0; i < 6; i++ {
+		s.Toggle(2)
+	}
+	buf := make([]gpiocdev.LineEvent, 8)
+	n, err := l.ReadEvents(context.Background(), buf)
+	assert.Nil(t, err)
+	require.Equal(t, 4, n)
+	assert.Equal(t, uint32(3), buf[0].Seqno)
+	assert.Equal(t, uint32(6), buf[3].Seqno)
+	assert.Equal(t, uint64(2), l.LostEvents())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.ReadEvents(ctx, buf)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestWatchLineInfo(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	c, err := gpiocdev.NewChip(s.ChipName())
+	require.Nil(t, err)
+	defer c.Close()
+
+	ich := make(chan gpiocdev.LineInfoChangeEvent, 5)
+	inf, err := c.WatchLineInfo(3, func(evt gpiocdev.LineInfoChangeEvent) {
+		ich <- evt
+	})
+	assert.Nil(t, err)
+	assert.False(t, inf.Used)
+	_, err = c.WatchLineInfo(3, nil)
+	assert.Equal(t, unix.EBUSY, err)
+
+	l, err := c.RequestLine(3, gpiocdev.AsOutput(0))
+	require.Nil(t, err)
+	checkInfoEvent(t, ich, gpiocdev.LineRequested, gpiocdev.LineDirectionOutput)
+	assert.Nil(t, l.Reconfigure(gpiocdev.AsInput))
+	checkInfoEvent(t, ich, gpiocdev.LineReconfigured, gpiocdev.LineDirectionInput)
+	l.Close()
+	checkInfoEvent(t, ich, gpiocdev.LineReleased, gpiocdev.LineDirectionInput)
+
+	assert.Nil(t, c.UnwatchLineInfo(3))
+	l, err = c.RequestLine(3, gpiocdev.AsOutput(0))
+	require.Nil(t, err)
+	l.Close()
+	select {
+	case evt := <-ich:
+		assert.Fail(t, "unexpected event", evt)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestClose(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+
+	c, err := gpiocdev.NewChip(s.ChipName())
+	require.Nil(t, err)
+	defer c.Close()
+	_, err = c.WatchLineInfo(2, func(gpiocdev.LineInfoChangeEvent) {})
+	require.Nil(t, err)
+
+	errch := make(chan error, 1)
+	l, err := c.RequestLine(3,
+		gpiocdev.WithBothEdges,
+		gpiocdev.WithEventHandler(func(gpiocdev.LineEvent) {}),
+		gpiocdev.WithErrorHandler(func(err error) {
+			errch <- err
+		}))
+	require.Nil(t, err)
+	defer l.Close()
+
+	s.Close()
+	select {
+	case err = <-errch:
+		assert.Equal(t, unix.ENODEV, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for error")
+	}
+	assert.Equal(t, unix.ENODEV, l.Err())
+	_, err = l.Value()
+	assert.Equal(t, unix.ENODEV, err)
+	assert.Eventually(t, func() bool { return c.Err() == unix.ENODEV },
+		time.Second, time.Millisecond)
+}
+
+func checkLevel(t *testing.T, s *sim.Chip, offset, xv int) {
+	t.Helper()
+	v, err := s.Level(offset)
+	assert.Nil(t, err)
+	assert.Equal(t, xv, v)
+}
+
+func checkEvent(t *testing.T, ech <-chan gpiocdev.LineEvent, offset int,
+	xtype gpiocdev.LineEventType, seqno, lineSeqno uint32) {
+	t.Helper()
+	select {
+	case evt := <-ech:
+		assert.Equal(t, offset, evt.Offset)
+		assert.Equal(t, xtype, evt.Type)
+		assert.Equal(t, seqno, evt.Seqno)
+		assert.Equal(t, lineSeqno, evt.LineSeqno)
+		assert.NotZero(t, evt.Timestamp)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for event")
+	}
+}
+
+func checkNoEvent(t *testing.T, ech <-chan gpiocdev.LineEvent) {
+	t.Helper()
+	select {
+	case evt := <-ech:
+		assert.Fail(t, "unexpected event", evt)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func checkInfoEvent(t *testing.T, ich <-chan gpiocdev.LineInfoChangeEvent,
+	xtype gpiocdev.LineInfoChangeType, xdir gpiocdev.LineDirection) {
+	t.Helper()
+	select {
+	case evt := <-ich:
+		assert.Equal(t, xtype, evt.Type)
+		assert.Equal(t, xdir, evt.Info.Config.Direction)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout waiting for info event")
+	}
+}
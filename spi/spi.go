@@ -0,0 +1,360 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+// Package spi provides a bit-banged SPI master using GPIO lines.
+//
+// The SCLK, MOSI, chip select and optional MISO lines are requested in a
+// single request, and each phase of the clock sets SCLK and MOSI with a single
+// call to SetValues, so the signals stay in step.
+//
+// As the master is clocked from user space the clock rate is low, and the
+// clock may pause at any point, so it is suitable for simple peripherals such
+// as shift registers, displays and ADCs, that tolerate an irregular clock.
+//
+//	m, _ := spi.New("gpiochip0", 11, 10, []int{8, 7},
+//		spi.WithMISO(9),
+//		spi.WithMode(spi.Mode0))
+//	defer m.Close()
+//	w := []byte{0x01, 0x80, 0x00}
+//	r := make([]byte, len(w))
+//	err := m.Tx(0, w, r)
+package spi
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/warthog618/go-gpiocdev"
+	"golang.org/x/sys/unix"
+)
+
+// Mode is the SPI mode, which determines the clock polarity (CPOL) and phase
+// (CPHA).
+type Mode int
+
+const (
+	// Mode0 has CPOL=0 and CPHA=0, so the clock idles low and data is sampled
+	// on the rising edge.
+	Mode0 Mode = iota
+
+	// Mode1 has CPOL=0 and CPHA=1, so the clock idles low and data is sampled
+	// on the falling edge.
+	Mode1
+
+	// Mode2 has CPOL=1 and CPHA=0, so the clock idles high and data is
+	// sampled on the falling edge.
+	Mode2
+
+	// Mode3 has CPOL=1 and CPHA=1, so the clock idles high and data is
+	// sampled on the rising edge.
+	Mode3
+)
+
+// BitOrder is the order the bits of each byte are transferred.
+type BitOrder int
+
+const (
+	// MSBFirst transfers the most significant bit of each byte first.
+	MSBFirst BitOrder = iota
+
+	// LSBFirst transfers the least significant bit of each byte first.
+	LSBFirst
+)
+
+// Master is a bit-banged SPI bus master.
+type Master struct {
+	ll *gpiocdev.Lines
+
+	// the number of chip select lines.
+	cs int
+
+	// indicates the MISO line is requested, as the last line.
+	miso bool
+
+	// the idle level of SCLK.
+	cpol int
+
+	// indicates data is sampled on the trailing edge of the clock.
+	cpha bool
+
+	order BitOrder
+
+	// half the period of SCLK.
+	half time.Duration
+
+	// mutex serialises transactions and covers the attributes below it.
+	mu sync.Mutex
+
+	// the values of the output lines - SCLK, MOSI, then the chip selects.
+	values []int
+
+	// buffer for reading the lines.
+	rvalues []int
+
+	closed bool
+}
+
+// Option defines the interface required to provide an option to New.
+type Option interface {
+	applyOption(*masterOptions)
+}
+
+type masterOptions struct {
+	mode      Mode
+	order     BitOrder
+	frequency int
+	miso      int
+	reqOpts   []gpiocdev.LineReqOption
+}
+
+// ModeOption sets the SPI mode.
+type ModeOption Mode
+
+// WithMode sets the SPI mode.
+//
+// The default is Mode0.
+func WithMode(mode Mode) ModeOption {
+	return ModeOption(mode)
+}
+
+func (o ModeOption) applyOption(opts *masterOptions) {
+	opts.mode = Mode(o)
+}
+
+// BitOrderOption sets the bit order.
+type BitOrderOption BitOrder
+
+// WithBitOrder sets the order the bits of each byte are transferred.
+//
+// The default is MSBFirst.
+func WithBitOrder(order BitOrder) BitOrderOption {
+	return BitOrderOption(order)
+}
+
+func (o BitOrderOption) applyOption(opts *masterOptions) {
+	opts.order = BitOrder(o)
+}
+
+// FrequencyOption sets the SCLK frequency.
+type FrequencyOption int
+
+// WithFrequency sets the target SCLK frequency, in Hz.
+//
+// This determines the minimum time for each phase of the clock, so the actual
+// frequency will be lower.
+//
+// The default is 100kHz.
+func WithFrequency(hz int) FrequencyOption {
+	return FrequencyOption(hz)
+}
+
+func (o FrequencyOption) applyOption(opts *masterOptions) {
+	opts.frequency = int(o)
+}
+
+// MISOOption specifies the MISO line.
+type MISOOption int
+
+// WithMISO specifies the offset of the MISO line.
+//
+// Without a MISO line the master can only write.
+func WithMISO(offset int) MISOOption {
+	return MISOOption(offset)
+}
+
+func (o MISOOption) applyOption(opts *masterOptions) {
+	opts.miso = int(o)
+}
+
+// RequestOption provides options for the line request.
+type RequestOption []gpiocdev.LineReqOption
+
+// WithRequestOptions provides options for the request of the bus lines, such
+// as consumer, drive or MISO bias.
+//
+// Direction and active level options are overridden by the master.
+func WithRequestOptions(options ...gpiocdev.LineReqOption) RequestOption {
+	return RequestOption(options)
+}
+
+func (o RequestOption) applyOption(opts *masterOptions) {
+	opts.reqOpts = append(opts.reqOpts, o...)
+}
+
+// New requests the bus lines from the chip and creates a master for the bus.
+//
+// The chip selects are active low, and each peripheral on the bus is
+// identified by the index of its chip select in cs.
+//
+// Returns unix.EINVAL if the mode, bit order or frequency is invalid.
+func New(chip string, sclk, mosi int, cs []int, options ...Option) (*Master, error) {
+	opts := masterOptions{frequency: 100000, miso: -1}
+	for _, option := range options {
+		option.applyOption(&opts)
+	}
+	if opts.mode < Mode0 || opts.mode > Mode3 ||
+		(opts.order != MSBFirst && opts.order != LSBFirst) ||
+		opts.frequency <= 0 {
+		return nil, unix.EINVAL
+	}
+	m := &Master{
+		cs:     len(cs),
+		miso:   opts.miso >= 0,
+		cpol:   int(opts.mode>>1) & 1,
+		cpha:   opts.mode&1 != 0,
+		order:  opts.order,
+		half:   time.Second / time.Duration(2*opts.frequency),
+		values: make([]int, 2+len(cs)),
+	}
+	m.values[0] = m.cpol
+	offsets := append([]int{sclk, mosi}, cs...)
+	reqOpts := append([]gpiocdev.LineReqOption{}, opts.reqOpts...)
+	reqOpts = append(reqOpts,
+		gpiocdev.AsActiveHigh,
+		gpiocdev.AsOutput(m.values...),
+		gpiocdev.WithLines(cs, gpiocdev.AsActiveLow))
+	if m.miso {
+		// MISO is last, and as an input is left unchanged by SetValues.
+		offsets = append(offsets, opts.miso)
+		reqOpts = append(reqOpts, gpiocdev.WithLines([]int{opts.miso}, gpiocdev.AsInput))
+	}
+	m.rvalues = make([]int, len(offsets))
+	ll, err := gpiocdev.RequestLines(chip, offsets, reqOpts...)
+	if err != nil {
+		return nil, err
+	}
+	m.ll = ll
+	return m, nil
+}
+
+// Close releases the bus lines.
+func (m *Master) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return gpiocdev.ErrClosed
+	}
+	m.closed = true
+	return m.ll.Close()
+}
+
+// Tx selects the peripheral, writes w to it while reading r from it, then
+// deselects it.
+//
+// The cs is the index of the peripheral's chip select, or -1 to not assert
+// any chip select.
+//
+// If w is nil then zeros are written, and if r is nil then nothing is read.
+// Otherwise w and r must be the same length.
+//
+// Returns unix.EINVAL if cs is out of range, the lengths of w and r differ,
+// or r is provided without a MISO line.
+func (m *Master) Tx(cs int, w, r []byte) error {
+	if cs < -1 || cs >= m.cs ||
+		(w != nil && r != nil && len(w) != len(r)) ||
+		(r != nil && !m.miso) {
+		return unix.EINVAL
+	}
+	n := len(w)
+	if w == nil {
+		n = len(r)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return gpiocdev.ErrClosed
+	}
+	if cs >= 0 {
+		m.values[2+cs] = 1
+		if err := m.set(m.cpol, 0); err != nil {
+			return err
+		}
+	}
+	var err error
+	for i := 0; i < n && err == nil; i++ {
+		var wb, rb byte
+		if w != nil {
+			wb = w[i]
+		}
+		rb, err = m.transfer(wb)
+		if r != nil {
+			r[i] = rb
+		}
+	}
+	if cs >= 0 {
+		m.values[2+cs] = 0
+		if serr := m.set(m.cpol, 0); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// transfer writes a byte and simultaneously reads one.
+func (m *Master) transfer(wb byte) (byte, error) {
+	var rb byte
+	for i := 0; i < 8; i++ {
+		shift := 7 - i
+		if m.order == LSBFirst {
+			shift = i
+		}
+		v, err := m.clock(int(wb>>shift) & 1)
+		if err != nil {
+			return 0, err
+		}
+		rb |= byte(v) << shift
+	}
+	return rb, nil
+}
+
+// clock writes a bit in one clock cycle, and returns the bit read.
+//
+// In mode 0 and 2 the first phase sets MOSI with SCLK idle and the second
+// the leading edge.
+// In mode 1 and 3 the first phase sets MOSI with the leading edge and the
+// second the trailing edge.
+// So in either case the peripheral has output its bit by the end of the
+// second phase.
+func (m *Master) clock(bit int) (int, error) {
+	first := m.cpol
+	if m.cpha {
+		first ^= 1
+	}
+	if err := m.set(first, bit); err != nil {
+		return 0, err
+	}
+	if err := m.set(first^1, bit); err != nil {
+		return 0, err
+	}
+	if !m.miso {
+		return 0, nil
+	}
+	if err := m.ll.Values(m.rvalues); err != nil {
+		return 0, err
+	}
+	return m.rvalues[len(m.rvalues)-1], nil
+}
+
+// set sets SCLK and MOSI, along with the chip selects, and waits for half
+// a clock period.
+func (m *Master) set(sclk, mosi int) error {
+	m.values[0] = sclk
+	m.values[1] = mosi
+	if err := m.ll.SetValues(m.values); err != nil {
+		return err
+	}
+	m.delay()
+	return nil
+}
+
+// delay waits for half an SCLK period.
+//
+// The delay is too short for time.Sleep, so it busy waits, yielding to other
+// goroutines so they are not starved on single core systems.
+func (m *Master) delay() {
+	for start := time.Now(); time.Since(start) < m.half; {
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2026 Kent Gibson <warthog618@gmail.com>
+//
+// SPDX-License-Identifier: MIT
+
+package spi_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/warthog618/go-gpiocdev"
+	"github.com/warthog618/go-gpiocdev/sim"
+	"github.com/warthog618/go-gpiocdev/spi"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	sclk = 1
+	mosi = 2
+	cs0  = 3
+	cs1  = 4
+	miso = 5
+)
+
+func TestNew(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	_, err = spi.New(s.ChipName(), sclk, mosi, nil, spi.WithMode(4))
+	assert.Equal(t, unix.EINVAL, err)
+
+	_, err = spi.New(s.ChipName(), sclk, mosi, []int{8})
+	assert.Equal(t, gpiocdev.ErrInvalidOffset, err)
+
+	m, err := spi.New(s.ChipName(), sclk, mosi, []int{cs0}, spi.WithMode(spi.Mode2))
+	require.Nil(t, err)
+	checkLevel(t, s, sclk, 1)
+	checkLevel(t, s, cs0, 1)
+
+	assert.Equal(t, unix.EINVAL, m.Tx(1, []byte{1}, nil))
+	assert.Equal(t, unix.EINVAL, m.Tx(0, []byte{1}, make([]byte, 2)))
+	// no MISO
+	assert.Equal(t, unix.EINVAL, m.Tx(0, []byte{1}, make([]byte, 1)))
+
+	assert.Nil(t, m.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, m.Close())
+	assert.Equal(t, gpiocdev.ErrClosed, m.Tx(0, []byte{1}, nil))
+}
+
+func TestTx(t *testing.T) {
+	s, err := sim.NewChip(8)
+	require.Nil(t, err)
+	defer s.Close()
+
+	w := []byte{0xa5, 0x3c, 0x01}
+	for _, mode := range []spi.Mode{spi.Mode0, spi.Mode1, spi.Mode2, spi.Mode3} {
+		for _, order := range []spi.BitOrder{spi.MSBFirst, spi.LSBFirst} {
+			m, err := spi.New(s.ChipName(), sclk, mosi, []int{cs0, cs1},
+				spi.WithMISO(miso),
+				spi.WithMode(mode),
+				spi.WithBitOrder(order),
+				spi.WithFrequency(5000))
+			require.Nil(t, err)
+			p := newPeripheral(s, cs1, mode, order)
+
+			r := make([]byte, len(w))
+			assert.Nil(t, m.Tx(1, w, r))
+			p.close()
+			assert.Equal(t, w, r, "mode %d, order %d", mode, order)
+			assert.Equal(t, w, p.rx, "mode %d, order %d", mode, order)
+			checkLevel(t, s, sclk, int(mode>>1))
+			checkLevel(t, s, cs0, 1)
+			checkLevel(t, s, cs1, 1)
+			assert.Nil(t, m.Close())
+		}
+	}
+}
+
+func checkLevel(t *testing.T, s *sim.Chip, offset, level int) {
+	t.Helper()
+	v, err := s.Level(offset)
+	assert.Nil(t, err)
+	assert.Equal(t, level, v, "offset %d", offset)
+}
+
+// peripheral emulates a loopback SPI peripheral by polling the bus levels.
+//
+// It captures the bits from MOSI on the sampling edge of the clock, and
+// echoes MOSI onto MISO.
+type peripheral struct {
+	s     *sim.Chip
+	cs    int
+	cpol  int
+	cpha  bool
+	order spi.BitOrder
+
+	// the bytes received while selected.
+	rx []byte
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newPeripheral(s *sim.Chip, cs int, mode spi.Mode, order spi.BitOrder) *peripheral {
+	p := &peripheral{
+		s:     s,
+		cs:    cs,
+		cpol:  int(mode>>1) & 1,
+		cpha:  mode&1 != 0,
+		order: order,
+		done:  make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+func (p *peripheral) close() {
+	close(p.done)
+	p.wg.Wait()
+}
+
+func (p *peripheral) run() {
+	defer p.wg.Done()
+	prevSCLK := p.cpol
+	var b byte
+	bits := 0
+	for {
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+		c, _ := p.s.Level(sclk)
+		d, _ := p.s.Level(mosi)
+		sel, _ := p.s.Level(p.cs)
+		p.s.SetPull(miso, d)
+		if sel == 0 && c != prevSCLK {
+			leading := c != p.cpol
+			if leading != p.cpha {
+				if p.order == spi.MSBFirst {
+					b |= byte(d) << (7 - bits)
+				} else {
+					b |= byte(d) << bits
+				}
+				bits++
+				if bits == 8 {
+					p.rx = append(p.rx, b)
+					b = 0
+					bits = 0
+				}
+			}
+		}
+		prevSCLK = c
+		runtime.Gosched()
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"regexp"
 	"strconv"
 	"unsafe"
@@ -169,7 +170,12 @@ func BytesToString(a []byte) string {
 type fdReader int
 
 func (fd fdReader) Read(b []byte) (int, error) {
-	return unix.Read(int(fd), b[:])
+	n, err := unix.Read(int(fd), b[:])
+	if n == 0 && err == nil && len(b) != 0 {
+		// end of file - else io.ReadFull would spin
+		return 0, io.EOF
+	}
+	return n, err
 }
 
 // ReadEvent reads a single event from a requested line.
@@ -118,6 +118,31 @@ func ReadLineEvent(fd uintptr) (LineEvent, error) {
 	return le, err
 }
 
+// ReadLineEvents reads as many events as are available from a requested line,
+// up to the length of buf.
+//
+// The fd is a requested line, as returned by GetLine.
+//
+// Returns the number of events read into buf.
+//
+// This function is blocking and should only be called when the fd is known to
+// be ready to read.
+func ReadLineEvents(fd uintptr, buf []LineEvent) (int, error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	size := int(unsafe.Sizeof(buf[0]))
+	b := unsafe.Slice((*byte)(unsafe.Pointer(&buf[0])), len(buf)*size)
+	n, err := unix.Read(int(fd), b)
+	if err != nil {
+		return 0, err
+	}
+	if n%size != 0 {
+		return n / size, unix.EIO
+	}
+	return n / size, nil
+}
+
 // ReadLineInfoChangedV2 reads a line info changed event from a chip.
 //
 // The fd is an open GPIO character device.
@@ -2125,6 +2125,59 @@ func TestWatchLineInfoV2(t *testing.T) {
 	assert.Nil(t, chg, "spurious change")
 }
 
+func TestReadLineEvents(t *testing.T) {
+	requireKernel(t, uapiV2Kernel)
+	s, err := gpiosim.NewSimpleton(4)
+	require.Nil(t, err)
+	defer s.Close()
+	f, err := os.Open(s.DevPath())
+	require.Nil(t, err)
+	defer f.Close()
+	err = s.SetPull(1, 0)
+	require.Nil(t, err)
+
+	lr := uapi.LineRequest{
+		Lines:   1,
+		Offsets: [uapi.LinesMax]uint32{1},
+		Config: uapi.LineConfig{
+			Flags: uapi.LineFlagV2Input | uapi.LineFlagV2EdgeBoth,
+		},
+	}
+	err = uapi.GetLine(f.Fd(), &lr)
+	require.Nil(t, err)
+	defer unix.Close(int(lr.Fd))
+
+	// empty buffer
+	n, err := uapi.ReadLineEvents(uintptr(lr.Fd), nil)
+	assert.Nil(t, err)
+	assert.Zero(t, n)
+
+	s.SetPull(1, 1)
+	s.SetPull(1, 0)
+	s.SetPull(1, 1)
+	time.Sleep(eventWaitTimeout)
+
+	// partial
+	buf := make([]uapi.LineEvent, 2)
+	n, err = uapi.ReadLineEvents(uintptr(lr.Fd), buf)
+	require.Nil(t, err)
+	require.Equal(t, 2, n)
+	for i, id := range []uapi.LineEventID{uapi.LineEventRisingEdge, uapi.LineEventFallingEdge} {
+		assert.Equal(t, id, buf[i].ID)
+		assert.Equal(t, uint32(1), buf[i].Offset)
+		assert.Equal(t, uint32(i+1), buf[i].Seqno)
+		assert.Equal(t, uint32(i+1), buf[i].LineSeqno)
+	}
+
+	// remainder
+	buf = make([]uapi.LineEvent, 4)
+	n, err = uapi.ReadLineEvents(uintptr(lr.Fd), buf)
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+	assert.Equal(t, uapi.LineEventRisingEdge, buf[0].ID)
+	assert.Equal(t, uint32(3), buf[0].Seqno)
+}
+
 func TestReadLineEvent(t *testing.T) {
 	requireKernel(t, uapiV2Kernel)
 	s, err := gpiosim.NewSimpleton(4)
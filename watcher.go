@@ -5,205 +5,216 @@
 package gpiocdev
 
 import (
-	"fmt"
+	"sync"
 	"time"
 
 	"github.com/warthog618/go-gpiocdev/uapi"
 	"golang.org/x/sys/unix"
 )
 
-type watcher struct {
-	epfd int
+// loopWatcher registers a set of fds with an EventLoop.
+type loopWatcher struct {
+	loop *EventLoop
 
-	// eventfd to signal watcher to shutdown
-	donefd int
+	// indicates the loop was created for, and so is closed with, the watcher.
+	private bool
 
-	// the handler for detected events
-	eh EventHandler
+	// the sources registered with the loop
+	srcs []*eventSource
+
+	// the handler for errors reading the fds
+	errh ErrorHandler
+
+	// mutex covers the attributes below it.
+	mu sync.Mutex
 
-	// closed once watcher exits
-	doneCh chan struct{}
+	// the first error reading the fds
+	err error
 }
 
-func newWatcher(fd int32, eh EventHandler) (w *watcher, err error) {
-	var epfd, donefd int
-	epfd, err = unix.EpollCreate1(unix.EPOLL_CLOEXEC)
-	if err != nil {
-		return
-	}
-	defer func() {
+// watch registers the fds with the loop.
+//
+// If loop is nil then a private loop is created for the watcher.
+func (lw *loopWatcher) watch(loop *EventLoop, fds []int, read func(fd int) error) (err error) {
+	if loop == nil {
+		loop, err = NewEventLoop()
 		if err != nil {
-			unix.Close(epfd)
+			return
 		}
-	}()
-	donefd, err = unix.Eventfd(0, unix.EFD_CLOEXEC)
-	if err != nil {
-		return
+		lw.private = true
 	}
-	defer func() {
+	lw.loop = loop
+	for _, fd := range fds {
+		var src *eventSource
+		src, err = loop.add(fd, read, lw.fail)
 		if err != nil {
-			unix.Close(donefd)
+			lw.unwatch()
+			return
 		}
-	}()
-	epv := unix.EpollEvent{Events: unix.EPOLLIN, Fd: int32(donefd)}
-	err = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, int(donefd), &epv)
-	if err != nil {
-		return
+		lw.srcs = append(lw.srcs, src)
 	}
-	epv.Fd = int32(fd)
-	err = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, int(fd), &epv)
-	if err != nil {
-		return
+	return
+}
+
+// unwatch deregisters the fds from the loop.
+func (lw *loopWatcher) unwatch() {
+	for _, src := range lw.srcs {
+		lw.loop.remove(src)
 	}
-	w = &watcher{
-		epfd:   epfd,
-		donefd: donefd,
-		eh:     eh,
-		doneCh: make(chan struct{}),
+	lw.srcs = nil
+	if lw.private {
+		lw.loop.Close()
 	}
-	go w.watch()
-	return
+}
+
+// fail records the error and forwards it to the error handler.
+//
+// Called from the loop goroutine.
+func (lw *loopWatcher) fail(err error) {
+	lw.mu.Lock()
+	if lw.err == nil {
+		lw.err = err
+	}
+	lw.mu.Unlock()
+	if lw.errh != nil {
+		lw.errh(err)
+	}
+}
+
+// Err returns the first error reading the fds, if any.
+func (lw *loopWatcher) Err() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.err
+}
+
+type watcher struct {
+	loopWatcher
+
+	// the handler for detected events
+	bh EventBatchHandler
+
+	// buffers for events read from the kernel, reused for each read.
+	uevts []uapi.LineEvent
+	evts  []LineEvent
+}
+
+func newWatcher(fd int32, bh EventBatchHandler, errh ErrorHandler, batchSize int, loop *EventLoop) (*watcher, error) {
+	w := &watcher{
+		loopWatcher: loopWatcher{errh: errh},
+		bh:          bh,
+		uevts:       make([]uapi.LineEvent, batchSize),
+		evts:        make([]LineEvent, batchSize),
+	}
+	if err := w.watch(loop, []int{int(fd)}, w.read); err != nil {
+		return nil, err
+	}
+	return w, nil
 }
 
 func (w *watcher) Close() error {
-	unix.Write(w.donefd, []byte{1, 0, 0, 0, 0, 0, 0, 0})
-	<-w.doneCh
-	unix.Close(w.donefd)
+	w.unwatch()
 	return nil
 }
 
-func (w *watcher) watch() {
-	epollEvents := make([]unix.EpollEvent, 2)
-	defer close(w.doneCh)
-	for {
-		n, err := unix.EpollWait(w.epfd, epollEvents[:], -1)
-		if err != nil {
-			if err == unix.EBADF || err == unix.EINVAL {
-				// fd closed so exit
-				return
-			}
-			if err == unix.EINTR {
-				continue
-			}
-			panic(fmt.Sprintf("EpollWait unexpected error: %v", err))
-		}
-		for i := 0; i < n; i++ {
-			ev := epollEvents[i]
-			fd := ev.Fd
-			if fd == int32(w.donefd) {
-				unix.Close(w.epfd)
-				return
-			}
-			evt, err := uapi.ReadLineEvent(uintptr(fd))
-			if err != nil {
-				continue
-			}
-			le := LineEvent{
-				Offset:    int(evt.Offset),
-				Timestamp: time.Duration(evt.Timestamp),
-				Type:      LineEventType(evt.ID),
-				Seqno:     evt.Seqno,
-				LineSeqno: evt.LineSeqno,
-			}
-			w.eh(le)
-		}
+func (w *watcher) read(fd int) error {
+	n, err := uapi.ReadLineEvents(uintptr(fd), w.uevts)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// the kernel only returns 0 if the request has been removed.
+		return unix.ENODEV
 	}
+	for i := 0; i < n; i++ {
+		w.evts[i] = newLineEvent(w.uevts[i])
+	}
+	w.bh(w.evts[:n])
+	return nil
 }
 
 type watcherV1 struct {
-	watcher
+	loopWatcher
+
+	// the handler for detected events
+	eh EventHandler
 
 	// fd to offset mapping
 	evtfds map[int]int
 }
 
-func newWatcherV1(fds map[int]int, eh EventHandler) (w *watcherV1, err error) {
-	var epfd, donefd int
-	epfd, err = unix.EpollCreate1(unix.EPOLL_CLOEXEC)
-	if err != nil {
-		return
-	}
-	defer func() {
-		if err != nil {
-			unix.Close(epfd)
-		}
-	}()
-	donefd, err = unix.Eventfd(0, unix.EFD_CLOEXEC)
-	if err != nil {
-		return
-	}
-	defer func() {
-		if err != nil {
-			unix.Close(donefd)
-		}
-	}()
-	epv := unix.EpollEvent{Events: unix.EPOLLIN, Fd: int32(donefd)}
-	err = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, int(donefd), &epv)
-	if err != nil {
-		return
+func newWatcherV1(fds map[int]int, eh EventHandler, errh ErrorHandler, loop *EventLoop) (*watcherV1, error) {
+	w := &watcherV1{
+		loopWatcher: loopWatcher{errh: errh},
+		eh:          eh,
+		evtfds:      fds,
 	}
+	efds := make([]int, 0, len(fds))
 	for fd := range fds {
-		epv.Fd = int32(fd)
-		err = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, fd, &epv)
-		if err != nil {
-			return
-		}
+		efds = append(efds, fd)
 	}
-	w = &watcherV1{
-		watcher: watcher{
-			epfd:   epfd,
-			donefd: donefd,
-			eh:     eh,
-			doneCh: make(chan struct{}),
-		},
-		evtfds: fds,
+	if err := w.watch(loop, efds, w.read); err != nil {
+		return nil, err
 	}
-	go w.watch()
-	return
+	return w, nil
 }
 
 func (w *watcherV1) Close() error {
-	unix.Write(w.donefd, []byte{1, 0, 0, 0, 0, 0, 0, 0})
-	<-w.doneCh
+	w.unwatch()
 	for fd := range w.evtfds {
 		unix.Close(fd)
 	}
-	unix.Close(w.donefd)
 	return nil
 }
 
-func (w *watcherV1) watch() {
-	epollEvents := make([]unix.EpollEvent, len(w.evtfds))
-	defer close(w.doneCh)
-	for {
-		n, err := unix.EpollWait(w.epfd, epollEvents[:], -1)
-		if err != nil {
-			if err == unix.EBADF || err == unix.EINVAL {
-				// fd closed so exit
-				return
-			}
-			if err == unix.EINTR {
-				continue
-			}
-			panic(fmt.Sprintf("EpollWait unexpected error: %v", err))
-		}
-		for i := 0; i < n; i++ {
-			ev := epollEvents[i]
-			fd := ev.Fd
-			if fd == int32(w.donefd) {
-				unix.Close(w.epfd)
-				return
-			}
-			evt, err := uapi.ReadEvent(uintptr(fd))
-			if err != nil {
-				continue
-			}
-			le := LineEvent{
-				Offset:    w.evtfds[int(fd)],
-				Timestamp: time.Duration(evt.Timestamp),
-				Type:      LineEventType(evt.ID),
-			}
-			w.eh(le)
+func (w *watcherV1) read(fd int) error {
+	evt, err := uapi.ReadEvent(uintptr(fd))
+	if err != nil {
+		return err
+	}
+	le := LineEvent{
+		Offset:    w.evtfds[fd],
+		Timestamp: time.Duration(evt.Timestamp),
+		Type:      LineEventType(evt.ID),
+	}
+	w.eh(le)
+	return nil
+}
+
+// splitWatcher merges the events from the chunks of a split request.
+//
+// The chunks deliver their events via the loop to the merger, which forwards
+// them to the request's handler.
+type splitWatcher struct {
+	loop *EventLoop
+
+	// indicates the loop was created for, and so is closed with, the watcher.
+	private bool
+
+	merger *eventMerger
+
+	// the requests for the chunks
+	chunks []*Lines
+}
+
+// Close stops the merging of events.
+//
+// The chunks must be closed first.
+func (sw *splitWatcher) Close() error {
+	sw.loop.removeFlusher(sw.merger)
+	if sw.private {
+		sw.loop.Close()
+	}
+	return nil
+}
+
+// Err returns the first error stopping the delivery of events for any of the
+// chunks.
+func (sw *splitWatcher) Err() error {
+	for _, chunk := range sw.chunks {
+		if err := chunk.Err(); err != nil {
+			return err
 		}
 	}
+	return nil
 }